@@ -0,0 +1,75 @@
+// Command flexpane-secrets manages the encrypted secrets file referenced from
+// config as secret://name.
+//
+//	flexpane-secrets list
+//	flexpane-secrets set <name>      (reads the value from stdin)
+//	flexpane-secrets delete <name>
+//
+// The passphrase comes from FLEXPANE_SECRETS_PASSPHRASE or the key file
+// configured in config/panes.json.
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+
+	"flexpane/internal/config"
+)
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+	}
+
+	cfg, err := config.Load("config/panes.json")
+	if err != nil {
+		fail(err)
+	}
+	store, err := cfg.OpenSecrets()
+	if err != nil {
+		fail(err)
+	}
+
+	switch os.Args[1] {
+	case "list":
+		for _, name := range store.Names() {
+			fmt.Println(name)
+		}
+
+	case "set":
+		if len(os.Args) != 3 {
+			usage()
+		}
+		fmt.Fprintf(os.Stderr, "Value for %s: ", os.Args[2])
+		value, err := bufio.NewReader(os.Stdin).ReadString('\n')
+		if err != nil && value == "" {
+			fail(err)
+		}
+		if err := store.Set(os.Args[2], strings.TrimRight(value, "\r\n")); err != nil {
+			fail(err)
+		}
+
+	case "delete":
+		if len(os.Args) != 3 {
+			usage()
+		}
+		if err := store.Delete(os.Args[2]); err != nil {
+			fail(err)
+		}
+
+	default:
+		usage()
+	}
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "usage: flexpane-secrets list | set <name> | delete <name>")
+	os.Exit(2)
+}
+
+func fail(err error) {
+	fmt.Fprintln(os.Stderr, "flexpane-secrets:", err)
+	os.Exit(1)
+}
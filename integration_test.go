@@ -2,14 +2,18 @@ package main
 
 import (
 	"encoding/json"
+	"errors"
 	"html/template"
 	"net/http"
 	"net/http/httptest"
 	"os"
+	"path/filepath"
 	"strings"
 	"testing"
+	"time"
 
 	"flexpane/internal/handlers"
+	"flexpane/internal/models"
 	"flexpane/internal/panes"
 	"flexpane/internal/providers"
 	"flexpane/internal/services"
@@ -34,7 +38,7 @@ func TestFullApplication_HomePage(t *testing.T) {
 	registry.SetEnabledPanes([]string{"calendar", "todos", "email"})
 
 	// Use real templates (this will help catch template errors)
-	templates, err := loadTemplates()
+	templates, err := loadTemplates(registry)
 	if err != nil {
 		t.Skipf("Skipping integration test - templates not available: %v", err)
 	}
@@ -56,10 +60,11 @@ func TestFullApplication_HomePage(t *testing.T) {
 
 	// Check all panes are present
 	expectedContent := []string{
-		"Flexpane",     // Page title
+		"Flexpane",      // Page title
 		"Calendar",      // Calendar pane
 		"Todos",         // Todo pane
 		"Email Preview", // Email pane
+		`<span class="pane-count">4 items</span>`, // Email count in the pane header
 	}
 
 	for _, expected := range expectedContent {
@@ -69,6 +74,82 @@ func TestFullApplication_HomePage(t *testing.T) {
 	}
 }
 
+func TestFullApplication_ReplayProvider(t *testing.T) {
+	// Replayed data keeps the page reproducible without live accounts
+	dataProvider, err := providers.NewReplayProvider("testdata/cassette.json")
+	if err != nil {
+		t.Fatalf("Failed to create replay provider: %v", err)
+	}
+
+	registry := services.NewPaneRegistry()
+	registry.RegisterPane(panes.NewCalendarPane(dataProvider))
+	registry.RegisterPane(panes.NewEmailPane(dataProvider))
+	registry.SetEnabledPanes([]string{"calendar", "email"})
+
+	templates, err := loadTemplates(registry)
+	if err != nil {
+		t.Skipf("Skipping integration test - templates not available: %v", err)
+	}
+
+	handler := handlers.NewHandler(registry, templates)
+
+	req := httptest.NewRequest("GET", "/", nil)
+	recorder := httptest.NewRecorder()
+
+	handler.Home(recorder, req)
+
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", recorder.Code)
+	}
+
+	body := recorder.Body.String()
+	for _, expected := range []string{"Team Standup", "Product Review", "Budget Meeting"} {
+		if !strings.Contains(body, expected) {
+			t.Errorf("Expected response to contain '%s'", expected)
+		}
+	}
+}
+
+func TestFullApplication_CalendarViews(t *testing.T) {
+	dataProvider, err := providers.NewReplayProvider("testdata/cassette.json")
+	if err != nil {
+		t.Fatalf("Failed to create replay provider: %v", err)
+	}
+
+	registry := services.NewPaneRegistry()
+	registry.RegisterPane(panes.NewCalendarPane(dataProvider))
+	registry.SetEnabledPanes([]string{"calendar"})
+
+	templates, err := loadTemplates(registry)
+	if err != nil {
+		t.Skipf("Skipping integration test - templates not available: %v", err)
+	}
+
+	handler := handlers.NewHandler(registry, templates)
+
+	// Navigate to the day of the first event so the test doesn't depend on the clock
+	events, _ := dataProvider.GetCalendarEvents()
+	date := events[0].Start.Local().Format("2006-01-02")
+
+	for _, view := range []string{"agenda", "day", "week", "month"} {
+		req := httptest.NewRequest("GET", "/?calendar.view="+view+"&calendar.date="+date, nil)
+		recorder := httptest.NewRecorder()
+
+		handler.Home(recorder, req)
+
+		if recorder.Code != http.StatusOK {
+			t.Fatalf("%s view: expected status 200, got %d", view, recorder.Code)
+		}
+		body := recorder.Body.String()
+		if !strings.Contains(body, `calendar-view-link active">`+view) {
+			t.Errorf("%s view: expected the view to be marked active", view)
+		}
+		if !strings.Contains(body, "Team Standup") {
+			t.Errorf("%s view: expected replayed event to be shown", view)
+		}
+	}
+}
+
 func TestFullApplication_TodosAPI(t *testing.T) {
 	// Setup
 	todoService := services.NewTodoService("test_integration_todos_api.json")
@@ -76,7 +157,7 @@ func TestFullApplication_TodosAPI(t *testing.T) {
 	registry.RegisterPane(panes.NewTodoPane(todoService))
 	registry.SetEnabledPanes([]string{"todos"})
 
-	templates, err := loadTemplates()
+	templates, err := loadTemplates(registry)
 	if err != nil {
 		t.Skipf("Skipping integration test - templates not available: %v", err)
 	}
@@ -109,6 +190,96 @@ func TestFullApplication_TodosAPI(t *testing.T) {
 	}
 }
 
+// failingProvider stands in for an unreachable calendar and mail server
+type failingProvider struct{}
+
+func (failingProvider) GetCalendarEvents() ([]models.Event, error) {
+	return nil, errors.New("dial tcp: connection refused")
+}
+
+func (failingProvider) GetEmails() ([]models.Email, error) {
+	return nil, errors.New("dial tcp: connection refused")
+}
+
+func TestFullApplication_PaneErrors(t *testing.T) {
+	todoService := services.NewTodoService("test_integration_todos.json")
+	registry := services.NewPaneRegistry()
+	registry.RegisterPane(panes.NewCalendarPane(failingProvider{}))
+	registry.RegisterPane(panes.NewTodoPane(todoService))
+	registry.SetEnabledPanes([]string{"calendar", "todos"})
+
+	templates, err := loadTemplates(registry)
+	if err != nil {
+		t.Skipf("Skipping integration test - templates not available: %v", err)
+	}
+	handler := handlers.NewHandler(registry, templates)
+
+	// A failing pane renders its error state without breaking the page
+	recorder := httptest.NewRecorder()
+	handler.Home(recorder, httptest.NewRequest("GET", "/", nil))
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", recorder.Code, recorder.Body.String())
+	}
+	body := recorder.Body.String()
+	for _, want := range []string{"pane-error-failed", `class="pane-retry" data-pane-id="calendar"`, "todo-form"} {
+		if !strings.Contains(body, want) {
+			t.Errorf("Expected page to contain %q", want)
+		}
+	}
+	if strings.Contains(body, "connection refused") {
+		t.Error("Expected the underlying error to stay out of the page")
+	}
+
+	// Retrying fetches just that pane
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /panes/{id}", handler.Pane)
+	recorder = httptest.NewRecorder()
+	mux.ServeHTTP(recorder, httptest.NewRequest("GET", "/panes/calendar", nil))
+	if recorder.Code != http.StatusOK || !strings.Contains(recorder.Body.String(), `data-pane-id="calendar"`) || strings.Contains(recorder.Body.String(), "todo-form") {
+		t.Errorf("Expected the calendar fragment alone, got %d: %s", recorder.Code, recorder.Body.String())
+	}
+
+	recorder = httptest.NewRecorder()
+	mux.ServeHTTP(recorder, httptest.NewRequest("GET", "/panes/email", nil))
+	if recorder.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 for a pane that isn't enabled, got %d", recorder.Code)
+	}
+}
+
+func TestFullApplication_PaneFragment(t *testing.T) {
+	todoService := services.NewTodoService(filepath.Join(t.TempDir(), "todos.json"))
+	if err := todoService.AddTodo("Water plants"); err != nil {
+		t.Fatal(err)
+	}
+	registry := services.NewPaneRegistry()
+	registry.RegisterPane(panes.NewTodoPane(todoService))
+	registry.SetEnabledPanes([]string{"todos"})
+	registry.SetRefreshes(map[string]time.Duration{"todos": time.Minute})
+
+	templates, err := loadTemplates(registry)
+	if err != nil {
+		t.Skipf("Skipping integration test - templates not available: %v", err)
+	}
+	handler := handlers.NewHandler(registry, templates)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /panes/{id}", handler.Pane)
+	recorder := httptest.NewRecorder()
+	mux.ServeHTTP(recorder, httptest.NewRequest("GET", "/panes/todos", nil))
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", recorder.Code, recorder.Body.String())
+	}
+	body := recorder.Body.String()
+	for _, want := range []string{`data-pane-id="todos"`, `data-refresh="60"`, "Water plants"} {
+		if !strings.Contains(body, want) {
+			t.Errorf("Expected fragment to contain %q, got %s", want, body)
+		}
+	}
+	if strings.Contains(body, "<html") {
+		t.Error("Expected a fragment, not the whole page")
+	}
+}
+
 func TestFullApplication_StaticFiles(t *testing.T) {
 	// Test that static file serving works
 	req := httptest.NewRequest("GET", "/static/css/style.css", nil)
@@ -132,20 +303,8 @@ func TestFullApplication_StaticFiles(t *testing.T) {
 // Removed redundant configuration tests - covered by unit tests
 
 // Helper function to load templates like main.go
-func loadTemplates() (*template.Template, error) {
-	tmpl, err := template.ParseGlob("web/templates/*.html")
-	if err != nil {
-		return nil, err
-	}
-	tmpl, err = tmpl.ParseGlob("web/templates/components/*.html")
-	if err != nil {
-		return nil, err
-	}
-	tmpl, err = tmpl.ParseGlob("web/templates/panes/*.html")
-	if err != nil {
-		return nil, err
-	}
-	return tmpl, nil
+func loadTemplates(registry *services.PaneRegistry) (*template.Template, error) {
+	return handlers.ParseTemplates("web/templates", registry)
 }
 
 func TestMain(m *testing.M) {
@@ -170,4 +329,4 @@ func TestMain(m *testing.M) {
 	}
 
 	os.Exit(code)
-}
\ No newline at end of file
+}
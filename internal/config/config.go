@@ -0,0 +1,441 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"flexpane/internal/providers"
+	"flexpane/internal/secrets"
+	"flexpane/internal/services"
+)
+
+// DefaultProvider is the instance name panes use when they don't pick one
+const DefaultProvider = "default"
+
+// DefaultSecretsFile is where encrypted secrets live unless configured otherwise
+const DefaultSecretsFile = "data/secrets.enc"
+
+// Config is the application configuration loaded from config/panes.json
+type Config struct {
+	Enabled   []string                             `json:"enabled"`
+	Layout    map[string]services.PaneLayoutConfig `json:"layout"`
+	Providers map[string]providers.InstanceConfig  `json:"providers,omitempty"`
+	Panes     map[string]PaneSettings              `json:"panes,omitempty"`
+	Secrets   SecretsConfig                        `json:"secrets,omitempty"`
+
+	// IANA zone names; times render in TimeZone (server local when empty) and
+	// the calendar also shows SecondaryTimeZone when set
+	TimeZone          string `json:"timezone,omitempty"`
+	SecondaryTimeZone string `json:"secondary_timezone,omitempty"`
+
+	// Free slots are only suggested within working hours
+	WorkingHours services.WorkingHours `json:"working_hours,omitempty"`
+
+	// How long before a meeting the calendar starts warning it's time to leave
+	LeaveWarning string `json:"leave_warning,omitempty"`
+
+	// Subscribable calendar feed; disabled without a token
+	Feed FeedConfig `json:"feed,omitempty"`
+
+	// Reminder rules and the channels they deliver through
+	Reminders RemindersConfig `json:"reminders,omitempty"`
+
+	// Outgoing mail and triage rules for the email pane
+	Email EmailConfig `json:"email,omitempty"`
+}
+
+// DefaultLeaveWarning applies when leave_warning is not configured
+const DefaultLeaveWarning = 5 * time.Minute
+
+// SecretsConfig locates the encrypted secrets file and its key. The
+// FLEXPANE_SECRETS_PASSPHRASE environment variable takes precedence over the
+// key file.
+type SecretsConfig struct {
+	File    string `json:"file,omitempty"`
+	KeyFile string `json:"key_file,omitempty"`
+}
+
+// FeedConfig controls the published .ics feed. Token is usually a
+// secret:// reference since anyone holding the URL can read the feed.
+type FeedConfig struct {
+	Token string `json:"token,omitempty"`
+	Todos string `json:"todos,omitempty"` // "vtodo" (default) or "events"
+
+	token secrets.Value
+}
+
+// RemindersConfig declares reminder rules and channel settings. The
+// "browser" channel needs no settings; "webhook" and "smtp" are available
+// once configured.
+type RemindersConfig struct {
+	Interval string                  `json:"interval,omitempty"` // How often rules are evaluated, default 1m
+	Rules    []services.ReminderRule `json:"rules,omitempty"`
+	Webhook  *WebhookConfig          `json:"webhook,omitempty"`
+	SMTP     *SMTPConfig             `json:"smtp,omitempty"`
+}
+
+// WebhookConfig is a reminder webhook endpoint
+type WebhookConfig struct {
+	URL     string            `json:"url"`
+	Headers map[string]string `json:"headers,omitempty"`
+}
+
+// SMTPConfig is an outgoing mail server. Password may be a secret://
+// reference. Connections must be upgraded with STARTTLS unless TLS (implicit
+// TLS, usually port 465) or Insecure is set.
+type SMTPConfig struct {
+	Addr     string   `json:"addr"` // host:port
+	Username string   `json:"username,omitempty"`
+	Password string   `json:"password,omitempty"`
+	From     string   `json:"from"`
+	To       []string `json:"to,omitempty"` // Reminder recipients, default From
+	TLS      bool     `json:"tls,omitempty"`
+	Insecure bool     `json:"insecure,omitempty"`
+
+	password secrets.Value
+}
+
+// EmailConfig enables sending from the email pane and declares triage rules.
+// Rules notify through the reminder channels.
+type EmailConfig struct {
+	SMTP     *SMTPConfig          `json:"smtp,omitempty"`
+	Rules    []services.EmailRule `json:"rules,omitempty"`
+	Interval string               `json:"interval,omitempty"` // How often to check for new mail, default 1m
+}
+
+// Channel names usable in reminder rules
+const (
+	ChannelBrowser = "browser"
+	ChannelWebhook = "webhook"
+	ChannelSMTP    = "smtp"
+)
+
+// PaneSettings holds per-pane options
+type PaneSettings struct {
+	Provider string `json:"provider,omitempty"` // Provider instance name
+	Timeout  string `json:"timeout,omitempty"`  // How long the page waits for the pane, default 5s
+	Refresh  string `json:"refresh,omitempty"`  // How often an open page reloads the pane, e.g. "5m"
+}
+
+// Default returns the configuration used when no config file exists
+func Default() *Config {
+	return &Config{
+		Enabled: []string{"calendar", "todos", "email"},
+		Layout:  map[string]services.PaneLayoutConfig{},
+	}
+}
+
+// Load reads the configuration file, falling back to defaults when it is missing
+func Load(path string) (*Config, error) {
+	config := Default()
+
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		config.applyDefaults()
+		return config, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(data, config); err != nil {
+		return nil, fmt.Errorf("invalid config %s: %w", path, err)
+	}
+	config.applyDefaults()
+
+	return config, config.validate()
+}
+
+func (c *Config) applyDefaults() {
+	if c.Secrets.File == "" {
+		c.Secrets.File = DefaultSecretsFile
+	}
+	if c.WorkingHours.Start == "" && c.WorkingHours.End == "" {
+		c.WorkingHours = services.DefaultWorkingHours
+	}
+	if len(c.Providers) == 0 {
+		// Without declared providers, a single default instance is chosen by environment
+		c.Providers = map[string]providers.InstanceConfig{
+			DefaultProvider: {
+				Type:   os.Getenv("FLEXPANE_PROVIDER"),
+				Record: os.Getenv("FLEXPANE_RECORD"),
+			},
+		}
+	}
+	for name, instance := range c.Providers {
+		if instance.Type == "" {
+			instance.Type = "mock"
+			c.Providers[name] = instance
+		}
+	}
+}
+
+func (c *Config) validate() error {
+	if _, _, err := c.Locations(); err != nil {
+		return err
+	}
+	if err := c.WorkingHours.Validate(); err != nil {
+		return err
+	}
+	if c.LeaveWarning != "" {
+		if _, err := time.ParseDuration(c.LeaveWarning); err != nil {
+			return fmt.Errorf("invalid leave_warning: %w", err)
+		}
+	}
+	switch c.Feed.Todos {
+	case "", services.FeedTodosVTODO, services.FeedTodosEvents:
+	default:
+		return fmt.Errorf("invalid feed todos %q: expected %q or %q", c.Feed.Todos, services.FeedTodosVTODO, services.FeedTodosEvents)
+	}
+	if err := c.Reminders.validate(); err != nil {
+		return err
+	}
+	if smtp := c.Email.SMTP; smtp != nil && (smtp.Addr == "" || smtp.From == "") {
+		return fmt.Errorf("email smtp needs addr and from")
+	}
+	if c.Email.Interval != "" {
+		if _, err := time.ParseDuration(c.Email.Interval); err != nil {
+			return fmt.Errorf("invalid email interval: %w", err)
+		}
+	}
+	for i, rule := range c.Email.Rules {
+		if err := rule.Validate(); err != nil {
+			return fmt.Errorf("email rule %d: %w", i+1, err)
+		}
+		for _, channel := range rule.Notify {
+			if !c.Reminders.hasChannel(channel) {
+				return fmt.Errorf("email rule %d: channel %q is not configured", i+1, channel)
+			}
+		}
+	}
+	for paneID, settings := range c.Panes {
+		if settings.Timeout != "" {
+			if d, err := time.ParseDuration(settings.Timeout); err != nil || d <= 0 {
+				return fmt.Errorf("pane %q has invalid timeout %q", paneID, settings.Timeout)
+			}
+		}
+		if settings.Refresh != "" {
+			if d, err := time.ParseDuration(settings.Refresh); err != nil || d < time.Second {
+				return fmt.Errorf("pane %q has invalid refresh %q", paneID, settings.Refresh)
+			}
+		}
+		if settings.Provider == "" {
+			continue
+		}
+		if _, exists := c.Providers[settings.Provider]; !exists {
+			return fmt.Errorf("pane %q references unknown provider %q", paneID, settings.Provider)
+		}
+	}
+	return nil
+}
+
+// ProviderFor returns the provider instance name a pane should use
+func (c *Config) ProviderFor(paneID string) string {
+	if name := c.Panes[paneID].Provider; name != "" {
+		return name
+	}
+	if _, exists := c.Providers[DefaultProvider]; exists {
+		return DefaultProvider
+	}
+	// Fall back to the only instance when there is exactly one
+	if len(c.Providers) == 1 {
+		for name := range c.Providers {
+			return name
+		}
+	}
+	return DefaultProvider
+}
+
+// PaneTimeouts returns the configured per-pane load deadlines
+func (c *Config) PaneTimeouts() map[string]time.Duration {
+	timeouts := map[string]time.Duration{}
+	for paneID, settings := range c.Panes {
+		if d, err := time.ParseDuration(settings.Timeout); err == nil && d > 0 {
+			timeouts[paneID] = d
+		}
+	}
+	return timeouts
+}
+
+// PaneRefreshes returns how often each pane is reloaded in the page, for
+// panes configured to refresh
+func (c *Config) PaneRefreshes() map[string]time.Duration {
+	refreshes := map[string]time.Duration{}
+	for paneID, settings := range c.Panes {
+		if d, err := time.ParseDuration(settings.Refresh); err == nil && d >= time.Second {
+			refreshes[paneID] = d
+		}
+	}
+	return refreshes
+}
+
+// OpenSecrets opens the configured secrets store
+func (c *Config) OpenSecrets() (*secrets.Store, error) {
+	key, err := secrets.LoadKey(c.Secrets.KeyFile)
+	if err != nil {
+		return nil, err
+	}
+	return secrets.Open(c.Secrets.File, key)
+}
+
+// ResolveSecrets replaces secret:// references in provider settings
+func (c *Config) ResolveSecrets(store *secrets.Store) error {
+	for name, instance := range c.Providers {
+		if err := instance.Settings.ResolveSecrets(store); err != nil {
+			return fmt.Errorf("provider %q: %w", name, err)
+		}
+	}
+	if secrets.IsReference(c.Feed.Token) {
+		token, err := store.Resolve(c.Feed.Token)
+		if err != nil {
+			return fmt.Errorf("feed token: %w", err)
+		}
+		c.Feed.token = token
+	}
+	if err := c.Reminders.SMTP.resolveSecrets(store); err != nil {
+		return fmt.Errorf("reminders smtp password: %w", err)
+	}
+	if err := c.Email.SMTP.resolveSecrets(store); err != nil {
+		return fmt.Errorf("email smtp password: %w", err)
+	}
+	return nil
+}
+
+// FeedToken returns the feed access token, which is zero when the feed is
+// disabled
+func (c *Config) FeedToken() secrets.Value {
+	if !c.Feed.token.IsZero() {
+		return c.Feed.token
+	}
+	if c.Feed.Token == "" || secrets.IsReference(c.Feed.Token) {
+		return secrets.Value{}
+	}
+	return secrets.NewValue(c.Feed.Token)
+}
+
+// Locations returns the primary and optional secondary display zones. The
+// secondary zone is nil when not configured.
+func (c *Config) Locations() (*time.Location, *time.Location, error) {
+	primary := time.Local
+	if c.TimeZone != "" {
+		loc, err := time.LoadLocation(c.TimeZone)
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid timezone: %w", err)
+		}
+		primary = loc
+	}
+
+	var secondary *time.Location
+	if c.SecondaryTimeZone != "" {
+		loc, err := time.LoadLocation(c.SecondaryTimeZone)
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid secondary_timezone: %w", err)
+		}
+		secondary = loc
+	}
+
+	return primary, secondary, nil
+}
+
+// LeaveWarningDuration returns the configured leave warning threshold
+func (c *Config) LeaveWarningDuration() time.Duration {
+	if d, err := time.ParseDuration(c.LeaveWarning); err == nil {
+		return d
+	}
+	return DefaultLeaveWarning
+}
+
+func (r *RemindersConfig) validate() error {
+	if r.Interval != "" {
+		if _, err := time.ParseDuration(r.Interval); err != nil {
+			return fmt.Errorf("invalid reminders interval: %w", err)
+		}
+	}
+	for i, rule := range r.Rules {
+		if err := rule.Validate(); err != nil {
+			return fmt.Errorf("reminder rule %d: %w", i+1, err)
+		}
+		for _, channel := range rule.Channels {
+			if !r.hasChannel(channel) {
+				return fmt.Errorf("reminder rule %d: channel %q is not configured", i+1, channel)
+			}
+		}
+	}
+	return nil
+}
+
+// hasChannel reports whether a notification channel can be used
+func (r *RemindersConfig) hasChannel(channel string) bool {
+	switch {
+	case channel == ChannelBrowser:
+	case channel == ChannelWebhook && r.Webhook != nil:
+	case channel == ChannelSMTP && r.SMTP != nil:
+	default:
+		return false
+	}
+	return true
+}
+
+// RulesInterval returns how often email rules check for new mail, which is
+// also how soon open pages hear of it
+func (e *EmailConfig) RulesInterval() time.Duration {
+	if d, err := time.ParseDuration(e.Interval); err == nil && d > 0 {
+		return d
+	}
+	return services.DefaultReminderInterval
+}
+
+// IntervalDuration returns how often reminders are evaluated
+func (r *RemindersConfig) IntervalDuration() time.Duration {
+	if d, err := time.ParseDuration(r.Interval); err == nil && d > 0 {
+		return d
+	}
+	return services.DefaultReminderInterval
+}
+
+// Notifiers builds the configured reminder channels. The browser channel is
+// passed in since the HTTP layer streams from it.
+func (r *RemindersConfig) Notifiers(browser *services.BrowserNotifier) map[string]services.Notifier {
+	notifiers := map[string]services.Notifier{ChannelBrowser: browser}
+	if r.Webhook != nil {
+		notifiers[ChannelWebhook] = services.NewWebhookNotifier(r.Webhook.URL, r.Webhook.Headers)
+	}
+	if r.SMTP != nil {
+		to := r.SMTP.To
+		if len(to) == 0 {
+			to = []string{r.SMTP.From}
+		}
+		notifiers[ChannelSMTP] = services.NewSMTPNotifier(r.SMTP.Mailer(), to)
+	}
+	return notifiers
+}
+
+func (s *SMTPConfig) resolveSecrets(store *secrets.Store) error {
+	if s == nil || !secrets.IsReference(s.Password) {
+		return nil
+	}
+	password, err := store.Resolve(s.Password)
+	if err != nil {
+		return err
+	}
+	s.password = password
+	return nil
+}
+
+// Mailer builds a client for the server
+func (s *SMTPConfig) Mailer() *services.Mailer {
+	password := s.password
+	if password.IsZero() && !secrets.IsReference(s.Password) {
+		password = secrets.NewValue(s.Password) // Registers it for log redaction
+	}
+	return services.NewMailer(services.MailerOptions{
+		Addr:     s.Addr,
+		Username: s.Username,
+		Password: password.Reveal(),
+		From:     s.From,
+		TLS:      s.TLS,
+		Insecure: s.Insecure,
+	})
+}
@@ -0,0 +1,262 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"flexpane/internal/services"
+)
+
+func writeConfig(t *testing.T, content string) string {
+	path := filepath.Join(t.TempDir(), "panes.json")
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+	return path
+}
+
+func TestLoad_MissingFileUsesDefaults(t *testing.T) {
+	t.Setenv("FLEXPANE_PROVIDER", "")
+
+	cfg, err := Load(filepath.Join(t.TempDir(), "missing.json"))
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+
+	if len(cfg.Enabled) != 3 {
+		t.Errorf("Expected 3 default panes, got %v", cfg.Enabled)
+	}
+	if cfg.Providers[DefaultProvider].Type != "mock" {
+		t.Errorf("Expected default mock provider, got %+v", cfg.Providers)
+	}
+	if cfg.ProviderFor("calendar") != DefaultProvider {
+		t.Errorf("Expected calendar to use the default provider, got %q", cfg.ProviderFor("calendar"))
+	}
+}
+
+func TestLoad_NamedProviders(t *testing.T) {
+	path := writeConfig(t, `{
+		"enabled": ["calendar", "email"],
+		"providers": {
+			"work": {"type": "replay", "settings": {"cassette": "work.json"}},
+			"home": {"type": "mock"}
+		},
+		"panes": {
+			"calendar": {"provider": "work"},
+			"email": {"provider": "home"}
+		}
+	}`)
+
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+
+	if cfg.ProviderFor("calendar") != "work" {
+		t.Errorf("Expected calendar to use work, got %q", cfg.ProviderFor("calendar"))
+	}
+	if cfg.ProviderFor("email") != "home" {
+		t.Errorf("Expected email to use home, got %q", cfg.ProviderFor("email"))
+	}
+	if cfg.Providers["work"].Settings.String("cassette") != "work.json" {
+		t.Errorf("Expected work settings to be loaded, got %+v", cfg.Providers["work"])
+	}
+}
+
+func TestLoad_UnknownProviderReference(t *testing.T) {
+	path := writeConfig(t, `{
+		"providers": {"home": {"type": "mock"}},
+		"panes": {"calendar": {"provider": "work"}}
+	}`)
+
+	_, err := Load(path)
+	if err == nil || !strings.Contains(err.Error(), `unknown provider "work"`) {
+		t.Errorf("Expected unknown provider error, got %v", err)
+	}
+}
+
+func TestResolveSecrets(t *testing.T) {
+	path := writeConfig(t, `{
+		"providers": {
+			"internal": {
+				"type": "plugin",
+				"settings": {"command": "plugin", "options": {"token": "secret://plugin-token"}}
+			}
+		},
+		"secrets": {"file": "`+filepath.Join(t.TempDir(), "secrets.enc")+`"}
+	}`)
+
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	store, err := cfg.OpenSecrets()
+	if err != nil {
+		t.Fatalf("OpenSecrets failed: %v", err)
+	}
+
+	if err := cfg.ResolveSecrets(store); err == nil {
+		t.Fatal("Expected error for unresolved secret")
+	}
+
+	t.Setenv("FLEXPANE_SECRET_PLUGIN_TOKEN", "plugin-token-value")
+	if err := cfg.ResolveSecrets(store); err != nil {
+		t.Fatalf("ResolveSecrets failed: %v", err)
+	}
+
+	options := cfg.Providers["internal"].Settings.Object("options")
+	if fmt.Sprint(options["token"]) == "plugin-token-value" {
+		t.Error("Expected resolved secret to stay wrapped")
+	}
+}
+
+func TestLoad_TimeZones(t *testing.T) {
+	cfg, err := Load(writeConfig(t, `{"timezone": "America/New_York", "secondary_timezone": "Europe/London"}`))
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+
+	primary, secondary, err := cfg.Locations()
+	if err != nil {
+		t.Fatalf("Locations failed: %v", err)
+	}
+	if primary.String() != "America/New_York" || secondary.String() != "Europe/London" {
+		t.Errorf("Unexpected zones %v / %v", primary, secondary)
+	}
+
+	if _, err := Load(writeConfig(t, `{"timezone": "Mars/Olympus_Mons"}`)); err == nil {
+		t.Error("Expected error for an unknown zone")
+	}
+}
+
+func TestLoad_LeaveWarning(t *testing.T) {
+	cfg, err := Load(writeConfig(t, `{"leave_warning": "10m"}`))
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if cfg.LeaveWarningDuration() != 10*time.Minute {
+		t.Errorf("Expected 10m, got %v", cfg.LeaveWarningDuration())
+	}
+
+	if (&Config{}).LeaveWarningDuration() != DefaultLeaveWarning {
+		t.Error("Expected default leave warning when unset")
+	}
+
+	if _, err := Load(writeConfig(t, `{"leave_warning": "soon"}`)); err == nil {
+		t.Error("Expected error for an invalid duration")
+	}
+}
+
+func TestLoad_FeedToken(t *testing.T) {
+	cfg, err := Load(writeConfig(t, `{"feed": {"token": "plain-token", "todos": "events"}}`))
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if cfg.FeedToken().Reveal() != "plain-token" {
+		t.Error("Expected plain token to be used as is")
+	}
+
+	if !Default().FeedToken().IsZero() {
+		t.Error("Expected feed disabled without a token")
+	}
+
+	if _, err := Load(writeConfig(t, `{"feed": {"todos": "tasks"}}`)); err == nil {
+		t.Error("Expected error for an unknown todos mode")
+	}
+}
+
+func TestLoad_Reminders(t *testing.T) {
+	cfg, err := Load(writeConfig(t, `{"reminders": {
+		"rules": [{"on": "event", "before": "10m", "channels": ["browser", "webhook"]}],
+		"webhook": {"url": "https://hooks.example.com/flexpane"}
+	}}`))
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+
+	notifiers := cfg.Reminders.Notifiers(services.NewBrowserNotifier(services.NewEventBus()))
+	if notifiers[ChannelBrowser] == nil || notifiers[ChannelWebhook] == nil || notifiers[ChannelSMTP] != nil {
+		t.Errorf("Unexpected notifiers %v", notifiers)
+	}
+	if cfg.Reminders.IntervalDuration() != services.DefaultReminderInterval {
+		t.Errorf("Expected default interval, got %v", cfg.Reminders.IntervalDuration())
+	}
+
+	// Rules may only use channels that are configured
+	if _, err := Load(writeConfig(t, `{"reminders": {"rules": [{"on": "todo", "channels": ["smtp"]}]}}`)); err == nil {
+		t.Error("Expected error for an unconfigured channel")
+	}
+}
+
+func TestLoad_EmailSMTP(t *testing.T) {
+	cfg, err := Load(writeConfig(t, `{"email": {"smtp": {"addr": "smtp.example.com:587", "from": "me@example.com"}}}`))
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if cfg.Email.SMTP.Mailer().From() != "me@example.com" {
+		t.Error("Expected the mailer to send from the configured address")
+	}
+
+	if _, err := Load(writeConfig(t, `{"email": {"smtp": {"addr": "smtp.example.com:587"}}}`)); err == nil {
+		t.Error("Expected error without a from address")
+	}
+}
+
+func TestLoad_EmailRules(t *testing.T) {
+	cfg, err := Load(writeConfig(t, `{"email": {"rules": [
+		{"name": "Bills", "from": "billing@", "label": "bills", "notify": ["browser"]}
+	]}}`))
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if len(cfg.Email.Rules) != 1 || cfg.Email.Rules[0].Notify[0] != ChannelBrowser {
+		t.Errorf("Unexpected rules %+v", cfg.Email.Rules)
+	}
+	if cfg.Email.RulesInterval() != services.DefaultReminderInterval {
+		t.Errorf("Expected default interval, got %v", cfg.Email.RulesInterval())
+	}
+
+	for _, config := range []string{
+		`{"email": {"rules": [{"label": "everything"}]}}`,
+		`{"email": {"rules": [{"from": "x", "notify": ["webhook"]}]}}`,
+		`{"email": {"interval": "soon"}}`,
+	} {
+		if _, err := Load(writeConfig(t, config)); err == nil {
+			t.Errorf("Expected error for %s", config)
+		}
+	}
+}
+
+func TestLoad_PaneTimeouts(t *testing.T) {
+	cfg, err := Load(writeConfig(t, `{"panes": {"email": {"timeout": "2s"}}}`))
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if timeouts := cfg.PaneTimeouts(); timeouts["email"] != 2*time.Second || len(timeouts) != 1 {
+		t.Errorf("Unexpected timeouts %v", timeouts)
+	}
+
+	if _, err := Load(writeConfig(t, `{"panes": {"email": {"timeout": "-1s"}}}`)); err == nil {
+		t.Error("Expected error for a negative timeout")
+	}
+}
+
+func TestLoad_PaneRefreshes(t *testing.T) {
+	cfg, err := Load(writeConfig(t, `{"panes": {"email": {"refresh": "5m"}}}`))
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if refreshes := cfg.PaneRefreshes(); refreshes["email"] != 5*time.Minute || len(refreshes) != 1 {
+		t.Errorf("Unexpected refreshes %v", refreshes)
+	}
+
+	for _, refresh := range []string{"soon", "500ms", "-1m"} {
+		if _, err := Load(writeConfig(t, `{"panes": {"email": {"refresh": "`+refresh+`"}}}`)); err == nil {
+			t.Errorf("Expected error for refresh %q", refresh)
+		}
+	}
+}
@@ -0,0 +1,88 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"flexpane/internal/services"
+)
+
+// sseHeartbeat keeps idle event streams from being closed by proxies
+const sseHeartbeat = 30 * time.Second
+
+// SetEvents enables the /events stream of pane changes and notifications
+func (h *Handler) SetEvents(events *services.EventBus) {
+	h.events = events
+}
+
+// Events streams pane changes ("change" events) and notifications
+// ("notification" events) to the page as Server-Sent Events, so each page
+// needs only one connection. A page
+// resumes from the Last-Event-ID header the browser sends when reconnecting,
+// or from ?since= on its first connection. When those changes can't be
+// replayed a "reset" event tells the page to reload every pane.
+func (h *Handler) Events(w http.ResponseWriter, r *http.Request) {
+	if h.events == nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	// The stream outlives the server's write timeout
+	controller := http.NewResponseController(w)
+	controller.SetWriteDeadline(time.Time{})
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(200)
+
+	lastID := r.Header.Get("Last-Event-ID")
+	if lastID == "" {
+		lastID = r.URL.Query().Get("since")
+	}
+	sub := h.events.Subscribe(lastID)
+	defer sub.Close()
+
+	if sub.Reset {
+		fmt.Fprintf(w, "id: %s\nevent: reset\ndata: {}\n\n", sub.LastID)
+	}
+	for _, event := range sub.Missed {
+		writeEvent(w, event)
+	}
+	if err := controller.Flush(); err != nil {
+		return
+	}
+
+	heartbeat := time.NewTicker(sseHeartbeat)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-heartbeat.C:
+			fmt.Fprint(w, ": heartbeat\n\n")
+		case event, ok := <-sub.Events:
+			if !ok {
+				return // Fell behind; the page reconnects and replays
+			}
+			writeEvent(w, event)
+		}
+		if err := controller.Flush(); err != nil {
+			return
+		}
+	}
+}
+
+func writeEvent(w http.ResponseWriter, event services.Event) {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return
+	}
+	name := "change"
+	if event.Kind == services.EventNotification {
+		name = "notification"
+	}
+	fmt.Fprintf(w, "id: %s\nevent: %s\ndata: %s\n\n", event.ID, name, data)
+}
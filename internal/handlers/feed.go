@@ -0,0 +1,42 @@
+package handlers
+
+import (
+	"bytes"
+	"crypto/subtle"
+	"log"
+	"net/http"
+
+	"flexpane/internal/secrets"
+	"flexpane/internal/services"
+)
+
+// SetFeed enables the subscribable calendar feed at
+// /feed/{token}/calendar.ics. A zero token leaves the feed disabled.
+func (h *Handler) SetFeed(feed *services.CalendarFeed, token secrets.Value) {
+	h.feed = feed
+	h.feedToken = token
+}
+
+// CalendarFeed serves the merged calendar as iCalendar. Wrong or missing
+// tokens get the same 404 as a disabled feed.
+func (h *Handler) CalendarFeed(w http.ResponseWriter, r *http.Request) {
+	token := r.PathValue("token")
+	if h.feed == nil || h.feedToken.IsZero() ||
+		subtle.ConstantTimeCompare([]byte(token), []byte(h.feedToken.Reveal())) != 1 {
+		http.NotFound(w, r)
+		return
+	}
+
+	// Encode fully before writing so a failure doesn't leave a truncated feed
+	var buf bytes.Buffer
+	if err := h.feed.Write(&buf); err != nil {
+		log.Printf("calendar feed: %v", err)
+		http.Error(w, "Internal Server Error", 500)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/calendar; charset=utf-8")
+	w.Header().Set("Content-Disposition", `inline; filename="flexpane.ics"`)
+	w.Header().Set("Cache-Control", "private, max-age=300")
+	w.Write(buf.Bytes())
+}
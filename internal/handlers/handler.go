@@ -1,17 +1,29 @@
 package handlers
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"html/template"
 	"net/http"
+	"time"
 
 	"flexpane/internal/models"
+	"flexpane/internal/secrets"
 	"flexpane/internal/services"
 )
 
 type Handler struct {
 	registry  *services.PaneRegistry
 	templates *template.Template
+	timeZone  string
+
+	feed      *services.CalendarFeed
+	feedToken secrets.Value
+
+	reminders *services.ReminderService
+	browser   *services.BrowserNotifier
+	events    *services.EventBus
 }
 
 func NewHandler(registry *services.PaneRegistry, templates *template.Template) *Handler {
@@ -21,8 +33,25 @@ func NewHandler(registry *services.PaneRegistry, templates *template.Template) *
 	}
 }
 
+// SetTimeZone records the configured user zone so the page can warn when the
+// browser is in a different one
+func (h *Handler) SetTimeZone(loc *time.Location) {
+	h.timeZone = ""
+	if loc != time.Local {
+		h.timeZone = loc.String()
+	}
+}
+
 func (h *Handler) Home(w http.ResponseWriter, r *http.Request) {
-	ctx := r.Context()
+	// Panes read view options such as ?calendar.view=week from the query
+	ctx := models.WithQuery(r.Context(), r.URL.Query())
+
+	// Changes from here on are replayed to the page, so none slip in between
+	// loading the panes and the page subscribing
+	var eventID string
+	if h.events != nil {
+		eventID = h.events.LastID()
+	}
 
 	// Get all enabled panes with their data
 	panes, err := h.registry.GetEnabledPanes(ctx)
@@ -33,8 +62,12 @@ func (h *Handler) Home(w http.ResponseWriter, r *http.Request) {
 
 	// Prepare template data
 	data := models.PageData{
-		Panes: panes,
+		Panes:         panes,
+		TimeZone:      h.timeZone,
+		Notifications: h.browser != nil,
+		EventID:       eventID,
 	}
+	data.Styles, data.Scripts = h.paneStatic(panes)
 
 	// Render template
 	w.Header().Set("Content-Type", "text/html; charset=utf-8")
@@ -44,13 +77,116 @@ func (h *Handler) Home(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
-// TODO: CONCURRENCY BUG - Index-based operations are unsafe with concurrent
-// reordering. Need to add unique IDs or implement proper locking before
-// multi-user support or background sync.
+// Pane renders one enabled pane as an HTML fragment, so the page can reload
+// it on its own, e.g. to retry after an error
+func (h *Handler) Pane(w http.ResponseWriter, r *http.Request) {
+	ctx := models.WithQuery(r.Context(), r.URL.Query())
+
+	pane, exists := h.registry.GetEnabledPane(ctx, r.PathValue("id"))
+	if !exists {
+		http.Error(w, "Pane not found", 404)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if err := h.templates.ExecuteTemplate(w, "pane-wrapper.html", pane); err != nil {
+		http.Error(w, "Internal Server Error", 500)
+		return
+	}
+}
+
+// PaneInfo describes a registered pane in the GET /api/panes listing
+type PaneInfo struct {
+	ID           string   `json:"id"`
+	Title        string   `json:"title"`
+	Enabled      bool     `json:"enabled"`
+	API          string   `json:"api"`
+	Capabilities []string `json:"capabilities"`
+}
+
+// Panes lists every registered pane and what it supports: "api" for panes
+// with their own API operations, "assets" for panes that ship templates and
+// static files, "lifecycle" for panes with startup or shutdown hooks and
+// "health" for panes that check themselves
+func (h *Handler) Panes(w http.ResponseWriter, r *http.Request) {
+	panes := h.registry.Panes()
+	infos := make([]PaneInfo, len(panes))
+	for i, pane := range panes {
+		capabilities := []string{}
+		if _, ok := pane.(models.APIHandler); ok {
+			capabilities = append(capabilities, "api")
+		}
+		if _, ok := pane.(models.AssetProvider); ok {
+			capabilities = append(capabilities, "assets")
+		}
+		if hasLifecycle(pane) {
+			capabilities = append(capabilities, "lifecycle")
+		}
+		if _, ok := pane.(models.HealthChecker); ok {
+			capabilities = append(capabilities, "health")
+		}
+		infos[i] = PaneInfo{
+			ID:           pane.ID(),
+			Title:        pane.Title(),
+			Enabled:      h.registry.IsEnabled(pane.ID()),
+			API:          "/api/panes/" + pane.ID(),
+			Capabilities: capabilities,
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(infos)
+}
+
+func hasLifecycle(pane models.Pane) bool {
+	_, initializer := pane.(models.Initializer)
+	_, starter := pane.(models.Starter)
+	_, stopper := pane.(models.Stopper)
+	return initializer || starter || stopper
+}
+
+// Health reports whether each enabled pane is working, with status 503 when
+// any isn't
+func (h *Handler) Health(w http.ResponseWriter, r *http.Request) {
+	panes := h.registry.Health(r.Context())
+	status := models.HealthOK
+	for _, pane := range panes {
+		if pane.Status != models.HealthOK {
+			status = models.HealthFailing
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if status != models.HealthOK {
+		w.WriteHeader(503)
+	}
+	json.NewEncoder(w).Encode(map[string]interface{}{"status": status, "panes": panes})
+}
+
+// PaneAPI serves /api/panes/{id} and /api/panes/{id}/{rest...} for any
+// registered pane, passing rest through to the pane
+func (h *Handler) PaneAPI(w http.ResponseWriter, r *http.Request) {
+	h.handlePaneAPI(r.PathValue("id"), w, r)
+}
+
+// TodosAPI is an alias for /api/panes/todos. Its index-based toggle is kept
+// for old clients; /api/panes/todos/items/{id} addresses todos by ID.
 func (h *Handler) TodosAPI(w http.ResponseWriter, r *http.Request) {
 	h.handlePaneAPI("todos", w, r)
 }
 
+// CalendarAPI is an alias for /api/panes/calendar, which exposes event
+// creation, editing and deletion
+func (h *Handler) CalendarAPI(w http.ResponseWriter, r *http.Request) {
+	h.handlePaneAPI("calendar", w, r)
+}
+
+// EmailAPI is an alias for /api/panes/email, which exposes message listing
+// and the full message view
+func (h *Handler) EmailAPI(w http.ResponseWriter, r *http.Request) {
+	h.handlePaneAPI("email", w, r)
+}
+
 // handlePaneAPI provides a generic API handler for panes that implement APIHandler
 func (h *Handler) handlePaneAPI(paneID string, w http.ResponseWriter, r *http.Request) {
 	pane, exists := h.registry.GetPane(paneID)
@@ -58,18 +194,15 @@ func (h *Handler) handlePaneAPI(paneID string, w http.ResponseWriter, r *http.Re
 		http.Error(w, "Pane not found", 404)
 		return
 	}
+	r = r.WithContext(models.WithQuery(r.Context(), r.URL.Query()))
 
-	// Check if pane supports API operations
-	if apiHandler, ok := pane.(models.APIHandler); ok {
-		if err := apiHandler.HandleAPI(w, r); err != nil {
-			http.Error(w, "Internal Server Error", 500)
+	// Every pane's data is served the same way, bounded like a page load
+	if r.Method == "GET" && r.PathValue("rest") == "" {
+		data, err := h.registry.LoadData(r.Context(), pane)
+		if errors.Is(err, context.DeadlineExceeded) {
+			http.Error(w, "Pane timed out", 504)
+			return
 		}
-		return
-	}
-
-	// Fallback for panes without API support
-	if r.Method == "GET" {
-		data, err := pane.GetData(r.Context())
 		if err != nil {
 			http.Error(w, "Internal Server Error", 500)
 			return
@@ -78,6 +211,14 @@ func (h *Handler) handlePaneAPI(paneID string, w http.ResponseWriter, r *http.Re
 		json.NewEncoder(w).Encode(data)
 		return
 	}
-	
+
+	// Check if pane supports API operations
+	if apiHandler, ok := pane.(models.APIHandler); ok {
+		if err := apiHandler.HandleAPI(w, r); err != nil {
+			http.Error(w, "Internal Server Error", 500)
+		}
+		return
+	}
+
 	http.Error(w, "Method Not Allowed", 405)
-}
\ No newline at end of file
+}
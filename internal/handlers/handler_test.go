@@ -1,14 +1,23 @@
 package handlers
 
 import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
 	"html/template"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
+	"path/filepath"
 	"strings"
 	"testing"
+	"time"
 
 	"flexpane/internal/models"
 	"flexpane/internal/panes"
+	"flexpane/internal/providers"
+	"flexpane/internal/secrets"
 	"flexpane/internal/services"
 )
 
@@ -186,4 +195,459 @@ func TestHandler_TodosAPI_MethodNotAllowed(t *testing.T) {
 // Helper function for string contains check
 func containsString(haystack, needle string) bool {
 	return strings.Contains(haystack, needle)
-}
\ No newline at end of file
+}
+func TestHandler_CalendarAPI_ReadOnlyProvider(t *testing.T) {
+	handler := setupTestHandler(t)
+
+	req := httptest.NewRequest("POST", "/api/calendar", strings.NewReader(`{"text": "Lunch tomorrow 12:30"}`))
+	recorder := httptest.NewRecorder()
+
+	handler.CalendarAPI(recorder, req)
+
+	if recorder.Code != http.StatusNotImplemented {
+		t.Errorf("Expected status 501, got %d", recorder.Code)
+	}
+}
+
+func TestHandler_CalendarAPI_QuickAdd(t *testing.T) {
+	tmpl := template.Must(template.New("layout.html").Parse(`<div>test</div>`))
+
+	provider, err := providers.NewLocalCalendarProvider(filepath.Join(t.TempDir(), "calendar.json"))
+	if err != nil {
+		t.Fatalf("Failed to create local calendar: %v", err)
+	}
+
+	registry := services.NewPaneRegistry()
+	registry.RegisterPane(panes.NewCalendarPane(provider))
+	handler := NewHandler(registry, tmpl)
+
+	req := httptest.NewRequest("POST", "/api/calendar", strings.NewReader(`{"text": "Lunch with Sam tomorrow 12:30 1h"}`))
+	recorder := httptest.NewRecorder()
+
+	handler.CalendarAPI(recorder, req)
+
+	if recorder.Code != http.StatusCreated {
+		t.Fatalf("Expected status 201, got %d: %s", recorder.Code, recorder.Body.String())
+	}
+
+	events, _ := provider.GetCalendarEvents()
+	if len(events) != 1 || events[0].Title != "Lunch with Sam" {
+		t.Fatalf("Expected quick-added event, got %+v", events)
+	}
+
+	req = httptest.NewRequest("DELETE", "/api/calendar?id="+events[0].ID, nil)
+	recorder = httptest.NewRecorder()
+
+	handler.CalendarAPI(recorder, req)
+
+	if recorder.Code != http.StatusOK {
+		t.Errorf("Expected status 200, got %d", recorder.Code)
+	}
+}
+
+func TestHandler_CalendarAPI_FreeBusy(t *testing.T) {
+	tmpl := template.Must(template.New("layout.html").Parse(`<div>test</div>`))
+
+	start := time.Date(2025, 1, 6, 9, 0, 0, 0, time.UTC)
+	provider := &MockDataProvider{events: []models.Event{
+		{ID: "1", Title: "Busy", Start: start, End: start.Add(3 * time.Hour)},
+	}}
+
+	calendarPane := panes.NewCalendarPane(provider)
+	calendarPane.SetTimeZones(time.UTC, nil)
+	calendarPane.SetFreeBusy(services.NewFreeBusyService([]services.EventSource{provider}, services.DefaultWorkingHours, time.UTC))
+
+	registry := services.NewPaneRegistry()
+	registry.RegisterPane(calendarPane)
+	handler := NewHandler(registry, tmpl)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/calendar/{rest...}", handler.CalendarAPI)
+
+	req := httptest.NewRequest("GET", "/api/calendar/freebusy?from=2025-01-06&to=2025-01-06&duration=1h", nil)
+	recorder := httptest.NewRecorder()
+
+	mux.ServeHTTP(recorder, req)
+
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", recorder.Code, recorder.Body.String())
+	}
+
+	var response struct {
+		Busy []services.Slot `json:"busy"`
+		Free []services.Slot `json:"free"`
+	}
+	if err := json.NewDecoder(recorder.Body).Decode(&response); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if len(response.Busy) != 1 || len(response.Free) != 1 || response.Free[0].Start.Hour() != 12 {
+		t.Errorf("Expected busy until 12:00 and free afterwards, got %+v", response)
+	}
+
+	// Ranges too long to search are refused
+	recorder = httptest.NewRecorder()
+	mux.ServeHTTP(recorder, httptest.NewRequest("GET", "/api/calendar/freebusy?from=0001-01-01&to=9999-12-31", nil))
+	if recorder.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 for a huge range, got %d", recorder.Code)
+	}
+}
+
+func TestHandler_CalendarFeed(t *testing.T) {
+	tmpl := template.Must(template.New("layout.html").Parse(`<div>test</div>`))
+
+	start := time.Date(2025, 1, 6, 9, 0, 0, 0, time.UTC)
+	provider := &MockDataProvider{events: []models.Event{
+		{ID: "1", Title: "Standup", Start: start, End: start.Add(15 * time.Minute)},
+	}}
+
+	handler := NewHandler(services.NewPaneRegistry(), tmpl)
+	handler.SetFeed(services.NewCalendarFeed([]services.EventSource{provider}, nil, "", time.UTC), secrets.NewValue("feed-token-123"))
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /feed/{token}/calendar.ics", handler.CalendarFeed)
+
+	recorder := httptest.NewRecorder()
+	mux.ServeHTTP(recorder, httptest.NewRequest("GET", "/feed/feed-token-123/calendar.ics", nil))
+
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", recorder.Code)
+	}
+	if !strings.HasPrefix(recorder.Header().Get("Content-Type"), "text/calendar") {
+		t.Errorf("Expected text/calendar, got %q", recorder.Header().Get("Content-Type"))
+	}
+	if !strings.Contains(recorder.Body.String(), "SUMMARY:Standup") {
+		t.Errorf("Expected event in feed, got %s", recorder.Body.String())
+	}
+
+	recorder = httptest.NewRecorder()
+	mux.ServeHTTP(recorder, httptest.NewRequest("GET", "/feed/wrong/calendar.ics", nil))
+	if recorder.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 for a wrong token, got %d", recorder.Code)
+	}
+}
+
+func TestHandler_SnoozeNotification(t *testing.T) {
+	tmpl := template.Must(template.New("layout.html").Parse(`<div>test</div>`))
+	handler := NewHandler(services.NewPaneRegistry(), tmpl)
+
+	recorder := httptest.NewRecorder()
+	handler.SnoozeNotification(recorder, httptest.NewRequest("POST", "/api/notifications/snooze", strings.NewReader(`{"id": "x", "for": "10m"}`)))
+	if recorder.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 with reminders disabled, got %d", recorder.Code)
+	}
+
+	rules := []services.ReminderRule{{On: services.ReminderOnEvent, Channels: []string{"browser"}}}
+	browser := services.NewBrowserNotifier(services.NewEventBus())
+	handler.SetReminders(services.NewReminderService(nil, nil, rules, map[string]services.Notifier{"browser": browser}, time.UTC), browser)
+
+	tests := []struct {
+		body string
+		code int
+	}{
+		{`{"id": "event:1:0", "for": "10m"}`, http.StatusNotFound}, // Never delivered
+		{`{"id": "event:1:0", "for": "later"}`, http.StatusBadRequest},
+		{`{"id": "event:1:0"}`, http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		recorder := httptest.NewRecorder()
+		handler.SnoozeNotification(recorder, httptest.NewRequest("POST", "/api/notifications/snooze", strings.NewReader(tt.body)))
+		if recorder.Code != tt.code {
+			t.Errorf("%s: expected %d, got %d", tt.body, tt.code, recorder.Code)
+		}
+	}
+}
+
+func TestHandler_EmailAPI_Message(t *testing.T) {
+	tmpl := template.Must(template.New("layout.html").Parse(`<div>test</div>`))
+
+	registry := services.NewPaneRegistry()
+	registry.RegisterPane(panes.NewEmailPane(providers.NewMockProvider()))
+	handler := NewHandler(registry, tmpl)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/email/{rest...}", handler.EmailAPI)
+
+	recorder := httptest.NewRecorder()
+	mux.ServeHTTP(recorder, httptest.NewRequest("GET", "/api/email/message?id=1", nil))
+
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", recorder.Code, recorder.Body.String())
+	}
+	var detail struct {
+		Subject string `json:"subject"`
+		Text    string `json:"text"`
+		HTML    string `json:"html"`
+	}
+	if err := json.NewDecoder(recorder.Body).Decode(&detail); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if detail.Subject != "Budget Meeting" || !strings.Contains(detail.Text, "budget estimates") || !strings.Contains(detail.HTML, "<b>budget estimates</b>") {
+		t.Errorf("Unexpected message detail %+v", detail)
+	}
+
+	recorder = httptest.NewRecorder()
+	mux.ServeHTTP(recorder, httptest.NewRequest("GET", "/api/email/message?id=missing", nil))
+	if recorder.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 for an unknown message, got %d", recorder.Code)
+	}
+}
+
+func TestHandler_EmailAPI_Actions(t *testing.T) {
+	tmpl := template.Must(template.New("layout.html").Parse(`<div>test</div>`))
+
+	provider := providers.NewMockProvider()
+	registry := services.NewPaneRegistry()
+	registry.RegisterPane(panes.NewEmailPane(provider))
+	handler := NewHandler(registry, tmpl)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/email", handler.EmailAPI)
+	mux.HandleFunc("/api/email/{rest...}", handler.EmailAPI)
+
+	requests := []struct {
+		method, path, body string
+		status             int
+	}{
+		{"PATCH", "/api/email?id=1", `{"read": true, "flagged": true}`, http.StatusOK},
+		{"PATCH", "/api/email?id=1", `{}`, http.StatusBadRequest},
+		{"POST", "/api/email/archive?id=2", ``, http.StatusOK},
+		{"DELETE", "/api/email?id=3", ``, http.StatusOK},
+		{"DELETE", "/api/email?id=3", ``, http.StatusNotFound},
+		{"DELETE", "/api/email", ``, http.StatusBadRequest},
+		{"GET", "/api/email/archive?id=1", ``, http.StatusMethodNotAllowed},
+	}
+	for _, tc := range requests {
+		recorder := httptest.NewRecorder()
+		mux.ServeHTTP(recorder, httptest.NewRequest(tc.method, tc.path, strings.NewReader(tc.body)))
+		if recorder.Code != tc.status {
+			t.Errorf("%s %s: expected status %d, got %d: %s", tc.method, tc.path, tc.status, recorder.Code, recorder.Body.String())
+		}
+	}
+
+	emails, _ := provider.GetEmails()
+	if len(emails) != 2 || emails[0].ID != "1" || !emails[0].Read || !emails[0].Flagged {
+		t.Errorf("Expected email 1 read and flagged and emails 2 and 3 gone, got %+v", emails)
+	}
+}
+
+func TestHandler_PanesAPI(t *testing.T) {
+	tmpl := template.Must(template.New("layout.html").Parse(`<div>test</div>`))
+
+	registry := services.NewPaneRegistry()
+	todoService := services.NewTodoService(filepath.Join(t.TempDir(), "todos.json"))
+	todo, err := todoService.Add(models.Todo{Message: "Write report"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	registry.RegisterPane(panes.NewTodoPane(todoService))
+	registry.RegisterPane(panes.NewCalendarPane(&MockDataProvider{}))
+	registry.SetEnabledPanes([]string{"todos"})
+	handler := NewHandler(registry, tmpl)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/panes", handler.Panes)
+	mux.HandleFunc("/api/panes/{id}", handler.PaneAPI)
+	mux.HandleFunc("/api/panes/{id}/{rest...}", handler.PaneAPI)
+	mux.HandleFunc("/api/todos", handler.TodosAPI)
+
+	serve := func(method, target, body string) *httptest.ResponseRecorder {
+		recorder := httptest.NewRecorder()
+		mux.ServeHTTP(recorder, httptest.NewRequest(method, target, strings.NewReader(body)))
+		return recorder
+	}
+
+	recorder := serve("GET", "/api/panes", "")
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", recorder.Code)
+	}
+	var infos []PaneInfo
+	if err := json.Unmarshal(recorder.Body.Bytes(), &infos); err != nil {
+		t.Fatal(err)
+	}
+	if len(infos) != 2 || infos[0].ID != "calendar" || infos[1].ID != "todos" {
+		t.Fatalf("Expected calendar and todos, got %+v", infos)
+	}
+	if infos[0].Enabled || !infos[1].Enabled {
+		t.Errorf("Expected only todos enabled, got %+v", infos)
+	}
+	if infos[1].API != "/api/panes/todos" || !containsString(strings.Join(infos[1].Capabilities, ","), "api") {
+		t.Errorf("Expected todos API capability, got %+v", infos[1])
+	}
+
+	// The generic route and the alias serve the same pane
+	for _, target := range []string{"/api/panes/todos", "/api/todos"} {
+		recorder := serve("GET", target, "")
+		if recorder.Code != http.StatusOK || !containsString(recorder.Body.String(), "Write report") {
+			t.Errorf("%s: expected todos, got %d %s", target, recorder.Code, recorder.Body.String())
+		}
+	}
+
+	recorder = serve("PATCH", "/api/panes/todos/items/"+todo.ID, `{"done": true}`)
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", recorder.Code, recorder.Body.String())
+	}
+	if !todoService.GetTodos()[0].Done {
+		t.Error("Expected todo to be done")
+	}
+
+	if recorder := serve("GET", "/api/panes/todos/items/missing", ""); recorder.Code != http.StatusNotFound {
+		t.Errorf("Expected status 404 for missing todo, got %d", recorder.Code)
+	}
+	if recorder := serve("GET", "/api/panes/unknown", ""); recorder.Code != http.StatusNotFound {
+		t.Errorf("Expected status 404 for unknown pane, got %d", recorder.Code)
+	}
+}
+
+// stuckProvider never returns, ignoring any deadline
+type stuckProvider struct {
+	MockDataProvider
+	release chan struct{}
+}
+
+func (p *stuckProvider) GetCalendarEvents() ([]models.Event, error) {
+	<-p.release
+	return nil, nil
+}
+
+func TestHandler_PaneAPI_Timeout(t *testing.T) {
+	provider := &stuckProvider{release: make(chan struct{})}
+	defer close(provider.release)
+
+	registry := services.NewPaneRegistry()
+	registry.RegisterPane(panes.NewCalendarPane(provider))
+	registry.SetTimeouts(map[string]time.Duration{"calendar": 20 * time.Millisecond})
+	handler := NewHandler(registry, template.New("test"))
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/calendar", handler.CalendarAPI)
+
+	recorder := httptest.NewRecorder()
+	mux.ServeHTTP(recorder, httptest.NewRequest("GET", "/api/calendar", nil))
+	if recorder.Code != http.StatusGatewayTimeout {
+		t.Errorf("Expected status 504, got %d", recorder.Code)
+	}
+}
+
+func TestHandler_Events(t *testing.T) {
+	bus := services.NewEventBus()
+	handler := NewHandler(services.NewPaneRegistry(), template.New("test"))
+	handler.SetEvents(bus)
+	server := httptest.NewServer(http.HandlerFunc(handler.Events))
+	defer server.Close()
+
+	// open connects and returns the stream's lines, without the blank lines
+	// between events
+	open := func(target, lastEventID string) (<-chan string, func()) {
+		req, _ := http.NewRequest("GET", server.URL+target, nil)
+		if lastEventID != "" {
+			req.Header.Set("Last-Event-ID", lastEventID)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.Header.Get("Content-Type") != "text/event-stream" {
+			t.Fatalf("Expected an event stream, got %s", resp.Header.Get("Content-Type"))
+		}
+		lines := make(chan string, 16)
+		go func() {
+			defer close(lines)
+			scanner := bufio.NewScanner(resp.Body)
+			for scanner.Scan() {
+				if scanner.Text() != "" {
+					lines <- scanner.Text()
+				}
+			}
+		}()
+		return lines, func() { resp.Body.Close() }
+	}
+	expect := func(lines <-chan string, want string) {
+		t.Helper()
+		select {
+		case line := <-lines:
+			if !strings.HasPrefix(line, want) {
+				t.Errorf("Expected %q, got %q", want, line)
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatalf("Timed out waiting for %q", want)
+		}
+	}
+
+	since := bus.LastID()
+	bus.Publish(services.EventTodosChanged, "todos")
+
+	// Changes since the page was rendered are replayed, then new ones follow
+	lines, closeStream := open("/events?since="+url.QueryEscape(since), "")
+	defer closeStream()
+	expect(lines, "id: ")
+	expect(lines, "event: change")
+	expect(lines, `data: {"id":`)
+	bus.Publish(services.EventEmailNew, "email")
+	expect(lines, "id: ")
+	expect(lines, "event: change")
+	expect(lines, "data: ")
+
+	// Notifications share the stream as their own event type
+	services.NewBrowserNotifier(bus).Notify(context.Background(), services.Notification{Title: "Standup"})
+	expect(lines, "id: ")
+	expect(lines, "event: notification")
+	expect(lines, "data: ")
+
+	// A reconnect from before a restart reloads everything
+	lines, closeReset := open("/events", "old-7")
+	defer closeReset()
+	expect(lines, "id: "+bus.LastID())
+	expect(lines, "event: reset")
+}
+
+// unhealthyPane fails its health check
+type unhealthyPane struct {
+	*panes.TodoPane
+}
+
+func (unhealthyPane) Health(ctx context.Context) error {
+	return errors.New("store unavailable")
+}
+
+func TestHandler_Health(t *testing.T) {
+	handler := setupTestHandler(t)
+	recorder := httptest.NewRecorder()
+	handler.Health(recorder, httptest.NewRequest("GET", "/health", nil))
+	if recorder.Code != http.StatusOK || !containsString(recorder.Body.String(), `"status":"ok"`) {
+		t.Errorf("Expected healthy, got %d %s", recorder.Code, recorder.Body.String())
+	}
+
+	registry := services.NewPaneRegistry()
+	todoService := services.NewTodoService(filepath.Join(t.TempDir(), "todos.json"))
+	registry.RegisterPane(unhealthyPane{panes.NewTodoPane(todoService)})
+	registry.SetEnabledPanes([]string{"todos"})
+	handler = NewHandler(registry, template.New("test"))
+
+	recorder = httptest.NewRecorder()
+	handler.Health(recorder, httptest.NewRequest("GET", "/health", nil))
+	if recorder.Code != http.StatusServiceUnavailable || !containsString(recorder.Body.String(), "store unavailable") {
+		t.Errorf("Expected 503 naming the failure, got %d %s", recorder.Code, recorder.Body.String())
+	}
+
+	// A pane whose provider can't be reached is failing
+	registry = services.NewPaneRegistry()
+	registry.RegisterPane(panes.NewCalendarPane(&failingProvider{}))
+	registry.SetEnabledPanes([]string{"calendar"})
+	handler = NewHandler(registry, template.New("test"))
+
+	recorder = httptest.NewRecorder()
+	handler.Health(recorder, httptest.NewRequest("GET", "/health", nil))
+	body := recorder.Body.String()
+	if recorder.Code != http.StatusServiceUnavailable || !containsString(body, `"status":"failing"`) || !containsString(body, "server unreachable") {
+		t.Errorf("Expected the calendar failing, got %d %s", recorder.Code, body)
+	}
+}
+
+// failingProvider can't reach its server
+type failingProvider struct {
+	MockDataProvider
+}
+
+func (*failingProvider) GetCalendarEvents() ([]models.Event, error) {
+	return nil, errors.New("server unreachable")
+}
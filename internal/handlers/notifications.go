@@ -0,0 +1,59 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"flexpane/internal/services"
+)
+
+// SetReminders enables browser notifications, which pages receive on
+// /events, and the snooze API. reminders may be nil when no reminder rules
+// are configured, in which case only email rules and snoozed items notify
+// and reminder snoozing is unavailable.
+func (h *Handler) SetReminders(reminders *services.ReminderService, browser *services.BrowserNotifier) {
+	h.reminders = reminders
+	h.browser = browser
+}
+
+// SnoozeNotification postpones a delivered reminder. The body names the
+// reminder and either a duration ("for": "10m") or a time ("until").
+func (h *Handler) SnoozeNotification(w http.ResponseWriter, r *http.Request) {
+	if h.reminders == nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	var req struct {
+		ID    string    `json:"id"`
+		For   string    `json:"for"`
+		Until time.Time `json:"until"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid JSON", 400)
+		return
+	}
+
+	until := req.Until
+	if req.For != "" {
+		d, err := time.ParseDuration(req.For)
+		if err != nil || d <= 0 {
+			http.Error(w, "Invalid snooze duration", 400)
+			return
+		}
+		until = time.Now().Add(d)
+	}
+	if until.IsZero() {
+		http.Error(w, "Snooze time required", 400)
+		return
+	}
+
+	if err := h.reminders.Snooze(req.ID, until); err != nil {
+		http.Error(w, "Reminder not found", 404)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"status": "snoozed", "until": until})
+}
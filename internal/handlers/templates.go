@@ -0,0 +1,186 @@
+package handlers
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"html/template"
+	"io/fs"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"reflect"
+	"strings"
+
+	"flexpane/internal/models"
+	"flexpane/internal/services"
+)
+
+// paneCount returns the Count a pane's data offers for its header, from a
+// map key or struct field, or nil when it has none
+func paneCount(data interface{}) *int {
+	v := reflect.ValueOf(data)
+	for v.Kind() == reflect.Pointer || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return nil
+		}
+		v = v.Elem()
+	}
+
+	var count reflect.Value
+	switch v.Kind() {
+	case reflect.Map:
+		if v.Type().Key().Kind() != reflect.String {
+			return nil
+		}
+		count = v.MapIndex(reflect.ValueOf("Count").Convert(v.Type().Key()))
+	case reflect.Struct:
+		count = v.FieldByName("Count")
+	}
+	for count.IsValid() && count.Kind() == reflect.Interface {
+		count = count.Elem()
+	}
+	var n int
+	switch {
+	case !count.IsValid():
+		return nil
+	case count.CanInt():
+		n = int(count.Int())
+	case count.CanUint():
+		n = int(count.Uint())
+	default:
+		return nil
+	}
+	return &n
+}
+
+// ParseTemplates parses the page templates in dir and the templates panes
+// ship themselves. Pane templates are named "panes/<file>" after their path
+// under dir or under a pane's templates/ directory, which is what
+// Pane.Template returns. Every registered pane's template must exist.
+func ParseTemplates(dir string, registry *services.PaneRegistry) (*template.Template, error) {
+	tmpl := template.New("flexpane")
+	tmpl.Funcs(template.FuncMap{
+		// renderPane executes a template chosen at run time, which the
+		// template action can't do
+		"renderPane": func(name string, data interface{}) (template.HTML, error) {
+			var b bytes.Buffer
+			if err := tmpl.ExecuteTemplate(&b, name, data); err != nil {
+				return "", err
+			}
+			return template.HTML(b.String()), nil
+		},
+		"paneCount": paneCount,
+	})
+
+	for _, pattern := range []string{"*.html", "components/*.html"} {
+		if _, err := tmpl.ParseGlob(filepath.Join(dir, pattern)); err != nil {
+			return nil, err
+		}
+	}
+	if err := parseNamed(tmpl, os.DirFS(filepath.Join(dir, "panes")), "panes/"); err != nil {
+		return nil, err
+	}
+
+	for _, pane := range registry.Panes() {
+		if assets := paneAssets(pane); assets != nil {
+			templates, err := fs.Sub(assets, "templates")
+			if err != nil {
+				return nil, err
+			}
+			if err := parseNamed(tmpl, templates, "panes/"); err != nil {
+				return nil, fmt.Errorf("pane %s: %w", pane.ID(), err)
+			}
+		}
+	}
+
+	for _, pane := range registry.Panes() {
+		if tmpl.Lookup(pane.Template()) == nil {
+			return nil, fmt.Errorf("pane %s: template %q not found", pane.ID(), pane.Template())
+		}
+	}
+	return tmpl, nil
+}
+
+// parseNamed parses every .html file in fsys as prefix plus its path
+func parseNamed(tmpl *template.Template, fsys fs.FS, prefix string) error {
+	return fs.WalkDir(fsys, ".", func(name string, entry fs.DirEntry, err error) error {
+		if errors.Is(err, fs.ErrNotExist) && name == "." {
+			return nil // Nothing to parse
+		}
+		if err != nil || entry.IsDir() || path.Ext(name) != ".html" {
+			return err
+		}
+		content, err := fs.ReadFile(fsys, name)
+		if err != nil {
+			return err
+		}
+		if _, err := tmpl.New(prefix + name).Parse(string(content)); err != nil {
+			return err
+		}
+		return nil
+	})
+}
+
+// paneAssets returns the files a pane ships, or nil
+func paneAssets(pane models.Pane) fs.FS {
+	if provider, ok := pane.(models.AssetProvider); ok {
+		return provider.Assets()
+	}
+	return nil
+}
+
+// PaneAsset serves a pane's static files from /static/panes/{id}/{file...}
+func (h *Handler) PaneAsset(w http.ResponseWriter, r *http.Request) {
+	pane, exists := h.registry.GetPane(r.PathValue("id"))
+	if !exists {
+		http.NotFound(w, r)
+		return
+	}
+	assets := paneAssets(pane)
+	if assets == nil {
+		http.NotFound(w, r)
+		return
+	}
+	static, err := fs.Sub(assets, "static")
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	// fs.FS rejects ".." and absolute paths, so files outside static/ are
+	// unreachable
+	name := r.PathValue("file")
+	if info, err := fs.Stat(static, name); err != nil || info.IsDir() {
+		http.NotFound(w, r)
+		return
+	}
+	http.ServeFileFS(w, r, static, name)
+}
+
+// paneStatic lists the stylesheets and scripts enabled panes ship, for the
+// page to link
+func (h *Handler) paneStatic(panes []models.PaneData) (styles, scripts []string) {
+	for _, data := range panes {
+		pane, exists := h.registry.GetPane(data.ID)
+		if !exists {
+			continue
+		}
+		assets := paneAssets(pane)
+		if assets == nil {
+			continue
+		}
+		styles = append(styles, staticURLs(pane.ID(), assets, "*.css")...)
+		scripts = append(scripts, staticURLs(pane.ID(), assets, "*.js")...)
+	}
+	return styles, scripts
+}
+
+func staticURLs(paneID string, assets fs.FS, pattern string) []string {
+	names, _ := fs.Glob(assets, "static/"+pattern)
+	urls := make([]string, len(names))
+	for i, name := range names {
+		urls[i] = "/static/panes/" + paneID + "/" + strings.TrimPrefix(name, "static/")
+	}
+	return urls
+}
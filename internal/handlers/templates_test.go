@@ -0,0 +1,106 @@
+package handlers
+
+import (
+	"context"
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"flexpane/internal/services"
+)
+
+// weatherPane is a drop-in pane that ships its own template and assets
+type weatherPane struct {
+	template string
+}
+
+func (p *weatherPane) ID() string       { return "weather" }
+func (p *weatherPane) Title() string    { return "Weather" }
+func (p *weatherPane) Template() string { return p.template }
+func (p *weatherPane) GetData(ctx context.Context) (interface{}, error) {
+	return struct{ Forecast string }{"Sunny"}, nil // No Count for the header
+}
+
+func (p *weatherPane) Assets() fs.FS {
+	return fstest.MapFS{
+		"templates/weather.html": {Data: []byte(`<p class="forecast">{{.Forecast}}</p>`)},
+		"static/weather.css":     {Data: []byte(`.forecast { color: orange; }`)},
+		"static/weather.js":      {Data: []byte(`console.log("weather");`)},
+		"secret.txt":             {Data: []byte(`not served`)},
+	}
+}
+
+func TestParseTemplates_PaneAssets(t *testing.T) {
+	registry := services.NewPaneRegistry()
+	registry.RegisterPane(&weatherPane{template: "panes/weather.html"})
+	registry.SetEnabledPanes([]string{"weather"})
+
+	tmpl, err := ParseTemplates("../../web/templates", registry)
+	if err != nil {
+		t.Fatalf("ParseTemplates failed: %v", err)
+	}
+	handler := NewHandler(registry, tmpl)
+
+	recorder := httptest.NewRecorder()
+	handler.Home(recorder, httptest.NewRequest("GET", "/", nil))
+	body := recorder.Body.String()
+	if strings.Contains(body, "pane-count") {
+		t.Error("Expected no count in the header of a pane without one")
+	}
+	for _, want := range []string{
+		`<p class="forecast">Sunny</p>`,
+		`<link rel="stylesheet" href="/static/panes/weather/weather.css">`,
+		`<script src="/static/panes/weather/weather.js"></script>`,
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("Expected page to contain %s", want)
+		}
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /static/panes/{id}/{file...}", handler.PaneAsset)
+	for path, code := range map[string]int{
+		"/static/panes/weather/weather.css":       http.StatusOK,
+		"/static/panes/weather/%2e%2e/secret.txt": http.StatusNotFound,
+		"/static/panes/calendar/style.css":        http.StatusNotFound,
+	} {
+		recorder := httptest.NewRecorder()
+		mux.ServeHTTP(recorder, httptest.NewRequest("GET", path, nil))
+		if recorder.Code != code {
+			t.Errorf("%s: expected %d, got %d", path, code, recorder.Code)
+		}
+	}
+}
+
+func TestPaneCount(t *testing.T) {
+	three := 3
+	for _, test := range []struct {
+		data interface{}
+		want *int
+	}{
+		{map[string]interface{}{"Count": 3}, &three},
+		{struct{ Count int }{3}, &three},
+		{&struct{ Count uint }{3}, &three},
+		{map[string]interface{}{"Count": "3"}, nil},
+		{map[string]interface{}{"Items": 3}, nil},
+		{[]int{1, 2, 3}, nil},
+		{nil, nil},
+	} {
+		got := paneCount(test.data)
+		if (got == nil) != (test.want == nil) || (got != nil && *got != *test.want) {
+			t.Errorf("paneCount(%#v) = %v, want %v", test.data, got, test.want)
+		}
+	}
+}
+
+func TestParseTemplates_MissingPaneTemplate(t *testing.T) {
+	registry := services.NewPaneRegistry()
+	registry.RegisterPane(&weatherPane{template: "panes/forecast.html"})
+
+	if _, err := ParseTemplates("../../web/templates", registry); err == nil || !strings.Contains(err.Error(), "forecast.html") {
+		t.Errorf("Expected an error naming the missing template, got %v", err)
+	}
+}
@@ -0,0 +1,143 @@
+package ical
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"strings"
+	"time"
+
+	"flexpane/internal/models"
+)
+
+// Encoder writes iCalendar content lines with the required CRLF endings and
+// 75-octet line folding
+type Encoder struct {
+	w   io.Writer
+	err error
+	now func() time.Time
+}
+
+func NewEncoder(w io.Writer) *Encoder {
+	return &Encoder{w: w, now: time.Now}
+}
+
+// Begin opens a component
+func (e *Encoder) Begin(name string) {
+	e.Line("BEGIN:" + name)
+}
+
+// End closes a component
+func (e *Encoder) End(name string) {
+	e.Line("END:" + name)
+}
+
+// Text writes a property with an escaped text value, skipping empty values
+func (e *Encoder) Text(name, value string) {
+	if value == "" {
+		return
+	}
+	e.Line(name + ":" + escapeText(value))
+}
+
+// Time writes a DATE-TIME property in UTC, or a DATE when allDay is set
+func (e *Encoder) Time(name string, t time.Time, allDay bool) {
+	if allDay {
+		e.Line(name + ";VALUE=DATE:" + t.Format(dateLayout))
+		return
+	}
+	e.Line(name + ":" + t.UTC().Format(utcLayout))
+}
+
+// Line writes one raw content line, folding it if needed
+func (e *Encoder) Line(line string) {
+	if e.err != nil {
+		return
+	}
+
+	var b strings.Builder
+	width := 0
+	for _, r := range line {
+		size := len(string(r))
+		if width+size > 75 {
+			b.WriteString("\r\n ")
+			width = 1
+		}
+		b.WriteRune(r)
+		width += size
+	}
+	b.WriteString("\r\n")
+
+	_, e.err = io.WriteString(e.w, b.String())
+}
+
+// Event writes a complete VEVENT. UID is required, so an event without an ID
+// gets one derived from its title and times, which stays the same each time
+// the event is written.
+func (e *Encoder) Event(event models.Event) {
+	uid := event.ID
+	if uid == "" {
+		uid = stableUID(event)
+	}
+
+	e.Begin("VEVENT")
+	e.Text("UID", uid)
+	e.Line("DTSTAMP:" + e.now().UTC().Format(utcLayout))
+	e.Time("DTSTART", event.Start, event.AllDay)
+	e.Time("DTEND", event.End, event.AllDay)
+	e.Text("SUMMARY", event.Title)
+	e.Text("LOCATION", event.Location)
+	e.Text("DESCRIPTION", event.Description)
+	e.End("VEVENT")
+}
+
+// Todo writes a complete VTODO
+func (e *Encoder) Todo(todo models.Todo) {
+	e.Begin("VTODO")
+	e.Text("UID", todo.ID)
+	e.Line("DTSTAMP:" + e.now().UTC().Format(utcLayout))
+	e.Text("SUMMARY", todo.Message)
+	if todo.Due != nil {
+		e.Time("DUE", *todo.Due, todo.DueAllDay)
+	}
+	if todo.Done {
+		e.Line("STATUS:COMPLETED")
+	} else {
+		e.Line("STATUS:NEEDS-ACTION")
+	}
+	e.End("VTODO")
+}
+
+// Err returns the first write error
+func (e *Encoder) Err() error {
+	return e.err
+}
+
+// EncodeEvents writes a VCALENDAR containing the given events
+func EncodeEvents(w io.Writer, events []models.Event) error {
+	encoder := NewEncoder(w)
+	encoder.Begin("VCALENDAR")
+	encoder.Line("VERSION:2.0")
+	encoder.Line("PRODID:" + ProdID)
+	for _, event := range events {
+		encoder.Event(event)
+	}
+	encoder.End("VCALENDAR")
+	return encoder.Err()
+}
+
+// stableUID derives a UID from an event's content
+func stableUID(event models.Event) string {
+	sum := sha256.Sum256([]byte(event.Title + "\x00" + event.Start.UTC().Format(time.RFC3339) + "\x00" + event.End.UTC().Format(time.RFC3339)))
+	return hex.EncodeToString(sum[:16]) + "@flexpane"
+}
+
+func escapeText(value string) string {
+	return strings.NewReplacer(
+		`\`, `\\`,
+		";", `\;`,
+		",", `\,`,
+		"\r\n", `\n`,
+		"\n", `\n`,
+	).Replace(value)
+}
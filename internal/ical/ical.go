@@ -0,0 +1,312 @@
+// Package ical reads and writes the subset of iCalendar (RFC 5545) Flexpane
+// needs: VEVENTs with UTC, zoned or all-day times.
+package ical
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"flexpane/internal/models"
+)
+
+// ProdID identifies Flexpane as the producer of generated calendars
+const ProdID = "-//Flexpane//Flexpane//EN"
+
+const (
+	utcLayout   = "20060102T150405Z"
+	localLayout = "20060102T150405"
+	dateLayout  = "20060102"
+)
+
+// Property is a single content line, e.g. DTSTART;TZID=Europe/London:20250106T090000
+type Property struct {
+	Name   string
+	Params map[string]string
+	Value  string
+}
+
+// Component is a BEGIN/END block with its properties and children
+type Component struct {
+	Name       string
+	Properties []Property
+	Children   []*Component
+}
+
+// Get returns the first property with the given name
+func (c *Component) Get(name string) (Property, bool) {
+	for _, prop := range c.Properties {
+		if prop.Name == name {
+			return prop, true
+		}
+	}
+	return Property{}, false
+}
+
+// Value returns the first value of a property, unescaped when it is text
+func (c *Component) Value(name string) string {
+	prop, _ := c.Get(name)
+	return unescapeText(prop.Value)
+}
+
+// Parse reads a stream of iCalendar data into its top-level components
+func Parse(r io.Reader) ([]*Component, error) {
+	lines, err := unfold(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var roots []*Component
+	var stack []*Component
+
+	for number, line := range lines {
+		if line == "" {
+			continue
+		}
+		prop, err := parseLine(line)
+		if err != nil {
+			return nil, fmt.Errorf("ical line %d: %w", number+1, err)
+		}
+
+		switch prop.Name {
+		case "BEGIN":
+			component := &Component{Name: strings.ToUpper(prop.Value)}
+			if len(stack) > 0 {
+				parent := stack[len(stack)-1]
+				parent.Children = append(parent.Children, component)
+			} else {
+				roots = append(roots, component)
+			}
+			stack = append(stack, component)
+
+		case "END":
+			if len(stack) == 0 || stack[len(stack)-1].Name != strings.ToUpper(prop.Value) {
+				return nil, fmt.Errorf("ical line %d: unexpected END:%s", number+1, prop.Value)
+			}
+			stack = stack[:len(stack)-1]
+
+		default:
+			if len(stack) == 0 {
+				return nil, fmt.Errorf("ical line %d: property outside of a component", number+1)
+			}
+			current := stack[len(stack)-1]
+			current.Properties = append(current.Properties, prop)
+		}
+	}
+
+	if len(stack) > 0 {
+		return nil, fmt.Errorf("ical: unterminated %s", stack[len(stack)-1].Name)
+	}
+	return roots, nil
+}
+
+// DecodeEvents parses iCalendar data and returns every VEVENT it contains
+func DecodeEvents(r io.Reader) ([]models.Event, error) {
+	roots, err := Parse(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var events []models.Event
+	var walk func(components []*Component) error
+	walk = func(components []*Component) error {
+		for _, component := range components {
+			if component.Name == "VEVENT" {
+				event, err := EventFromComponent(component)
+				if err != nil {
+					return err
+				}
+				events = append(events, event)
+				continue
+			}
+			if err := walk(component.Children); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	return events, walk(roots)
+}
+
+// EventFromComponent converts a VEVENT into an Event
+func EventFromComponent(component *Component) (models.Event, error) {
+	event := models.Event{
+		ID:          component.Value("UID"),
+		Title:       component.Value("SUMMARY"),
+		Location:    component.Value("LOCATION"),
+		Description: component.Value("DESCRIPTION"),
+	}
+
+	start, ok := component.Get("DTSTART")
+	if !ok {
+		return event, fmt.Errorf("ical: VEVENT %q has no DTSTART", event.ID)
+	}
+	var err error
+	event.Start, event.AllDay, err = ParseTime(start)
+	if err != nil {
+		return event, err
+	}
+	if tzid := start.Params["TZID"]; tzid != "" && event.Start.Location().String() == tzid {
+		event.TimeZone = tzid
+	}
+
+	if end, ok := component.Get("DTEND"); ok {
+		event.End, _, err = ParseTime(end)
+		if err != nil {
+			return event, err
+		}
+	} else if duration, ok := component.Get("DURATION"); ok {
+		d, err := ParseDuration(duration.Value)
+		if err != nil {
+			return event, err
+		}
+		event.End = event.Start.Add(d)
+	} else if event.AllDay {
+		event.End = event.Start.AddDate(0, 0, 1)
+	} else {
+		event.End = event.Start
+	}
+
+	return event, nil
+}
+
+// ParseTime parses a DATE or DATE-TIME property value. The second result
+// reports whether the value was a date without a time.
+func ParseTime(prop Property) (time.Time, bool, error) {
+	value := prop.Value
+
+	if prop.Params["VALUE"] == "DATE" || len(value) == len(dateLayout) {
+		t, err := time.ParseInLocation(dateLayout, value, time.Local)
+		return t, true, err
+	}
+
+	if strings.HasSuffix(value, "Z") {
+		t, err := time.Parse(utcLayout, value)
+		return t, false, err
+	}
+
+	loc := time.Local
+	if tzid := prop.Params["TZID"]; tzid != "" {
+		if zone, err := time.LoadLocation(tzid); err == nil {
+			loc = zone
+		}
+	}
+	t, err := time.ParseInLocation(localLayout, value, loc)
+	return t, false, err
+}
+
+// ParseDuration parses an RFC 5545 duration such as PT1H30M or P1D
+func ParseDuration(value string) (time.Duration, error) {
+	negative := strings.HasPrefix(value, "-")
+	value = strings.TrimLeft(value, "+-")
+	if !strings.HasPrefix(value, "P") {
+		return 0, fmt.Errorf("ical: invalid duration %q", value)
+	}
+
+	var total time.Duration
+	var number int
+	inTime := false
+	for _, r := range value[1:] {
+		switch {
+		case r >= '0' && r <= '9':
+			number = number*10 + int(r-'0')
+			continue
+		case r == 'T':
+			inTime = true
+			continue
+		case r == 'W':
+			total += time.Duration(number) * 7 * 24 * time.Hour
+		case r == 'D':
+			total += time.Duration(number) * 24 * time.Hour
+		case r == 'H' && inTime:
+			total += time.Duration(number) * time.Hour
+		case r == 'M' && inTime:
+			total += time.Duration(number) * time.Minute
+		case r == 'S' && inTime:
+			total += time.Duration(number) * time.Second
+		default:
+			return 0, fmt.Errorf("ical: invalid duration %q", value)
+		}
+		number = 0
+	}
+
+	if negative {
+		total = -total
+	}
+	return total, nil
+}
+
+func unfold(r io.Reader) ([]string, error) {
+	var lines []string
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 4*1024*1024)
+
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if (strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t")) && len(lines) > 0 {
+			lines[len(lines)-1] += line[1:]
+			continue
+		}
+		lines = append(lines, line)
+	}
+	return lines, scanner.Err()
+}
+
+func parseLine(line string) (Property, error) {
+	// The value starts at the first colon that isn't inside a quoted parameter
+	inQuotes := false
+	colon := -1
+	for i, r := range line {
+		if r == '"' {
+			inQuotes = !inQuotes
+		} else if r == ':' && !inQuotes {
+			colon = i
+			break
+		}
+	}
+	if colon < 0 {
+		return Property{}, fmt.Errorf("missing ':' in %q", line)
+	}
+
+	head, value := line[:colon], line[colon+1:]
+	parts := strings.Split(head, ";")
+	prop := Property{
+		Name:   strings.ToUpper(parts[0]),
+		Params: make(map[string]string),
+		Value:  value,
+	}
+	for _, param := range parts[1:] {
+		key, val, _ := strings.Cut(param, "=")
+		prop.Params[strings.ToUpper(key)] = strings.Trim(val, `"`)
+	}
+	return prop, nil
+}
+
+func unescapeText(value string) string {
+	if !strings.Contains(value, `\`) {
+		return value
+	}
+	var b strings.Builder
+	escaped := false
+	for _, r := range value {
+		if escaped {
+			switch r {
+			case 'n', 'N':
+				b.WriteByte('\n')
+			default:
+				b.WriteRune(r)
+			}
+			escaped = false
+			continue
+		}
+		if r == '\\' {
+			escaped = true
+			continue
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
@@ -0,0 +1,121 @@
+package ical
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"flexpane/internal/models"
+)
+
+func TestEncodeDecodeRoundTrip(t *testing.T) {
+	start := time.Date(2025, 1, 6, 9, 0, 0, 0, time.UTC)
+	events := []models.Event{
+		{
+			ID:          "standup@flexpane",
+			Title:       "Standup; daily, quick",
+			Start:       start,
+			End:         start.Add(15 * time.Minute),
+			Location:    "Room A",
+			Description: "Line one\nLine two " + strings.Repeat("long ", 30),
+		},
+		{
+			ID:     "offsite@flexpane",
+			Title:  "Offsite",
+			Start:  time.Date(2025, 1, 8, 0, 0, 0, 0, time.Local),
+			End:    time.Date(2025, 1, 10, 0, 0, 0, 0, time.Local),
+			AllDay: true,
+		},
+	}
+
+	var buf bytes.Buffer
+	if err := EncodeEvents(&buf, events); err != nil {
+		t.Fatalf("EncodeEvents failed: %v", err)
+	}
+
+	for _, line := range strings.Split(buf.String(), "\r\n") {
+		if len(line) > 75 {
+			t.Errorf("Line longer than 75 octets: %q", line)
+		}
+	}
+
+	decoded, err := DecodeEvents(&buf)
+	if err != nil {
+		t.Fatalf("DecodeEvents failed: %v", err)
+	}
+	if len(decoded) != 2 {
+		t.Fatalf("Expected 2 events, got %d", len(decoded))
+	}
+
+	if decoded[0].Title != events[0].Title || decoded[0].Description != events[0].Description {
+		t.Errorf("Text did not survive round trip: %+v", decoded[0])
+	}
+	if !decoded[0].Start.Equal(start) || !decoded[0].End.Equal(events[0].End) {
+		t.Errorf("Times did not survive round trip: %v - %v", decoded[0].Start, decoded[0].End)
+	}
+	if !decoded[1].AllDay || !decoded[1].Start.Equal(events[1].Start) || !decoded[1].End.Equal(events[1].End) {
+		t.Errorf("All-day event did not survive round trip: %+v", decoded[1])
+	}
+}
+
+func TestDecodeEvents_ZonesAndDurations(t *testing.T) {
+	data := "BEGIN:VCALENDAR\r\n" +
+		"BEGIN:VEVENT\r\n" +
+		"UID:london\r\n" +
+		"SUMMARY:Call with\r\n  London\r\n" +
+		"DTSTART;TZID=Europe/London:20250706T090000\r\n" +
+		"DURATION:PT1H30M\r\n" +
+		"END:VEVENT\r\n" +
+		"END:VCALENDAR\r\n"
+
+	events, err := DecodeEvents(strings.NewReader(data))
+	if err != nil {
+		t.Fatalf("DecodeEvents failed: %v", err)
+	}
+	if len(events) != 1 {
+		t.Fatalf("Expected 1 event, got %d", len(events))
+	}
+
+	event := events[0]
+	if event.Title != "Call with London" {
+		t.Errorf("Expected folded title to be joined, got %q", event.Title)
+	}
+	// 09:00 BST is 08:00 UTC
+	if want := time.Date(2025, 7, 6, 8, 0, 0, 0, time.UTC); !event.Start.Equal(want) {
+		t.Errorf("Expected start %v, got %v", want, event.Start.UTC())
+	}
+	if event.End.Sub(event.Start) != 90*time.Minute {
+		t.Errorf("Expected 90 minute duration, got %v", event.End.Sub(event.Start))
+	}
+}
+
+func TestParse_Unterminated(t *testing.T) {
+	if _, err := Parse(strings.NewReader("BEGIN:VCALENDAR\r\nBEGIN:VEVENT\r\n")); err == nil {
+		t.Error("Expected error for unterminated component")
+	}
+}
+
+func TestEncoder_EventWithoutID(t *testing.T) {
+	event := models.Event{Title: "Lunch", Start: time.Date(2025, 1, 6, 12, 0, 0, 0, time.UTC), End: time.Date(2025, 1, 6, 13, 0, 0, 0, time.UTC)}
+
+	encode := func() string {
+		var buf bytes.Buffer
+		if err := EncodeEvents(&buf, []models.Event{event}); err != nil {
+			t.Fatalf("EncodeEvents failed: %v", err)
+		}
+		decoded, err := DecodeEvents(&buf)
+		if err != nil || len(decoded) != 1 {
+			t.Fatalf("DecodeEvents failed: %v %+v", err, decoded)
+		}
+		return decoded[0].ID
+	}
+
+	uid := encode()
+	if uid == "" {
+		t.Fatal("Expected a UID to be generated")
+	}
+	if again := encode(); again != uid {
+		t.Errorf("Expected the same UID each time, got %q then %q", uid, again)
+	}
+}
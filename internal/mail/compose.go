@@ -0,0 +1,171 @@
+package mail
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"mime"
+	"mime/quotedprintable"
+	netmail "net/mail"
+	"strings"
+	"time"
+)
+
+// Outgoing is a plain text message to send
+type Outgoing struct {
+	From       string
+	To         []string
+	Cc         []string
+	Bcc        []string // Recipients only; never written to the headers
+	Subject    string
+	Body       string
+	InReplyTo  string
+	References []string
+}
+
+// Recipients returns the bare addresses of every recipient, for the SMTP
+// envelope
+func (o *Outgoing) Recipients() ([]string, error) {
+	var addrs []string
+	for _, list := range [][]string{o.To, o.Cc, o.Bcc} {
+		for _, value := range list {
+			addr, err := netmail.ParseAddress(value)
+			if err != nil {
+				return nil, fmt.Errorf("invalid recipient %q: %w", value, err)
+			}
+			addrs = append(addrs, addr.Address)
+		}
+	}
+	if len(addrs) == 0 {
+		return nil, errors.New("at least one recipient is required")
+	}
+	return addrs, nil
+}
+
+// Bytes renders the message as quoted-printable UTF-8 text with a fresh
+// Message-ID, which is returned too
+func (o *Outgoing) Bytes(now time.Time) ([]byte, string, error) {
+	from, err := netmail.ParseAddress(o.From)
+	if err != nil {
+		return nil, "", fmt.Errorf("invalid sender %q: %w", o.From, err)
+	}
+	if _, err := o.Recipients(); err != nil {
+		return nil, "", err
+	}
+
+	messageID, err := newMessageID(from.Address)
+	if err != nil {
+		return nil, "", err
+	}
+
+	var b bytes.Buffer
+	header := func(name, value string) {
+		b.WriteString(name + ": " + value + "\r\n")
+	}
+	header("From", from.String())
+	if len(o.To) > 0 {
+		header("To", addressHeader(o.To))
+	}
+	if len(o.Cc) > 0 {
+		header("Cc", addressHeader(o.Cc))
+	}
+	header("Subject", encodeHeader(o.Subject))
+	header("Date", now.Format(time.RFC1123Z))
+	header("Message-ID", "<"+messageID+">")
+	if o.InReplyTo != "" {
+		header("In-Reply-To", "<"+bareID(o.InReplyTo)+">")
+	}
+	if len(o.References) > 0 {
+		refs := make([]string, len(o.References))
+		for i, ref := range o.References {
+			refs[i] = "<" + bareID(ref) + ">"
+		}
+		header("References", strings.Join(refs, " "))
+	}
+	header("MIME-Version", "1.0")
+	header("Content-Type", "text/plain; charset=utf-8")
+	header("Content-Transfer-Encoding", "quoted-printable")
+	b.WriteString("\r\n")
+
+	body := strings.ReplaceAll(strings.ReplaceAll(o.Body, "\r\n", "\n"), "\n", "\r\n")
+	if !strings.HasSuffix(body, "\r\n") {
+		body += "\r\n"
+	}
+	qp := quotedprintable.NewWriter(&b)
+	if _, err := qp.Write([]byte(body)); err != nil {
+		return nil, "", err
+	}
+	if err := qp.Close(); err != nil {
+		return nil, "", err
+	}
+
+	return b.Bytes(), messageID, nil
+}
+
+// ReplySubject prefixes "Re: " unless the subject already has it
+func ReplySubject(subject string) string {
+	if strings.HasPrefix(strings.ToLower(strings.TrimSpace(subject)), "re:") {
+		return subject
+	}
+	return "Re: " + subject
+}
+
+// ForwardSubject prefixes "Fwd: "
+func ForwardSubject(subject string) string {
+	lower := strings.ToLower(strings.TrimSpace(subject))
+	if strings.HasPrefix(lower, "fwd:") || strings.HasPrefix(lower, "fw:") {
+		return subject
+	}
+	return "Fwd: " + subject
+}
+
+// Quote marks each line of text as quoted, for reply bodies
+func Quote(text string) string {
+	lines := strings.Split(strings.TrimRight(text, "\r\n"), "\n")
+	for i, line := range lines {
+		line = strings.TrimRight(line, "\r")
+		if strings.HasPrefix(line, ">") {
+			lines[i] = ">" + line
+		} else {
+			lines[i] = "> " + line
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+func newMessageID(from string) (string, error) {
+	domain := "flexpane"
+	if _, host, found := strings.Cut(from, "@"); found && host != "" {
+		domain = host
+	}
+	random := make([]byte, 12)
+	if _, err := rand.Read(random); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(random) + "@" + domain, nil
+}
+
+func addressHeader(values []string) string {
+	formatted := make([]string, 0, len(values))
+	for _, value := range values {
+		addr, err := netmail.ParseAddress(value)
+		if err != nil {
+			continue // Recipients already rejected invalid addresses
+		}
+		formatted = append(formatted, addr.String())
+	}
+	return strings.Join(formatted, ", ")
+}
+
+// encodeHeader keeps user text from breaking out of a header line and
+// encodes non-ASCII text
+func encodeHeader(value string) string {
+	return mime.QEncoding.Encode("utf-8", strings.NewReplacer("\r", " ", "\n", " ").Replace(value))
+}
+
+// bareID strips angle brackets and line breaks from a message ID
+func bareID(value string) string {
+	return strings.NewReplacer("\r", "", "\n", "", "<", "", ">", "", " ", "").Replace(value)
+}
@@ -0,0 +1,186 @@
+package mail
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+const multipartMessage = "Message-ID: <abc@example.com>\r\n" +
+	"In-Reply-To: <parent@example.com>\r\n" +
+	"References: <root@example.com> <parent@example.com>\r\n" +
+	"From: =?utf-8?q?Ren=C3=A9e?= <renee@example.com>\r\n" +
+	"To: a@example.com, \"Bob B\" <b@example.com>\r\n" +
+	"Subject: =?utf-8?b?UmU6IEJ1ZGdldCDinJM=?=\r\n" +
+	"Date: Mon, 06 Jan 2025 09:00:00 +0000\r\n" +
+	"MIME-Version: 1.0\r\n" +
+	"Content-Type: multipart/mixed; boundary=outer\r\n" +
+	"\r\n" +
+	"--outer\r\n" +
+	"Content-Type: multipart/alternative; boundary=inner\r\n" +
+	"\r\n" +
+	"--inner\r\n" +
+	"Content-Type: text/plain; charset=iso-8859-1\r\n" +
+	"Content-Transfer-Encoding: quoted-printable\r\n" +
+	"\r\n" +
+	"Caf=E9 at noon?\r\n" +
+	"--inner\r\n" +
+	"Content-Type: text/html; charset=utf-8\r\n" +
+	"\r\n" +
+	"<p>Café at <b>noon</b>?</p>\r\n" +
+	"--inner--\r\n" +
+	"--outer\r\n" +
+	"Content-Type: application/pdf; name=\"budget.pdf\"\r\n" +
+	"Content-Disposition: attachment; filename=\"budget.pdf\"\r\n" +
+	"Content-Transfer-Encoding: base64\r\n" +
+	"\r\n" +
+	"JVBERi0x\r\n" +
+	"LjQK\r\n" +
+	"--outer--\r\n"
+
+func TestParse_Multipart(t *testing.T) {
+	msg, err := Parse(strings.NewReader(multipartMessage))
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+
+	if msg.MessageID != "abc@example.com" || msg.InReplyTo != "parent@example.com" || len(msg.References) != 2 {
+		t.Errorf("Unexpected threading headers %q %q %v", msg.MessageID, msg.InReplyTo, msg.References)
+	}
+	if msg.Subject != "Re: Budget ✓" || msg.From != "Renée <renee@example.com>" {
+		t.Errorf("Encoded headers not decoded: %q / %q", msg.Subject, msg.From)
+	}
+	if len(msg.To) != 2 || msg.To[1] != "Bob B <b@example.com>" {
+		t.Errorf("Unexpected recipients %v", msg.To)
+	}
+	if msg.Date.Day() != 6 {
+		t.Errorf("Unexpected date %v", msg.Date)
+	}
+	if strings.TrimSpace(msg.Text) != "Café at noon?" {
+		t.Errorf("Unexpected text body %q", msg.Text)
+	}
+	if !strings.Contains(msg.HTML, "<b>noon</b>") {
+		t.Errorf("Unexpected HTML body %q", msg.HTML)
+	}
+	if len(msg.Attachments) != 1 || msg.Attachments[0].Filename != "budget.pdf" || msg.Attachments[0].Size != 9 {
+		t.Errorf("Unexpected attachments %+v", msg.Attachments)
+	}
+}
+
+func TestParse_PlainDefault(t *testing.T) {
+	msg, err := Parse(strings.NewReader("Subject: Hi\r\n\r\nJust text.\r\n"))
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	if msg.Text != "Just text.\r\n" || msg.HTML != "" {
+		t.Errorf("Expected plain text body, got %q / %q", msg.Text, msg.HTML)
+	}
+}
+
+func TestSanitize(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{"script removed", `<p>Hi<script>alert("<b>x</b>")</script></p>`, `<p>Hi</p>`},
+		{"style and head removed", `<html><head><style>p{}</style></head><body><p>Hi</p></body></html>`, `<p>Hi</p>`},
+		{"event handlers dropped", `<b onclick="steal()" class="x" style="color:red">bold</b>`, `<b>bold</b>`},
+		{"javascript links dropped", `<a href="javascript:alert(1)">x</a>`, `<a target="_blank" rel="noopener noreferrer">x</a>`},
+		{"web links kept", `<a href="https://example.com/?a=1&amp;b=2">x</a>`, `<a href="https://example.com/?a=1&amp;b=2" target="_blank" rel="noopener noreferrer">x</a>`},
+		{"stray end tags ignored", `</div></div><p>text`, `<p>text</p>`},
+		{"text escaped", `1 < 2 &amp; "quotes"`, `1 &lt; 2 &amp; &#34;quotes&#34;`},
+		{"comments dropped", `a<!-- <script>x</script> -->b`, `ab`},
+		{"unclosed head", `<head><meta charset="utf-8"><body><p>Hi</p>`, `<p>Hi</p>`},
+		{"iframes removed", `<iframe src="https://evil.example"></iframe>ok`, `ok`},
+		{"unknown wrappers unwrapped", `<font color="red"><center>hi</center></font>`, `hi`},
+		{"inline images kept", `<img src="data:image/png;base64,iVBORw0KGgo=" alt="dot">`, `<img src="data:image/png;base64,iVBORw0KGgo=" alt="dot">`},
+		{"svg data images dropped", `<img src="data:image/svg+xml;base64,PHN2Zz4=">`, ``},
+	}
+
+	for _, tt := range tests {
+		if got := Sanitize(tt.in, false).HTML; got != tt.want {
+			t.Errorf("%s: got %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestSanitize_RemoteImages(t *testing.T) {
+	src := `<p><img src="https://tracker.example/p.gif" width="1"><img src="http://cdn.example/logo.png"></p>`
+
+	blocked := Sanitize(src, false)
+	if blocked.BlockedImages != 2 || strings.Contains(blocked.HTML, "img") {
+		t.Errorf("Expected remote images blocked, got %+v", blocked)
+	}
+
+	allowed := Sanitize(src, true)
+	if allowed.BlockedImages != 0 || strings.Count(allowed.HTML, "<img") != 2 {
+		t.Errorf("Expected remote images kept, got %+v", allowed)
+	}
+}
+
+func TestPlainText(t *testing.T) {
+	got := PlainText("<html><head><title>T</title></head><body><h1>News</h1>\n<p>Line   one<br>Line&nbsp;two</p><script>x()</script></body></html>")
+	want := "News\n\nLine one\nLine two"
+	if got != want {
+		t.Errorf("PlainText = %q, want %q", got, want)
+	}
+}
+
+func TestOutgoing_Bytes(t *testing.T) {
+	out := &Outgoing{
+		From:       "Renée <renee@example.com>",
+		To:         []string{"ann@example.com"},
+		Bcc:        []string{"hidden@example.com"},
+		Subject:    "Café\r\nBcc: injected@example.com",
+		Body:       "Line one\nA long line that goes on and on and on and on and on and on and on and on and on and on\n",
+		InReplyTo:  "<parent@example.com>",
+		References: []string{"root@example.com", "parent@example.com"},
+	}
+
+	recipients, err := out.Recipients()
+	if err != nil || len(recipients) != 2 || recipients[1] != "hidden@example.com" {
+		t.Fatalf("Unexpected recipients %v, %v", recipients, err)
+	}
+
+	raw, messageID, err := out.Bytes(time.Date(2025, 1, 6, 9, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatalf("Bytes failed: %v", err)
+	}
+	if strings.Contains(string(raw), "hidden@example.com") || strings.Contains(string(raw), "\r\nBcc:") {
+		t.Errorf("Bcc leaked into the headers:\n%s", raw)
+	}
+	if !strings.HasSuffix(messageID, "@example.com") {
+		t.Errorf("Expected a Message-ID on the sender's domain, got %q", messageID)
+	}
+
+	msg, err := ParseBytes(raw)
+	if err != nil {
+		t.Fatalf("Rendered message doesn't parse: %v", err)
+	}
+	if msg.From != "Renée <renee@example.com>" || msg.Subject != "Café  Bcc: injected@example.com" {
+		t.Errorf("Unexpected headers %q %q", msg.From, msg.Subject)
+	}
+	if msg.MessageID != messageID || msg.InReplyTo != "parent@example.com" || len(msg.References) != 2 {
+		t.Errorf("Unexpected threading headers %+v", msg)
+	}
+	if msg.Text != strings.ReplaceAll(out.Body, "\n", "\r\n") {
+		t.Errorf("Body didn't survive quoted-printable: %q", msg.Text)
+	}
+
+	if _, _, err := (&Outgoing{From: "me@example.com"}).Bytes(time.Now()); err == nil {
+		t.Error("Expected an error without recipients")
+	}
+}
+
+func TestReplyHelpers(t *testing.T) {
+	if got := ReplySubject("RE: Budget"); got != "RE: Budget" {
+		t.Errorf("ReplySubject added a second prefix: %q", got)
+	}
+	if got := ForwardSubject("Budget"); got != "Fwd: Budget" {
+		t.Errorf("ForwardSubject = %q", got)
+	}
+	if got := Quote("Hi\n> earlier\n"); got != "> Hi\n>> earlier" {
+		t.Errorf("Quote = %q", got)
+	}
+}
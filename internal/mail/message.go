@@ -0,0 +1,289 @@
+// Package mail parses RFC 5322 messages into the parts the email pane shows:
+// headers, a plain text body, an HTML body and attachment metadata. It also
+// renders the plain text messages the pane sends.
+package mail
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	netmail "net/mail"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// maxDepth bounds multipart nesting so hostile messages can't recurse forever
+const maxDepth = 10
+
+// Message is a parsed email
+type Message struct {
+	MessageID  string
+	InReplyTo  string
+	References []string
+	Subject    string
+	From       string
+	ReplyTo    []string
+	To         []string
+	Cc         []string
+	Date       time.Time
+
+	Text        string // First text/plain part
+	HTML        string // First text/html part, unsanitized
+	Attachments []Attachment
+}
+
+// Attachment describes a part that isn't shown inline
+type Attachment struct {
+	Filename    string `json:"filename"`
+	ContentType string `json:"content_type"`
+	Size        int    `json:"size"`
+}
+
+var wordDecoder = &mime.WordDecoder{CharsetReader: charsetReader}
+
+// Parse reads a raw message
+func Parse(r io.Reader) (*Message, error) {
+	msg, err := parse(r)
+	if err != nil {
+		return nil, err
+	}
+	return msg, nil
+}
+
+// Summarize parses a message for list views. Unlike Parse it tolerates a
+// truncated or malformed body, keeping whatever parts were readable, so it
+// only fails when the headers are unusable.
+func Summarize(raw []byte) (*Message, error) {
+	msg, err := parse(bytes.NewReader(raw))
+	if msg != nil {
+		return msg, nil
+	}
+	return nil, err
+}
+
+// Snippet returns up to n characters of the message text on one line
+func (m *Message) Snippet(n int) string {
+	text := m.Text
+	if text == "" && m.HTML != "" {
+		text = PlainText(m.HTML)
+	}
+	text = strings.Join(strings.Fields(text), " ")
+	if utf8.RuneCountInString(text) <= n {
+		return text
+	}
+	runes := []rune(text)
+	return strings.TrimSpace(string(runes[:n])) + "…"
+}
+
+// parse returns a nil message when the headers are unusable. A body error
+// comes with the partially filled message.
+func parse(r io.Reader) (*Message, error) {
+	raw, err := netmail.ReadMessage(r)
+	if err != nil {
+		return nil, fmt.Errorf("invalid message: %w", err)
+	}
+
+	header := raw.Header
+	msg := &Message{
+		MessageID:  strings.Trim(strings.TrimSpace(header.Get("Message-ID")), "<>"),
+		InReplyTo:  strings.Trim(strings.TrimSpace(header.Get("In-Reply-To")), "<>"),
+		References: messageIDs(header.Get("References")),
+		Subject:    decodeHeader(header.Get("Subject")),
+		From:       decodeAddress(header.Get("From")),
+		ReplyTo:    decodeAddressList(header.Get("Reply-To")),
+		To:         decodeAddressList(header.Get("To")),
+		Cc:         decodeAddressList(header.Get("Cc")),
+	}
+	if date, err := header.Date(); err == nil {
+		msg.Date = date
+	}
+
+	return msg, msg.walk(header.Get("Content-Type"), header.Get("Content-Transfer-Encoding"), header.Get("Content-Disposition"), raw.Body, 0)
+}
+
+// walk visits one MIME part, descending into multiparts
+func (m *Message) walk(contentType, encoding, disposition string, body io.Reader, depth int) error {
+	if depth > maxDepth {
+		return fmt.Errorf("message nested deeper than %d parts", maxDepth)
+	}
+
+	mediaType, params, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		mediaType, params = "text/plain", map[string]string{}
+	}
+
+	if strings.HasPrefix(mediaType, "multipart/") {
+		reader := multipart.NewReader(body, params["boundary"])
+		for {
+			part, err := reader.NextRawPart()
+			if err == io.EOF {
+				return nil
+			}
+			if err != nil {
+				return fmt.Errorf("invalid multipart body: %w", err)
+			}
+			err = m.walk(part.Header.Get("Content-Type"), part.Header.Get("Content-Transfer-Encoding"), part.Header.Get("Content-Disposition"), part, depth+1)
+			part.Close()
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	// Keep what was readable from a truncated part
+	data, readErr := io.ReadAll(decodeTransfer(body, encoding))
+	if readErr != nil {
+		readErr = fmt.Errorf("invalid %s part: %w", mediaType, readErr)
+	}
+
+	dispositionType, dispositionParams, _ := mime.ParseMediaType(disposition)
+	filename := dispositionParams["filename"]
+	if filename == "" {
+		filename = params["name"]
+	}
+
+	switch {
+	case dispositionType == "attachment" || filename != "" || !strings.HasPrefix(mediaType, "text/"):
+		m.Attachments = append(m.Attachments, Attachment{
+			Filename:    decodeHeader(filename),
+			ContentType: mediaType,
+			Size:        len(data),
+		})
+	case mediaType == "text/html":
+		if m.HTML == "" {
+			m.HTML = decodeCharset(data, params["charset"])
+		}
+	default:
+		if m.Text == "" {
+			m.Text = decodeCharset(data, params["charset"])
+		}
+	}
+	return readErr
+}
+
+func decodeTransfer(r io.Reader, encoding string) io.Reader {
+	switch strings.ToLower(strings.TrimSpace(encoding)) {
+	case "base64":
+		return base64.NewDecoder(base64.StdEncoding, &newlineStripper{r: r})
+	case "quoted-printable":
+		return quotedprintable.NewReader(r)
+	}
+	return r
+}
+
+// newlineStripper removes line breaks, which base64 bodies are wrapped with
+type newlineStripper struct {
+	r io.Reader
+}
+
+func (n *newlineStripper) Read(p []byte) (int, error) {
+	for {
+		count, err := n.r.Read(p)
+		kept := 0
+		for _, b := range p[:count] {
+			if b != '\r' && b != '\n' && b != ' ' && b != '\t' {
+				p[kept] = b
+				kept++
+			}
+		}
+		if kept > 0 || err != nil {
+			return kept, err
+		}
+	}
+}
+
+// decodeCharset converts the common single-byte charsets to UTF-8. Unknown
+// charsets pass through, with invalid bytes replaced.
+func decodeCharset(data []byte, charset string) string {
+	switch strings.ToLower(charset) {
+	case "iso-8859-1", "latin1", "windows-1252", "cp1252":
+		return latin1(data)
+	}
+	return strings.ToValidUTF8(string(data), "�")
+}
+
+func latin1(data []byte) string {
+	runes := make([]rune, len(data))
+	for i, b := range data {
+		runes[i] = rune(b)
+	}
+	return string(runes)
+}
+
+func charsetReader(charset string, input io.Reader) (io.Reader, error) {
+	switch strings.ToLower(charset) {
+	case "iso-8859-1", "latin1", "windows-1252", "cp1252":
+		data, err := io.ReadAll(input)
+		if err != nil {
+			return nil, err
+		}
+		return strings.NewReader(latin1(data)), nil
+	}
+	return nil, fmt.Errorf("unsupported charset %q", charset)
+}
+
+func decodeHeader(value string) string {
+	decoded, err := wordDecoder.DecodeHeader(value)
+	if err != nil || !utf8.ValidString(decoded) {
+		return strings.ToValidUTF8(value, "�")
+	}
+	return decoded
+}
+
+// decodeAddress renders a single address as "Name <addr>" or just the
+// address, falling back to the raw header when it doesn't parse
+func decodeAddress(value string) string {
+	if value == "" {
+		return ""
+	}
+	parser := netmail.AddressParser{WordDecoder: wordDecoder}
+	addr, err := parser.Parse(value)
+	if err != nil {
+		return decodeHeader(value)
+	}
+	return formatAddress(addr)
+}
+
+func decodeAddressList(value string) []string {
+	if value == "" {
+		return nil
+	}
+	parser := netmail.AddressParser{WordDecoder: wordDecoder}
+	addrs, err := parser.ParseList(value)
+	if err != nil {
+		return []string{decodeHeader(value)}
+	}
+	list := make([]string, len(addrs))
+	for i, addr := range addrs {
+		list[i] = formatAddress(addr)
+	}
+	return list
+}
+
+func formatAddress(addr *netmail.Address) string {
+	if addr.Name == "" {
+		return addr.Address
+	}
+	return addr.Name + " <" + addr.Address + ">"
+}
+
+// messageIDs splits a References header into bare IDs
+func messageIDs(value string) []string {
+	var ids []string
+	for _, field := range strings.Fields(value) {
+		if id := strings.Trim(field, "<>,"); id != "" {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+// ParseBytes is Parse for a message already in memory
+func ParseBytes(raw []byte) (*Message, error) {
+	return Parse(bytes.NewReader(raw))
+}
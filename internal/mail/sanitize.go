@@ -0,0 +1,235 @@
+package mail
+
+import (
+	"html"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// allowedElements maps each permitted element to its permitted attributes.
+// Everything else is dropped; class, id and style never survive so message
+// markup can't restyle or script the dashboard around it.
+var allowedElements = map[string]map[string]bool{
+	"a": {"href": true, "title": true}, "abbr": {"title": true}, "b": {}, "blockquote": {},
+	"br": {}, "caption": {}, "code": {}, "dd": {}, "del": {}, "div": {"align": true, "dir": true},
+	"dl": {}, "dt": {}, "em": {}, "h1": {}, "h2": {}, "h3": {}, "h4": {}, "h5": {}, "h6": {},
+	"hr": {}, "i": {}, "img": {"src": true, "alt": true, "title": true, "width": true, "height": true},
+	"ins": {}, "li": {}, "ol": {"start": true}, "p": {"align": true, "dir": true}, "pre": {},
+	"q": {}, "s": {}, "small": {}, "span": {}, "strong": {}, "sub": {}, "sup": {},
+	"table": {"width": true, "border": true, "cellpadding": true, "cellspacing": true, "align": true},
+	"tbody": {}, "td": {"colspan": true, "rowspan": true, "width": true, "align": true, "valign": true},
+	"tfoot": {}, "th": {"colspan": true, "rowspan": true, "width": true, "align": true, "valign": true},
+	"thead": {}, "tr": {"align": true, "valign": true}, "u": {}, "ul": {},
+}
+
+// droppedWithContent are removed along with everything inside them
+var droppedWithContent = map[string]bool{
+	"script": true, "style": true, "textarea": true, "title": true, "xmp": true,
+	"iframe": true, "noembed": true, "noframes": true, "noscript": true,
+	"head": true, "object": true, "applet": true, "svg": true, "math": true,
+	"template": true, "select": true, "frameset": true,
+}
+
+var voidElements = map[string]bool{"br": true, "hr": true, "img": true}
+
+// inlineImage matches data: URLs for raster images, which are safe to show
+var inlineImage = regexp.MustCompile(`^data:image/(png|gif|jpeg|webp);base64,[A-Za-z0-9+/=\s]+$`)
+
+// Sanitized is HTML made safe to embed in the dashboard
+type Sanitized struct {
+	HTML          string
+	BlockedImages int // Remote images removed because remote content is blocked
+}
+
+// Sanitize reduces message HTML to an allowlist of formatting elements.
+// Scripts, styles, forms, frames and event handlers are removed, links open
+// in a new tab, and remote images are dropped unless allowRemoteImages is
+// set, since loading them tells the sender the message was read.
+func Sanitize(src string, allowRemoteImages bool) Sanitized {
+	var out strings.Builder
+	var result Sanitized
+	var open []string // Allowed elements currently open, for balancing
+	skipping := map[string]int{}
+	skipDepth := 0
+
+	for _, tok := range tokenize(src) {
+		switch tok.Type {
+		case textToken:
+			if skipDepth == 0 {
+				out.WriteString(html.EscapeString(html.UnescapeString(tok.Data)))
+			}
+
+		case startTagToken, selfClosingTagToken:
+			if tok.Name == "body" {
+				// An unclosed <head> must not swallow the whole message
+				skipDepth -= skipping["head"]
+				skipping["head"] = 0
+			}
+			if droppedWithContent[tok.Name] {
+				if tok.Type == startTagToken {
+					skipping[tok.Name]++
+					skipDepth++
+				}
+				continue
+			}
+			attrs, ok := allowedElements[tok.Name]
+			if !ok || skipDepth > 0 {
+				continue
+			}
+			tag, keep := sanitizeTag(tok, attrs, allowRemoteImages, &result)
+			if !keep {
+				continue
+			}
+			out.WriteString(tag)
+			if !voidElements[tok.Name] {
+				if tok.Type == selfClosingTagToken {
+					out.WriteString("</" + tok.Name + ">")
+				} else {
+					open = append(open, tok.Name)
+				}
+			}
+
+		case endTagToken:
+			if skipping[tok.Name] > 0 {
+				skipping[tok.Name]--
+				skipDepth--
+				continue
+			}
+			if skipDepth > 0 || voidElements[tok.Name] {
+				continue
+			}
+			// Only close elements we opened, so stray end tags can't close
+			// the page's own containers
+			for i := len(open) - 1; i >= 0; i-- {
+				if open[i] == tok.Name {
+					for j := len(open) - 1; j >= i; j-- {
+						out.WriteString("</" + open[j] + ">")
+					}
+					open = open[:i]
+					break
+				}
+			}
+		}
+	}
+
+	for i := len(open) - 1; i >= 0; i-- {
+		out.WriteString("</" + open[i] + ">")
+	}
+
+	result.HTML = out.String()
+	return result
+}
+
+func sanitizeTag(tok token, allowed map[string]bool, allowRemoteImages bool, result *Sanitized) (string, bool) {
+	var b strings.Builder
+	b.WriteString("<" + tok.Name)
+
+	hasSrc := false
+	for _, attr := range tok.Attrs {
+		if !allowed[attr.Name] {
+			continue
+		}
+		value := html.UnescapeString(attr.Value)
+
+		switch attr.Name {
+		case "href":
+			if !safeLink(value) {
+				continue
+			}
+		case "src":
+			switch {
+			case inlineImage.MatchString(value):
+			case remoteURL(value):
+				if !allowRemoteImages {
+					result.BlockedImages++
+					return "", false
+				}
+			default:
+				continue // cid: and anything else can't be shown
+			}
+			hasSrc = true
+		}
+
+		b.WriteString(" " + attr.Name + `="` + html.EscapeString(value) + `"`)
+	}
+
+	if tok.Name == "img" && !hasSrc {
+		return "", false
+	}
+	if tok.Name == "a" {
+		b.WriteString(` target="_blank" rel="noopener noreferrer"`)
+	}
+
+	b.WriteString(">")
+	return b.String(), true
+}
+
+// safeLink allows web and mail links only
+func safeLink(value string) bool {
+	u, err := url.Parse(strings.TrimSpace(value))
+	if err != nil {
+		return false
+	}
+	switch strings.ToLower(u.Scheme) {
+	case "http", "https", "mailto":
+		return true
+	}
+	return false
+}
+
+func remoteURL(value string) bool {
+	u, err := url.Parse(strings.TrimSpace(value))
+	if err != nil {
+		return false
+	}
+	scheme := strings.ToLower(u.Scheme)
+	return (scheme == "http" || scheme == "https") && u.Host != ""
+}
+
+// blockElements end a line when HTML is flattened to text
+var blockElements = map[string]bool{
+	"br": true, "p": true, "div": true, "tr": true, "li": true, "h1": true, "h2": true,
+	"h3": true, "h4": true, "h5": true, "h6": true, "blockquote": true, "pre": true,
+	"table": true, "hr": true,
+}
+
+var blankLines = regexp.MustCompile(`\n{3,}`)
+var spaceRuns = regexp.MustCompile(`[ \t\r\f]+`)
+
+// PlainText flattens HTML to readable text, used as the fallback body when
+// a message has no text/plain part
+func PlainText(src string) string {
+	var b strings.Builder
+	skipDepth := 0
+
+	for _, tok := range tokenize(src) {
+		switch tok.Type {
+		case textToken:
+			if skipDepth == 0 {
+				text := strings.NewReplacer("\n", " ", "\u00a0", " ").Replace(html.UnescapeString(tok.Data))
+				b.WriteString(spaceRuns.ReplaceAllString(text, " "))
+			}
+		case startTagToken, selfClosingTagToken:
+			if droppedWithContent[tok.Name] && tok.Type == startTagToken {
+				skipDepth++
+			} else if blockElements[tok.Name] && skipDepth == 0 {
+				b.WriteString("\n")
+			}
+		case endTagToken:
+			if droppedWithContent[tok.Name] {
+				if skipDepth > 0 {
+					skipDepth--
+				}
+			} else if blockElements[tok.Name] && skipDepth == 0 {
+				b.WriteString("\n")
+			}
+		}
+	}
+
+	lines := strings.Split(b.String(), "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimSpace(line)
+	}
+	return strings.TrimSpace(blankLines.ReplaceAllString(strings.Join(lines, "\n"), "\n\n"))
+}
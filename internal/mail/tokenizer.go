@@ -0,0 +1,203 @@
+package mail
+
+import (
+	"strings"
+)
+
+// tokenType distinguishes the pieces of an HTML document
+type tokenType int
+
+const (
+	textToken tokenType = iota
+	startTagToken
+	endTagToken
+	selfClosingTagToken
+)
+
+type attribute struct {
+	Name  string
+	Value string // Raw value, still entity-encoded
+}
+
+type token struct {
+	Type  tokenType
+	Name  string // Lowercased tag name
+	Attrs []attribute
+	Data  string // Raw text for text tokens
+}
+
+// rawTextElements hold unparsed text up to their closing tag, so markup
+// inside a <script> never surfaces as tags
+var rawTextElements = map[string]bool{
+	"script": true, "style": true, "textarea": true, "title": true,
+	"xmp": true, "iframe": true, "noembed": true, "noframes": true, "noscript": true,
+}
+
+// tokenize splits HTML into tags and text. It is deliberately forgiving:
+// email HTML is frequently malformed, and anything that isn't a well-formed
+// tag is treated as text so it gets escaped on output. Comments, doctypes
+// and processing instructions are dropped.
+func tokenize(src string) []token {
+	var tokens []token
+	i := 0
+	for i < len(src) {
+		if src[i] != '<' {
+			next := strings.IndexByte(src[i:], '<')
+			if next < 0 {
+				next = len(src) - i
+			}
+			tokens = append(tokens, token{Type: textToken, Data: src[i : i+next]})
+			i += next
+			continue
+		}
+
+		rest := src[i:]
+		switch {
+		case strings.HasPrefix(rest, "<!--"):
+			end := strings.Index(rest[4:], "-->")
+			if end < 0 {
+				return tokens
+			}
+			i += 4 + end + 3
+
+		case strings.HasPrefix(rest, "<!") || strings.HasPrefix(rest, "<?"):
+			end := strings.IndexByte(rest, '>')
+			if end < 0 {
+				return tokens
+			}
+			i += end + 1
+
+		case len(rest) > 2 && rest[1] == '/' && isLetter(rest[2]):
+			name, n := readName(rest[2:])
+			end := strings.IndexByte(rest, '>')
+			if end < 0 {
+				return tokens
+			}
+			tokens = append(tokens, token{Type: endTagToken, Name: name})
+			i += max(end+1, 2+n)
+
+		case len(rest) > 1 && isLetter(rest[1]):
+			tok, n := readTag(rest)
+			tokens = append(tokens, tok)
+			i += n
+
+			if tok.Type == startTagToken && rawTextElements[tok.Name] {
+				// Skip to the matching close tag
+				closing := indexFold(src[i:], "</"+tok.Name)
+				if closing < 0 {
+					tokens = append(tokens, token{Type: textToken, Data: src[i:]})
+					return tokens
+				}
+				if closing > 0 {
+					tokens = append(tokens, token{Type: textToken, Data: src[i : i+closing]})
+				}
+				i += closing
+			}
+
+		default:
+			// A stray '<' is just text
+			tokens = append(tokens, token{Type: textToken, Data: "<"})
+			i++
+		}
+	}
+	return tokens
+}
+
+// readTag parses a start tag beginning at src[0] == '<' and returns the
+// token and the number of bytes consumed
+func readTag(src string) (token, int) {
+	name, n := readName(src[1:])
+	tok := token{Type: startTagToken, Name: name}
+	i := 1 + n
+
+	for i < len(src) {
+		switch c := src[i]; {
+		case c == '>':
+			return tok, i + 1
+		case c == '/':
+			if i+1 < len(src) && src[i+1] == '>' {
+				tok.Type = selfClosingTagToken
+				return tok, i + 2
+			}
+			i++
+		case isSpace(c):
+			i++
+		default:
+			attr, n := readAttribute(src[i:])
+			if n == 0 {
+				i++
+				continue
+			}
+			tok.Attrs = append(tok.Attrs, attr)
+			i += n
+		}
+	}
+	// Unterminated tag runs to the end of the input
+	return tok, len(src)
+}
+
+func readAttribute(src string) (attribute, int) {
+	i := 0
+	for i < len(src) && !isSpace(src[i]) && src[i] != '=' && src[i] != '>' && src[i] != '/' {
+		i++
+	}
+	attr := attribute{Name: strings.ToLower(src[:i])}
+
+	j := i
+	for j < len(src) && isSpace(src[j]) {
+		j++
+	}
+	if j >= len(src) || src[j] != '=' {
+		return attr, i
+	}
+	j++
+	for j < len(src) && isSpace(src[j]) {
+		j++
+	}
+	if j >= len(src) {
+		return attr, j
+	}
+
+	if quote := src[j]; quote == '"' || quote == '\'' {
+		end := strings.IndexByte(src[j+1:], quote)
+		if end < 0 {
+			attr.Value = src[j+1:]
+			return attr, len(src)
+		}
+		attr.Value = src[j+1 : j+1+end]
+		return attr, j + 1 + end + 1
+	}
+
+	start := j
+	for j < len(src) && !isSpace(src[j]) && src[j] != '>' {
+		j++
+	}
+	attr.Value = src[start:j]
+	return attr, j
+}
+
+func readName(src string) (string, int) {
+	i := 0
+	for i < len(src) && (isLetter(src[i]) || (src[i] >= '0' && src[i] <= '9') || src[i] == '-' || src[i] == ':') {
+		i++
+	}
+	return strings.ToLower(src[:i]), i
+}
+
+// indexFold finds substr in s ignoring ASCII case
+func indexFold(s, substr string) int {
+	for i := 0; i+len(substr) <= len(s); i++ {
+		if strings.EqualFold(s[i:i+len(substr)], substr) {
+			return i
+		}
+	}
+	return -1
+}
+
+func isLetter(c byte) bool {
+	return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\f'
+}
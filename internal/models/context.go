@@ -0,0 +1,44 @@
+package models
+
+import (
+	"context"
+	"net/url"
+)
+
+type queryKey struct{}
+
+// WithQuery attaches the request's query parameters so panes can read view
+// options (dates, modes) in GetData
+func WithQuery(ctx context.Context, query url.Values) context.Context {
+	return context.WithValue(ctx, queryKey{}, query)
+}
+
+// QueryParam returns a query parameter for a pane. The pane-prefixed form
+// (calendar.view) wins over the bare name (view) so several panes can share
+// one page URL.
+func QueryParam(ctx context.Context, paneID, key string) string {
+	query, _ := ctx.Value(queryKey{}).(url.Values)
+	if query == nil {
+		return ""
+	}
+	if value := query.Get(paneID + "." + key); value != "" {
+		return value
+	}
+	return query.Get(key)
+}
+
+// QueryWithout returns the page's query string, starting with "?", minus a
+// pane's keys in either form. Links built from it change one pane while
+// keeping the rest of the page as it was.
+func QueryWithout(ctx context.Context, paneID string, keys ...string) string {
+	query, _ := ctx.Value(queryKey{}).(url.Values)
+	kept := url.Values{}
+	for name, values := range query {
+		kept[name] = values
+	}
+	for _, key := range keys {
+		kept.Del(paneID + "." + key)
+		kept.Del(key)
+	}
+	return "?" + kept.Encode()
+}
@@ -2,6 +2,7 @@ package models
 
 import (
 	"context"
+	"io/fs"
 	"net/http"
 	"time"
 )
@@ -21,7 +22,9 @@ type PaneGridArea struct {
 	Column string `json:"column"` // CSS grid-column value (e.g., "span 3", "1 / 4")
 }
 
-// Pane interface defines the contract for all panes
+// Pane interface defines the contract for all panes. When the data from
+// GetData has an integer Count, as a map key or struct field, the pane's
+// header shows it; the count is optional.
 type Pane interface {
 	ID() string
 	Title() string
@@ -29,11 +32,64 @@ type Pane interface {
 	Template() string
 }
 
-// APIHandler interface for panes that need API endpoints
+// AssetProvider is implemented by panes that ship their own templates and
+// static files, so a new pane needs no changes to the shared ones. Files
+// under templates/ are parsed as "panes/<path>", matching Template(). Files
+// under static/ are served from /static/panes/<id>/, and the .css and .js
+// files directly in it are linked from the page.
+type AssetProvider interface {
+	Assets() fs.FS
+}
+
+// APIHandler interface for panes that need API endpoints. A GET of the
+// pane's own route is answered from GetData within the pane's timeout, so
+// HandleAPI sees every other request.
 type APIHandler interface {
 	HandleAPI(w http.ResponseWriter, r *http.Request) error
 }
 
+// Optional lifecycle hooks, driven by PaneRegistry for enabled panes. At
+// startup every pane is initialized, then every pane is started; at shutdown
+// panes are stopped in reverse order.
+type (
+	// Initializer prepares a pane before it serves, for example by checking
+	// its settings or connecting. An error stops Flexpane starting.
+	Initializer interface {
+		Init(ctx context.Context) error
+	}
+
+	// Starter begins background work such as polling and returns. The work
+	// runs until ctx is cancelled or the pane is stopped.
+	Starter interface {
+		Start(ctx context.Context) error
+	}
+
+	// Stopper releases what a pane holds. ctx bounds how long shutdown waits.
+	Stopper interface {
+		Stop(ctx context.Context) error
+	}
+
+	// HealthChecker reports whether a pane can currently do its job, for
+	// the health endpoint
+	HealthChecker interface {
+		Health(ctx context.Context) error
+	}
+)
+
+// Pane health states
+const (
+	HealthOK      = "ok"
+	HealthFailing = "failing"
+)
+
+// PaneHealth is one pane's entry in the health report. Panes that don't
+// check themselves are reported ok.
+type PaneHealth struct {
+	ID     string `json:"id"`
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
 // PaneData holds the rendered data for a pane
 type PaneData struct {
 	ID       string       `json:"id"`
@@ -41,20 +97,52 @@ type PaneData struct {
 	GridArea PaneGridArea `json:"grid_area"`
 	Data     interface{}  `json:"data"`
 	Template string       `json:"template"`
+	Error    *PaneError   `json:"error,omitempty"`   // Set instead of Data when loading failed
+	Refresh  int          `json:"refresh,omitempty"` // Seconds between reloads in the page, 0 for none
+}
+
+// Kinds of pane error
+const (
+	PaneErrorTimeout     = "timeout"      // Data didn't load before the pane's deadline
+	PaneErrorRateLimited = "rate_limited" // The provider asked to be retried later
+	PaneErrorFailed      = "failed"       // Any other failure
+)
+
+// PaneError describes why a pane has no data. Message is safe to show; the
+// underlying error is only logged.
+type PaneError struct {
+	Kind       string `json:"kind"`
+	Message    string `json:"message"`
+	RetryAfter int    `json:"retry_after,omitempty"` // Seconds to wait before retrying
+}
+
+// RetryAfterError is implemented by errors that say when a retry may
+// succeed, such as a provider's rate limit
+type RetryAfterError interface {
+	error
+	RetryAfter() time.Duration
 }
 
 // Simple data models
 type Todo struct {
-	Done    bool   `json:"done"`
-	Message string `json:"message"`
+	ID        string     `json:"id,omitempty"`
+	Done      bool       `json:"done"`
+	Message   string     `json:"message"`
+	Due       *time.Time `json:"due,omitempty"`
+	DueAllDay bool       `json:"due_all_day,omitempty"` // Due is a date without a time
+	Notes     string     `json:"notes,omitempty"`
+	EmailID   string     `json:"email_id,omitempty"` // Email the todo was made from
 }
 
 type Event struct {
-	ID       string    `json:"id"`
-	Title    string    `json:"title"`
-	Start    time.Time `json:"start"`
-	End      time.Time `json:"end"`
-	Location string    `json:"location,omitempty"`
+	ID          string    `json:"id"`
+	Title       string    `json:"title"`
+	Start       time.Time `json:"start"`
+	End         time.Time `json:"end"`
+	Location    string    `json:"location,omitempty"`
+	Description string    `json:"description,omitempty"`
+	AllDay      bool      `json:"all_day,omitempty"`   // End is exclusive, at midnight after the last day
+	TimeZone    string    `json:"time_zone,omitempty"` // IANA zone the event was scheduled in, if known
 }
 
 type Email struct {
@@ -64,9 +152,23 @@ type Email struct {
 	Preview string    `json:"preview"`
 	Time    time.Time `json:"time"`
 	Read    bool      `json:"read"`
+	Flagged bool      `json:"flagged,omitempty"`
+
+	// Threading headers, as bare IDs without angle brackets
+	MessageID  string   `json:"message_id,omitempty"`
+	InReplyTo  string   `json:"in_reply_to,omitempty"`
+	References []string `json:"references,omitempty"`
+
+	// Set by email rules
+	Labels []string `json:"labels,omitempty"`
 }
 
 // PageData contains all data for the main page
 type PageData struct {
-	Panes []PaneData `json:"panes"`
-}
\ No newline at end of file
+	Panes         []PaneData `json:"panes"`
+	TimeZone      string     `json:"time_zone,omitempty"`     // Configured user zone, compared against the browser's
+	Notifications bool       `json:"notifications,omitempty"` // Reminders stream to the browser
+	EventID       string     `json:"event_id,omitempty"`      // Latest change event when rendered, for live updates
+	Styles        []string   `json:"styles,omitempty"`        // Stylesheets panes ship
+	Scripts       []string   `json:"scripts,omitempty"`       // Scripts panes ship
+}
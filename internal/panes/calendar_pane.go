@@ -2,22 +2,89 @@ package panes
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
 
 	"flexpane/internal/models"
 	"flexpane/internal/providers"
+	"flexpane/internal/services"
 )
 
 // CalendarPane implements the Pane interface for calendar events
 type CalendarPane struct {
-	provider providers.DataProvider
+	provider  providers.DataProvider
+	now       func() time.Time
+	location  *time.Location
+	secondary *time.Location
+	freeBusy  *services.FreeBusyService
+	hours     services.WorkingHours
+	events    *services.EventBus
+	pollers   pollers
+
+	leaveWarning time.Duration
 }
 
 func NewCalendarPane(provider providers.DataProvider) *CalendarPane {
 	return &CalendarPane{
 		provider: provider,
+		now:      time.Now,
+		location: time.Local,
+		hours:    services.DefaultWorkingHours,
+
+		leaveWarning: 5 * time.Minute,
 	}
 }
 
+// SetLeaveWarning sets how long before the next meeting the pane warns that
+// it's time to leave
+func (cp *CalendarPane) SetLeaveWarning(d time.Duration) {
+	cp.leaveWarning = d
+}
+
+// SetFreeBusy enables the /freebusy endpoint, which checks availability
+// across every configured calendar, and adopts its working hours
+func (cp *CalendarPane) SetFreeBusy(freeBusy *services.FreeBusyService) {
+	cp.freeBusy = freeBusy
+	cp.hours = freeBusy.WorkingHours()
+}
+
+// SetEvents sets where changes made through the pane are announced
+func (cp *CalendarPane) SetEvents(events *services.EventBus) {
+	cp.events = events
+}
+
+// SetPollers sets background checks, such as reminders, that run while the
+// pane is started
+func (cp *CalendarPane) SetPollers(pollers ...*services.Poller) {
+	cp.pollers = pollers
+}
+
+// Start runs the pane's background checks
+func (cp *CalendarPane) Start(ctx context.Context) error {
+	return cp.pollers.start(ctx)
+}
+
+// Stop ends the pane's background checks
+func (cp *CalendarPane) Stop(ctx context.Context) error {
+	return cp.pollers.stop(ctx)
+}
+
+// Health reports whether the calendar provider can list events
+func (cp *CalendarPane) Health(ctx context.Context) error {
+	_, err := cp.provider.GetCalendarEvents()
+	return err
+}
+
+// SetTimeZones sets the zone events are displayed in and an optional
+// secondary zone shown alongside (nil to hide it)
+func (cp *CalendarPane) SetTimeZones(primary, secondary *time.Location) {
+	cp.location = primary
+	cp.secondary = secondary
+}
+
 func (cp *CalendarPane) ID() string {
 	return "calendar"
 }
@@ -26,22 +93,299 @@ func (cp *CalendarPane) Title() string {
 	return "Calendar"
 }
 
-
 func (cp *CalendarPane) Template() string {
 	return "panes/calendar.html"
 }
 
+// GetData returns the selected view (?calendar.view=agenda|day|week|month)
+// around the selected date (?calendar.date=2006-01-02, default today)
 func (cp *CalendarPane) GetData(ctx context.Context) (interface{}, error) {
+	now := cp.now().In(cp.location)
+	date := now
+	if value := models.QueryParam(ctx, cp.ID(), "date"); value != "" {
+		if parsed, err := time.ParseInLocation("2006-01-02", value, now.Location()); err == nil {
+			date = parsed
+		}
+	}
+	viewRange := newCalendarRange(models.QueryParam(ctx, cp.ID(), "view"), date)
+
 	events, err := cp.provider.GetCalendarEvents()
 	if err != nil {
-		return map[string]interface{}{
-			"Events": []models.Event{},
-			"Count":  0,
-		}, err
+		events = []models.Event{}
+	}
+
+	data := buildCalendarView(viewRange, events, now)
+	_, data["Writable"] = cp.provider.(providers.CalendarWriter)
+
+	// Flag overlapping events and suggest free time on the selected day
+	conflicts := map[string]bool{}
+	for id := range services.FindConflicts(data["Events"].([]models.Event)) {
+		conflicts[id] = true
+	}
+	data["Conflicts"] = conflicts
+
+	// Current and next meetings drive the live countdown
+	upcoming := make([]models.Event, len(events))
+	for i, event := range events {
+		upcoming[i] = eventInZone(event, cp.location)
+	}
+	current, next := services.CurrentAndNext(upcoming, now, cp.leaveWarning)
+	data["NowMeetings"] = current
+	data["NextMeeting"] = next
+	data["LeaveWarningSeconds"] = int(cp.leaveWarning.Seconds())
+
+	currentIDs := map[string]bool{}
+	for _, status := range current {
+		currentIDs[status.Event.ID] = true
+	}
+	data["CurrentIDs"] = currentIDs
+
+	joinLinks := map[string]string{}
+	for _, event := range upcoming {
+		if link := services.JoinLink(event); link != "" {
+			joinLinks[event.ID] = link
+		}
+	}
+	data["JoinLinks"] = joinLinks
+	if viewRange.view == ViewAgenda || viewRange.view == ViewDay {
+		from, to := viewRange.date, viewRange.date.AddDate(0, 0, 1)
+		if now.After(from) {
+			from = now
+		}
+		if cp.freeBusy != nil {
+			// Free time means free in every calendar, not just this one
+			_, data["FreeSlots"], _ = cp.freeBusy.FreeBusy(from, to, freeSlotMinimum)
+		} else {
+			data["FreeSlots"] = services.FreeSlots(data["Events"].([]models.Event), from, to, freeSlotMinimum, cp.hours)
+		}
+	}
+
+	data["ZoneName"] = cp.location.String()
+	if cp.secondary != nil {
+		data["SecondaryZone"] = cp.secondary
+	}
+	return data, err
+}
+
+// freeSlotMinimum is the shortest gap shown as free time in the pane
+const freeSlotMinimum = 30 * time.Minute
+
+// HandleAPI implements the APIHandler interface for creating, updating and
+// deleting events on providers that support it, plus GET /freebusy
+func (cp *CalendarPane) HandleAPI(w http.ResponseWriter, r *http.Request) error {
+	switch r.PathValue("rest") {
+	case "":
+	case "freebusy":
+		return cp.handleFreeBusy(w, r)
+	default:
+		http.Error(w, "Not Found", 404)
+		return nil
+	}
+
+	writer, ok := cp.provider.(providers.CalendarWriter)
+	if !ok {
+		http.Error(w, "Calendar is read-only", 501)
+		return nil
+	}
+
+	switch r.Method {
+	case "POST":
+		return cp.handleCreateEvent(w, r, writer)
+
+	case "PUT":
+		return cp.handleUpdateEvent(w, r, writer)
+
+	case "DELETE":
+		return cp.handleDeleteEvent(w, r, writer)
+
+	default:
+		http.Error(w, "Method Not Allowed", 405)
+		return nil
+	}
+}
+
+// eventRequest is either a full event or quick-add text like "Lunch tomorrow 12:30"
+type eventRequest struct {
+	models.Event
+	Text string `json:"text"`
+}
+
+func (cp *CalendarPane) decodeEvent(w http.ResponseWriter, r *http.Request) (models.Event, bool) {
+	var req eventRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid JSON", 400)
+		return models.Event{}, false
+	}
+
+	event := req.Event
+	if req.Text != "" {
+		parsed, err := services.ParseQuickAdd(req.Text, cp.now().In(cp.location))
+		if err != nil {
+			http.Error(w, err.Error(), 400)
+			return models.Event{}, false
+		}
+		parsed.ID = event.ID
+		event = parsed
 	}
 
-	return map[string]interface{}{
-		"Events": events,
-		"Count":  len(events),
-	}, nil
-}
\ No newline at end of file
+	if event.Title == "" {
+		http.Error(w, "Title required", 400)
+		return models.Event{}, false
+	}
+	if event.Start.IsZero() {
+		http.Error(w, "Start required", 400)
+		return models.Event{}, false
+	}
+	if event.End.IsZero() {
+		event.End = event.Start.Add(services.DefaultQuickAddDuration)
+	}
+	if event.End.Before(event.Start) {
+		http.Error(w, "End must not be before start", 400)
+		return models.Event{}, false
+	}
+
+	return event, true
+}
+
+func (cp *CalendarPane) handleCreateEvent(w http.ResponseWriter, r *http.Request, writer providers.CalendarWriter) error {
+	event, ok := cp.decodeEvent(w, r)
+	if !ok {
+		return nil
+	}
+
+	created, err := writer.CreateEvent(event)
+	if errors.Is(err, providers.ErrEventExists) {
+		http.Error(w, "Event already exists", 409)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	cp.events.Publish(services.EventCalendarChanged, cp.ID())
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(201)
+	return json.NewEncoder(w).Encode(created)
+}
+
+func (cp *CalendarPane) handleUpdateEvent(w http.ResponseWriter, r *http.Request, writer providers.CalendarWriter) error {
+	id := r.URL.Query().Get("id")
+	if id == "" {
+		http.Error(w, "ID required", 400)
+		return nil
+	}
+
+	event, ok := cp.decodeEvent(w, r)
+	if !ok {
+		return nil
+	}
+	event.ID = id
+
+	updated, err := writer.UpdateEvent(event)
+	if errors.Is(err, providers.ErrEventNotFound) {
+		http.Error(w, "Event not found", 404)
+		return nil
+	}
+	if errors.Is(err, providers.ErrEventConflict) {
+		http.Error(w, "Event changed since it was loaded", 409)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	cp.events.Publish(services.EventCalendarChanged, cp.ID())
+
+	w.Header().Set("Content-Type", "application/json")
+	return json.NewEncoder(w).Encode(updated)
+}
+
+func (cp *CalendarPane) handleDeleteEvent(w http.ResponseWriter, r *http.Request, writer providers.CalendarWriter) error {
+	id := r.URL.Query().Get("id")
+	if id == "" {
+		http.Error(w, "ID required", 400)
+		return nil
+	}
+
+	err := writer.DeleteEvent(id)
+	if errors.Is(err, providers.ErrEventNotFound) {
+		http.Error(w, "Event not found", 404)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	cp.events.Publish(services.EventCalendarChanged, cp.ID())
+
+	return json.NewEncoder(w).Encode(map[string]string{"status": "deleted"})
+}
+
+// handleFreeBusy serves GET /freebusy?from=&to=&duration=. from and to accept
+// RFC 3339 times or dates (to is inclusive for dates) and default to the
+// next seven days; duration defaults to 30m.
+func (cp *CalendarPane) handleFreeBusy(w http.ResponseWriter, r *http.Request) error {
+	if r.Method != "GET" {
+		http.Error(w, "Method Not Allowed", 405)
+		return nil
+	}
+	if cp.freeBusy == nil {
+		http.Error(w, "Free/busy is not configured", 501)
+		return nil
+	}
+
+	query := r.URL.Query()
+	now := cp.now().In(cp.location)
+
+	from, ok := parseTimeParam(query.Get("from"), now, false, cp.location)
+	if !ok {
+		http.Error(w, "Invalid from", 400)
+		return nil
+	}
+	to, ok := parseTimeParam(query.Get("to"), from.AddDate(0, 0, 7), true, cp.location)
+	if !ok || !to.After(from) {
+		http.Error(w, "Invalid to", 400)
+		return nil
+	}
+	if to.After(from.AddDate(0, 0, services.MaxFreeBusyDays)) {
+		http.Error(w, fmt.Sprintf("Range is longer than %d days", services.MaxFreeBusyDays), 400)
+		return nil
+	}
+	duration := freeSlotMinimum
+	if value := query.Get("duration"); value != "" {
+		parsed, err := time.ParseDuration(value)
+		if err != nil || parsed <= 0 {
+			http.Error(w, "Invalid duration", 400)
+			return nil
+		}
+		duration = parsed
+	}
+
+	busy, free, skipped := cp.freeBusy.FreeBusy(from, to, duration)
+
+	w.Header().Set("Content-Type", "application/json")
+	return json.NewEncoder(w).Encode(map[string]interface{}{
+		"from":     from,
+		"to":       to,
+		"duration": duration.String(),
+		"busy":     busy,
+		"free":     free,
+		"skipped":  skipped,
+	})
+}
+
+// parseTimeParam accepts RFC 3339 or a bare date. With endOfDay set, a bare
+// date means the end of that day.
+func parseTimeParam(value string, fallback time.Time, endOfDay bool, loc *time.Location) (time.Time, bool) {
+	if value == "" {
+		return fallback, true
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t.In(loc), true
+	}
+	if t, err := time.ParseInLocation("2006-01-02", value, loc); err == nil {
+		if endOfDay {
+			t = t.AddDate(0, 0, 1)
+		}
+		return t, true
+	}
+	return time.Time{}, false
+}
@@ -0,0 +1,111 @@
+package panes
+
+import (
+	"context"
+	"net/url"
+	"testing"
+	"time"
+
+	"flexpane/internal/models"
+	"flexpane/internal/services"
+)
+
+// stubProvider serves fixed data
+type stubProvider struct {
+	events []models.Event
+	emails []models.Email
+}
+
+func (s *stubProvider) GetCalendarEvents() ([]models.Event, error) { return s.events, nil }
+func (s *stubProvider) GetEmails() ([]models.Email, error)         { return s.emails, nil }
+
+func TestCalendarPane_TimeZones(t *testing.T) {
+	newYork, _ := time.LoadLocation("America/New_York")
+	tokyo, _ := time.LoadLocation("Asia/Tokyo")
+
+	provider := &stubProvider{events: []models.Event{
+		{ID: "call", Title: "Call", Start: time.Date(2025, 1, 6, 14, 0, 0, 0, time.UTC), End: time.Date(2025, 1, 6, 15, 0, 0, 0, time.UTC)},
+		// All-day events keep their date regardless of zone
+		{ID: "holiday", Title: "Holiday", Start: time.Date(2025, 1, 6, 0, 0, 0, 0, time.UTC), End: time.Date(2025, 1, 7, 0, 0, 0, 0, time.UTC), AllDay: true},
+	}}
+
+	pane := NewCalendarPane(provider)
+	pane.SetTimeZones(newYork, tokyo)
+	pane.now = func() time.Time { return time.Date(2025, 1, 6, 12, 0, 0, 0, time.UTC) }
+
+	ctx := models.WithQuery(context.Background(), url.Values{"calendar.view": {"day"}})
+	result, err := pane.GetData(ctx)
+	if err != nil {
+		t.Fatalf("GetData failed: %v", err)
+	}
+	data := result.(map[string]interface{})
+
+	if data["ZoneName"] != "America/New_York" || data["SecondaryZone"] != tokyo {
+		t.Errorf("Expected zones in data, got %v / %v", data["ZoneName"], data["SecondaryZone"])
+	}
+
+	day := data["Days"].([]CalendarDay)[0]
+	if len(day.Timed) != 1 || day.Timed[0].Start.Hour() != 9 || day.Timed[0].Start.Location() != newYork {
+		t.Errorf("Expected call at 09:00 New York time, got %+v", day.Timed)
+	}
+	if len(day.AllDay) != 1 || day.AllDay[0].Start.Day() != 6 || day.AllDay[0].Start.Hour() != 0 {
+		t.Errorf("Expected holiday on Jan 6, got %+v", day.AllDay)
+	}
+}
+
+func TestCalendarPane_NowAndNext(t *testing.T) {
+	provider := &stubProvider{events: []models.Event{
+		{ID: "standup", Title: "Standup", Start: time.Date(2025, 1, 6, 9, 0, 0, 0, time.UTC), End: time.Date(2025, 1, 6, 9, 30, 0, 0, time.UTC)},
+		{ID: "review", Title: "Review", Start: time.Date(2025, 1, 6, 9, 45, 0, 0, time.UTC), End: time.Date(2025, 1, 6, 10, 30, 0, 0, time.UTC), Description: "https://meet.google.com/abc-defg-hij"},
+	}}
+
+	pane := NewCalendarPane(provider)
+	pane.SetTimeZones(time.UTC, nil)
+	pane.SetLeaveWarning(20 * time.Minute)
+	pane.now = func() time.Time { return time.Date(2025, 1, 6, 9, 29, 0, 0, time.UTC) }
+
+	result, err := pane.GetData(context.Background())
+	if err != nil {
+		t.Fatalf("GetData failed: %v", err)
+	}
+	data := result.(map[string]interface{})
+
+	if !data["CurrentIDs"].(map[string]bool)["standup"] {
+		t.Errorf("Expected standup marked current, got %v", data["CurrentIDs"])
+	}
+	next := data["NextMeeting"].(*services.MeetingStatus)
+	if next.Event.ID != "review" || !next.LeavingSoon {
+		t.Errorf("Expected review next and leaving soon, got %+v", next)
+	}
+	if data["JoinLinks"].(map[string]string)["review"] != "https://meet.google.com/abc-defg-hij" {
+		t.Errorf("Expected join link for review, got %v", data["JoinLinks"])
+	}
+	if data["LeaveWarningSeconds"] != 1200 {
+		t.Errorf("Expected 1200 second warning, got %v", data["LeaveWarningSeconds"])
+	}
+}
+
+func TestCalendarPane_FreeSlotsAcrossCalendars(t *testing.T) {
+	work := &stubProvider{events: []models.Event{
+		{ID: "standup", Title: "Standup", Start: time.Date(2025, 1, 6, 9, 0, 0, 0, time.UTC), End: time.Date(2025, 1, 6, 12, 0, 0, 0, time.UTC)},
+	}}
+	home := &stubProvider{events: []models.Event{
+		{ID: "dentist", Title: "Dentist", Start: time.Date(2025, 1, 6, 12, 0, 0, 0, time.UTC), End: time.Date(2025, 1, 6, 16, 0, 0, 0, time.UTC)},
+	}}
+
+	pane := NewCalendarPane(work)
+	pane.SetTimeZones(time.UTC, nil)
+	pane.SetFreeBusy(services.NewFreeBusyService([]services.EventSource{work, home}, services.DefaultWorkingHours, time.UTC))
+	pane.now = func() time.Time { return time.Date(2025, 1, 6, 8, 0, 0, 0, time.UTC) }
+
+	ctx := models.WithQuery(context.Background(), url.Values{"calendar.view": {"day"}})
+	result, err := pane.GetData(ctx)
+	if err != nil {
+		t.Fatalf("GetData failed: %v", err)
+	}
+
+	free := result.(map[string]interface{})["FreeSlots"].([]services.Slot)
+	if len(free) != 1 || free[0].Start.Hour() != 16 {
+		t.Errorf("Expected free time only from 16:00, got %+v", free)
+	}
+}
@@ -0,0 +1,346 @@
+package panes
+
+import (
+	"math"
+	"sort"
+	"time"
+
+	"flexpane/internal/models"
+)
+
+// Calendar views selectable with ?calendar.view=
+const (
+	ViewAgenda = "agenda"
+	ViewDay    = "day"
+	ViewWeek   = "week"
+	ViewMonth  = "month"
+)
+
+// CalendarViews lists the views in the order they appear in the switcher
+var CalendarViews = []string{ViewAgenda, ViewDay, ViewWeek, ViewMonth}
+
+// Hours shown in the day timeline unless events fall outside them
+const (
+	timelineStartHour = 7
+	timelineEndHour   = 20
+)
+
+// CalendarDay is one day cell in the day, week or month view
+type CalendarDay struct {
+	Date    time.Time
+	Today   bool
+	InMonth bool       // False for padding days in the month view
+	AllDay  []DayEvent // All-day and multi-day events (day view only)
+	Timed   []DayEvent // Events that start and end on this day
+}
+
+// DayEvent is an event placed in a day. Top, Height, Left and Width are
+// percentages used to position it in the day timeline.
+type DayEvent struct {
+	models.Event
+	ContinuesBefore bool
+	ContinuesAfter  bool
+	Top             float64
+	Height          float64
+	Left            float64
+	Width           float64
+}
+
+// SpanEvent is an all-day or multi-day event laid out across a week row.
+// Column is 1-based so it can be used directly as a CSS grid column.
+type SpanEvent struct {
+	models.Event
+	Column          int
+	Span            int
+	ContinuesBefore bool
+	ContinuesAfter  bool
+}
+
+// CalendarWeek is a row of seven days with its spanning events
+type CalendarWeek struct {
+	Days  []CalendarDay
+	Spans []SpanEvent
+}
+
+// calendarRange describes the dates a view covers
+type calendarRange struct {
+	view     string
+	date     time.Time // Selected date at midnight
+	from, to time.Time
+	prev     time.Time
+	next     time.Time
+	label    string
+}
+
+func startOfDay(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+}
+
+// startOfWeek returns the Monday on or before t
+func startOfWeek(t time.Time) time.Time {
+	day := startOfDay(t)
+	offset := (int(day.Weekday()) + 6) % 7
+	return day.AddDate(0, 0, -offset)
+}
+
+func newCalendarRange(view string, date time.Time) calendarRange {
+	date = startOfDay(date)
+	r := calendarRange{view: view, date: date}
+
+	switch view {
+	case ViewDay:
+		r.from, r.to = date, date.AddDate(0, 0, 1)
+		r.prev, r.next = date.AddDate(0, 0, -1), date.AddDate(0, 0, 1)
+		r.label = date.Format("Monday, Jan 2")
+	case ViewWeek:
+		r.from = startOfWeek(date)
+		r.to = r.from.AddDate(0, 0, 7)
+		r.prev, r.next = date.AddDate(0, 0, -7), date.AddDate(0, 0, 7)
+		r.label = "Week of " + r.from.Format("Jan 2")
+	case ViewMonth:
+		first := time.Date(date.Year(), date.Month(), 1, 0, 0, 0, 0, date.Location())
+		r.from = startOfWeek(first)
+		r.to = startOfWeek(first.AddDate(0, 1, -1)).AddDate(0, 0, 7)
+		r.prev, r.next = first.AddDate(0, -1, 0), first.AddDate(0, 1, 0)
+		r.label = date.Format("January 2006")
+	default:
+		r.view = ViewAgenda
+		r.from = date
+		r.prev, r.next = date.AddDate(0, 0, -7), date.AddDate(0, 0, 7)
+		r.label = "From " + date.Format("Jan 2")
+	}
+	return r
+}
+
+// overlaps reports whether an event intersects [from, to). A zero to means
+// the range is open-ended.
+func overlaps(event models.Event, from, to time.Time) bool {
+	if !to.IsZero() && !event.Start.Before(to) {
+		return false
+	}
+	if event.End.Equal(event.Start) {
+		return !event.Start.Before(from)
+	}
+	return event.End.After(from)
+}
+
+// spansDays reports whether an event belongs in the all-day row
+func spansDays(event models.Event) bool {
+	if event.AllDay {
+		return true
+	}
+	lastInstant := event.End
+	if event.End.After(event.Start) {
+		lastInstant = event.End.Add(-time.Nanosecond)
+	}
+	return !startOfDay(event.Start).Equal(startOfDay(lastInstant))
+}
+
+func sortEvents(events []models.Event) {
+	sort.SliceStable(events, func(i, j int) bool {
+		if !events[i].Start.Equal(events[j].Start) {
+			return events[i].Start.Before(events[j].Start)
+		}
+		return events[i].End.After(events[j].End) // Longer events first
+	})
+}
+
+// buildCalendarView returns the template data for a view over the given events
+func buildCalendarView(r calendarRange, events []models.Event, now time.Time) map[string]interface{} {
+	inRange := []models.Event{}
+	for _, event := range events {
+		// Day boundaries are computed in the viewer's zone
+		event = eventInZone(event, now.Location())
+		if overlaps(event, r.from, r.to) {
+			inRange = append(inRange, event)
+		}
+	}
+	sortEvents(inRange)
+
+	data := map[string]interface{}{
+		"View":   r.view,
+		"Views":  CalendarViews,
+		"Date":   r.date,
+		"Prev":   r.prev,
+		"Next":   r.next,
+		"Today":  startOfDay(now),
+		"Label":  r.label,
+		"Events": inRange,
+		"Count":  len(inRange),
+	}
+
+	today := startOfDay(now)
+	switch r.view {
+	case ViewDay:
+		day, startHour, endHour := buildTimelineDay(r.date, inRange)
+		day.Today = r.date.Equal(today)
+		data["Days"] = []CalendarDay{day}
+		hours := []int{}
+		for hour := startHour; hour < endHour; hour++ {
+			hours = append(hours, hour)
+		}
+		data["Hours"] = hours
+	case ViewWeek, ViewMonth:
+		weeks := []CalendarWeek{}
+		for weekStart := r.from; weekStart.Before(r.to); weekStart = weekStart.AddDate(0, 0, 7) {
+			weeks = append(weeks, buildWeek(weekStart, inRange, today, r.date.Month(), r.view == ViewMonth))
+		}
+		data["Weeks"] = weeks
+	}
+
+	return data
+}
+
+func buildWeek(weekStart time.Time, events []models.Event, today time.Time, month time.Month, isMonth bool) CalendarWeek {
+	week := CalendarWeek{}
+	weekEnd := weekStart.AddDate(0, 0, 7)
+
+	for i := 0; i < 7; i++ {
+		date := weekStart.AddDate(0, 0, i)
+		day := CalendarDay{
+			Date:    date,
+			Today:   date.Equal(today),
+			InMonth: !isMonth || date.Month() == month,
+		}
+		for _, event := range events {
+			if !spansDays(event) && overlaps(event, date, date.AddDate(0, 0, 1)) {
+				day.Timed = append(day.Timed, DayEvent{Event: event})
+			}
+		}
+		week.Days = append(week.Days, day)
+	}
+
+	for _, event := range events {
+		if !spansDays(event) || !overlaps(event, weekStart, weekEnd) {
+			continue
+		}
+
+		first := startOfDay(event.Start)
+		last := startOfDay(event.End.Add(-time.Nanosecond))
+		span := SpanEvent{Event: event}
+		if first.Before(weekStart) {
+			first = weekStart
+			span.ContinuesBefore = true
+		}
+		if !last.Before(weekEnd) {
+			last = weekEnd.AddDate(0, 0, -1)
+			span.ContinuesAfter = true
+		}
+		span.Column = daysBetween(weekStart, first) + 1
+		span.Span = daysBetween(first, last) + 1
+		week.Spans = append(week.Spans, span)
+	}
+
+	return week
+}
+
+// daysBetween counts calendar days, which is DST-safe unlike dividing durations
+func daysBetween(from, to time.Time) int {
+	days := 0
+	for d := from; d.Before(to); d = d.AddDate(0, 0, 1) {
+		days++
+	}
+	return days
+}
+
+// buildTimelineDay positions timed events on the day timeline, placing
+// overlapping events side by side. It returns the hour range to display.
+func buildTimelineDay(date time.Time, events []models.Event) (CalendarDay, int, int) {
+	day := CalendarDay{Date: date, InMonth: true}
+	next := date.AddDate(0, 0, 1)
+	startHour, endHour := timelineStartHour, timelineEndHour
+
+	var timed []DayEvent
+	for _, event := range events {
+		if spansDays(event) {
+			day.AllDay = append(day.AllDay, DayEvent{
+				Event:           event,
+				ContinuesBefore: event.Start.Before(date),
+				ContinuesAfter:  event.End.After(next),
+			})
+			continue
+		}
+		timed = append(timed, DayEvent{Event: event})
+		if h := event.Start.Hour(); h < startHour {
+			startHour = h
+		}
+		// An event ending at midnight ends with the day, not at hour 0
+		if !event.End.Before(next) {
+			endHour = 24
+		} else if h := event.End.Hour(); event.End.Minute() > 0 && h+1 > endHour {
+			endHour = h + 1
+		} else if h > endHour {
+			endHour = h
+		}
+	}
+
+	// Built from the wall clock, since midnight plus hours is off by one on
+	// days the clocks change
+	windowStart := time.Date(date.Year(), date.Month(), date.Day(), startHour, 0, 0, 0, date.Location())
+	windowEnd := next
+	if endHour < 24 {
+		windowEnd = time.Date(date.Year(), date.Month(), date.Day(), endHour, 0, 0, 0, date.Location())
+	}
+	window := windowEnd.Sub(windowStart)
+	for i := range timed {
+		event := &timed[i]
+		end := event.End
+		if end.After(windowEnd) {
+			end = windowEnd
+		}
+		event.Top = 100 * float64(event.Start.Sub(windowStart)) / float64(window)
+		height := 100 * float64(end.Sub(event.Start)) / float64(window)
+		if height < 2 {
+			height = 2 // Keep very short events visible
+		}
+		event.Top, event.Height = roundPercent(event.Top), roundPercent(height)
+	}
+	assignLanes(timed)
+
+	day.Timed = timed
+	return day, startHour, endHour
+}
+
+// assignLanes gives each group of overlapping events equal-width columns
+func assignLanes(events []DayEvent) {
+	for groupStart := 0; groupStart < len(events); {
+		// Find the cluster of events that transitively overlap
+		groupEnd := groupStart + 1
+		clusterEnd := events[groupStart].End
+		for groupEnd < len(events) && events[groupEnd].Start.Before(clusterEnd) {
+			if events[groupEnd].End.After(clusterEnd) {
+				clusterEnd = events[groupEnd].End
+			}
+			groupEnd++
+		}
+
+		var laneEnds []time.Time
+		lanes := make([]int, groupEnd-groupStart)
+		for i := groupStart; i < groupEnd; i++ {
+			lane := -1
+			for l, end := range laneEnds {
+				if !events[i].Start.Before(end) {
+					lane = l
+					break
+				}
+			}
+			if lane < 0 {
+				lane = len(laneEnds)
+				laneEnds = append(laneEnds, time.Time{})
+			}
+			laneEnds[lane] = events[i].End
+			lanes[i-groupStart] = lane
+		}
+
+		width := 100 / float64(len(laneEnds))
+		for i := groupStart; i < groupEnd; i++ {
+			events[i].Left = roundPercent(float64(lanes[i-groupStart]) * width)
+			events[i].Width = roundPercent(width)
+		}
+		groupStart = groupEnd
+	}
+}
+
+func roundPercent(value float64) float64 {
+	return math.Round(value*100) / 100
+}
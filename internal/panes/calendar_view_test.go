@@ -0,0 +1,130 @@
+package panes
+
+import (
+	"testing"
+	"time"
+
+	"flexpane/internal/models"
+)
+
+func TestBuildCalendarView_WeekSpans(t *testing.T) {
+	// Wednesday 8 January 2025
+	now := time.Date(2025, 1, 8, 10, 0, 0, 0, time.UTC)
+	events := []models.Event{
+		{ID: "trip", Title: "Trip", Start: time.Date(2025, 1, 5, 0, 0, 0, 0, time.UTC), End: time.Date(2025, 1, 8, 0, 0, 0, 0, time.UTC), AllDay: true},
+		{ID: "offsite", Title: "Offsite", Start: time.Date(2025, 1, 10, 9, 0, 0, 0, time.UTC), End: time.Date(2025, 1, 14, 17, 0, 0, 0, time.UTC)},
+		{ID: "standup", Title: "Standup", Start: time.Date(2025, 1, 8, 9, 0, 0, 0, time.UTC), End: time.Date(2025, 1, 8, 9, 15, 0, 0, time.UTC)},
+		{ID: "later", Title: "Later", Start: time.Date(2025, 1, 20, 9, 0, 0, 0, time.UTC), End: time.Date(2025, 1, 20, 10, 0, 0, 0, time.UTC)},
+	}
+
+	data := buildCalendarView(newCalendarRange(ViewWeek, now), events, now)
+
+	if data["Count"] != 3 {
+		t.Errorf("Expected 3 events in the week, got %v", data["Count"])
+	}
+
+	weeks := data["Weeks"].([]CalendarWeek)
+	if len(weeks) != 1 {
+		t.Fatalf("Expected 1 week, got %d", len(weeks))
+	}
+	week := weeks[0]
+	if week.Days[0].Date.Weekday() != time.Monday {
+		t.Errorf("Expected week to start on Monday, got %v", week.Days[0].Date.Weekday())
+	}
+	if !week.Days[2].Today || len(week.Days[2].Timed) != 1 {
+		t.Errorf("Expected Wednesday to be today with the standup, got %+v", week.Days[2])
+	}
+
+	spans := map[string]SpanEvent{}
+	for _, span := range week.Spans {
+		spans[span.ID] = span
+	}
+	if trip := spans["trip"]; trip.Column != 1 || trip.Span != 2 || !trip.ContinuesBefore || trip.ContinuesAfter {
+		t.Errorf("Unexpected trip span: %+v", trip)
+	}
+	if offsite := spans["offsite"]; offsite.Column != 5 || offsite.Span != 3 || offsite.ContinuesBefore || !offsite.ContinuesAfter {
+		t.Errorf("Unexpected offsite span: %+v", offsite)
+	}
+}
+
+func TestBuildCalendarView_MonthGrid(t *testing.T) {
+	now := time.Date(2025, 2, 14, 10, 0, 0, 0, time.UTC)
+
+	data := buildCalendarView(newCalendarRange(ViewMonth, now), nil, now)
+
+	weeks := data["Weeks"].([]CalendarWeek)
+	// February 2025 starts on a Saturday and ends on a Friday
+	if len(weeks) != 5 {
+		t.Fatalf("Expected 5 weeks, got %d", len(weeks))
+	}
+	if first := weeks[0].Days[0]; first.Date.Day() != 27 || first.InMonth {
+		t.Errorf("Expected grid to start on padding day Jan 27, got %+v", first)
+	}
+	if prev := data["Prev"].(time.Time); prev.Month() != time.January {
+		t.Errorf("Expected previous month to be January, got %v", prev)
+	}
+}
+
+func TestBuildTimelineDay_OverlappingLanes(t *testing.T) {
+	date := time.Date(2025, 1, 8, 0, 0, 0, 0, time.UTC)
+	at := func(hour, minute int) time.Time {
+		return date.Add(time.Duration(hour)*time.Hour + time.Duration(minute)*time.Minute)
+	}
+
+	events := []models.Event{
+		{ID: "a", Start: at(9, 0), End: at(10, 0)},
+		{ID: "b", Start: at(9, 30), End: at(11, 0)},
+		{ID: "c", Start: at(10, 0), End: at(10, 30)},
+		{ID: "d", Start: at(14, 0), End: at(15, 0)},
+	}
+
+	day, startHour, endHour := buildTimelineDay(date, events)
+	if startHour != timelineStartHour || endHour != timelineEndHour {
+		t.Errorf("Expected default hours, got %d-%d", startHour, endHour)
+	}
+
+	lanes := map[string]DayEvent{}
+	for _, event := range day.Timed {
+		lanes[event.ID] = event
+	}
+	if lanes["a"].Width != 50 || lanes["b"].Left != 50 || lanes["c"].Left != 0 {
+		t.Errorf("Expected a/c to share the left lane and b on the right, got %+v", day.Timed)
+	}
+	if lanes["d"].Width != 100 {
+		t.Errorf("Expected a lone event to take the full width, got %v", lanes["d"].Width)
+	}
+}
+
+func TestBuildTimelineDay_MidnightAndDaylightSaving(t *testing.T) {
+	loc, err := time.LoadLocation("Europe/London")
+	if err != nil {
+		t.Skipf("Time zone data unavailable: %v", err)
+	}
+	// Clocks go forward at 01:00 on Sunday 30 March 2025
+	date := time.Date(2025, 3, 30, 0, 0, 0, 0, loc)
+	events := []models.Event{
+		{ID: "morning", Start: time.Date(2025, 3, 30, 9, 0, 0, 0, loc), End: time.Date(2025, 3, 30, 10, 0, 0, 0, loc)},
+		{ID: "late", Start: time.Date(2025, 3, 30, 22, 0, 0, 0, loc), End: time.Date(2025, 3, 31, 0, 0, 0, 0, loc)},
+	}
+
+	day, startHour, endHour := buildTimelineDay(date, events)
+	if startHour != timelineStartHour || endHour != 24 {
+		t.Fatalf("Expected the timeline to run to midnight, got %d-%d", startHour, endHour)
+	}
+
+	positions := map[string]DayEvent{}
+	for _, event := range day.Timed {
+		positions[event.ID] = event
+	}
+	hours := float64(24 - timelineStartHour)
+	if want := roundPercent(100 * 2 / hours); positions["morning"].Top != want {
+		t.Errorf("Expected 9am two hours down the timeline at %v%%, got %v%%", want, positions["morning"].Top)
+	}
+	late := positions["late"]
+	if late.Top+late.Height > 100.01 {
+		t.Errorf("Expected the late event to end with the grid, got top %v height %v", late.Top, late.Height)
+	}
+	if want := roundPercent(100 * 2 / hours); late.Height != want {
+		t.Errorf("Expected a two hour block of %v%%, got %v%%", want, late.Height)
+	}
+}
@@ -0,0 +1,187 @@
+package panes
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	netmail "net/mail"
+	"strings"
+	"time"
+
+	"flexpane/internal/mail"
+	"flexpane/internal/providers"
+)
+
+// composeRequest is the body of /send, /reply and /forward. Replies take
+// their recipients and subject from the original message; All also copies
+// its other recipients.
+type composeRequest struct {
+	To      []string `json:"to"`
+	Cc      []string `json:"cc"`
+	Bcc     []string `json:"bcc"`
+	Subject string   `json:"subject"`
+	Body    string   `json:"body"`
+	All     bool     `json:"all"`
+}
+
+func (ep *EmailPane) handleSend(w http.ResponseWriter, r *http.Request, mode string) error {
+	if ep.mailer == nil {
+		http.Error(w, "Sending is not configured", 501)
+		return nil
+	}
+
+	var req composeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid JSON", 400)
+		return nil
+	}
+
+	msg := &mail.Outgoing{
+		From:    ep.mailer.From(),
+		To:      req.To,
+		Cc:      req.Cc,
+		Bcc:     req.Bcc,
+		Subject: req.Subject,
+		Body:    req.Body,
+	}
+
+	if mode != "send" {
+		id := r.URL.Query().Get("id")
+		if id == "" {
+			http.Error(w, "ID required", 400)
+			return nil
+		}
+		original, err := ep.original(id)
+		if errors.Is(err, providers.ErrEmailNotFound) {
+			http.Error(w, "Email not found", 404)
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if mode == "reply" {
+			ep.prepareReply(msg, original, req.All)
+		} else {
+			ep.prepareForward(msg, original)
+		}
+	}
+
+	recipients, err := msg.Recipients()
+	if err != nil {
+		http.Error(w, err.Error(), 400)
+		return nil
+	}
+	raw, messageID, err := msg.Bytes(time.Now())
+	if err != nil {
+		http.Error(w, err.Error(), 400)
+		return nil
+	}
+
+	from, _ := netmail.ParseAddress(msg.From)
+	if err := ep.mailer.Send(r.Context(), from.Address, recipients, raw); err != nil {
+		return fmt.Errorf("send: %w", err)
+	}
+
+	// The message is already on its way, so a failed copy is only logged
+	saved := false
+	if saver, ok := ep.provider.(providers.SentSaver); ok {
+		if err := saver.SaveSent(raw); err != nil {
+			log.Printf("Failed to save sent message %s: %v", messageID, err)
+		} else {
+			saved = true
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	return json.NewEncoder(w).Encode(map[string]interface{}{
+		"status":     "sent",
+		"message_id": messageID,
+		"saved":      saved,
+	})
+}
+
+// original loads the message being replied to or forwarded. Providers that
+// can't return full messages fall back to the listing.
+func (ep *EmailPane) original(id string) (*mail.Message, error) {
+	email, err := ep.email(id)
+	if err != nil {
+		return nil, err
+	}
+	if reader, ok := ep.provider.(providers.MessageReader); ok {
+		raw, err := reader.ReadMessage(id)
+		if err != nil {
+			return nil, err
+		}
+		msg, err := mail.ParseBytes(raw)
+		if err != nil {
+			return nil, err
+		}
+		if msg.Text == "" && msg.HTML != "" {
+			msg.Text = mail.PlainText(msg.HTML)
+		}
+		return msg, nil
+	}
+	return &mail.Message{
+		MessageID:  email.MessageID,
+		InReplyTo:  email.InReplyTo,
+		References: email.References,
+		Subject:    email.Subject,
+		From:       email.From,
+		Date:       email.Time,
+		Text:       email.Preview,
+	}, nil
+}
+
+func (ep *EmailPane) prepareReply(msg *mail.Outgoing, original *mail.Message, all bool) {
+	msg.To = original.ReplyTo
+	if len(msg.To) == 0 {
+		msg.To = []string{original.From}
+	}
+	if all {
+		// Everyone else on the original, without duplicates or ourselves
+		seen := map[string]bool{bareAddress(msg.From): true}
+		for _, addr := range msg.To {
+			seen[bareAddress(addr)] = true
+		}
+		for _, addr := range append(append([]string{}, original.To...), original.Cc...) {
+			if key := bareAddress(addr); !seen[key] {
+				seen[key] = true
+				msg.Cc = append(msg.Cc, addr)
+			}
+		}
+	}
+
+	msg.Subject = mail.ReplySubject(original.Subject)
+	if original.MessageID != "" {
+		msg.InReplyTo = original.MessageID
+		msg.References = append(append([]string{}, original.References...), original.MessageID)
+	}
+	msg.Body = strings.TrimRight(msg.Body, "\n") + fmt.Sprintf("\n\nOn %s, %s wrote:\n%s\n",
+		original.Date.In(ep.location).Format("Mon, Jan 2, 2006 at 15:04"), original.From, mail.Quote(original.Text))
+}
+
+func (ep *EmailPane) prepareForward(msg *mail.Outgoing, original *mail.Message) {
+	msg.Subject = mail.ForwardSubject(original.Subject)
+
+	var b strings.Builder
+	b.WriteString(strings.TrimRight(msg.Body, "\n"))
+	b.WriteString("\n\n---------- Forwarded message ----------\n")
+	fmt.Fprintf(&b, "From: %s\n", original.From)
+	fmt.Fprintf(&b, "Date: %s\n", original.Date.In(ep.location).Format("Mon, Jan 2, 2006 at 15:04"))
+	fmt.Fprintf(&b, "Subject: %s\n", original.Subject)
+	if len(original.To) > 0 {
+		fmt.Fprintf(&b, "To: %s\n", strings.Join(original.To, ", "))
+	}
+	b.WriteString("\n" + original.Text + "\n")
+	msg.Body = b.String()
+}
+
+func bareAddress(value string) string {
+	if addr, err := netmail.ParseAddress(value); err == nil {
+		return strings.ToLower(addr.Address)
+	}
+	return strings.ToLower(strings.TrimSpace(value))
+}
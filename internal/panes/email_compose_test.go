@@ -0,0 +1,139 @@
+package panes
+
+import (
+	"bufio"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"flexpane/internal/mail"
+	"flexpane/internal/providers"
+	"flexpane/internal/services"
+)
+
+// startSMTPStandIn accepts one plaintext SMTP session and sends the DATA it
+// receives on the returned channel
+func startSMTPStandIn(t *testing.T) (string, <-chan string) {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	received := make(chan string, 1)
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		r := bufio.NewReader(conn)
+		conn.Write([]byte("220 stand-in\r\n"))
+		var data strings.Builder
+		for {
+			line, err := r.ReadString('\n')
+			if err != nil {
+				return
+			}
+			switch strings.ToUpper(strings.Fields(line + " x")[0]) {
+			case "EHLO", "HELO":
+				conn.Write([]byte("250 stand-in\r\n"))
+			case "DATA":
+				conn.Write([]byte("354 go ahead\r\n"))
+				for {
+					line, err := r.ReadString('\n')
+					if err != nil || line == ".\r\n" {
+						break
+					}
+					data.WriteString(line)
+				}
+				received <- data.String()
+				conn.Write([]byte("250 queued\r\n"))
+			case "QUIT":
+				conn.Write([]byte("221 bye\r\n"))
+				return
+			default:
+				conn.Write([]byte("250 ok\r\n"))
+			}
+		}
+	}()
+	return listener.Addr().String(), received
+}
+
+func TestEmailPane_ReplySendsAndSaves(t *testing.T) {
+	root := t.TempDir()
+	for _, sub := range []string{"cur", "new", "tmp"} {
+		os.Mkdir(filepath.Join(root, sub), 0700)
+	}
+	original := "Message-ID: <orig@example.com>\r\nReferences: <first@example.com>\r\nFrom: Ann <ann@example.com>\r\n" +
+		"To: me@example.com, Bob <bob@example.com>\r\nCc: carol@example.com\r\nSubject: Plans\r\n" +
+		"Date: Mon, 02 Jan 2006 15:04:05 +0000\r\n\r\nLunch on Friday?\r\n"
+	os.WriteFile(filepath.Join(root, "new", "100.a.host"), []byte(original), 0600)
+	// A newer message pushes the original out of the one-message listing
+	newer := "From: bob@example.com\r\nSubject: Later\r\nDate: Tue, 03 Jan 2006 15:04:05 +0000\r\n\r\nHi\r\n"
+	os.WriteFile(filepath.Join(root, "new", "200.b.host"), []byte(newer), 0600)
+
+	provider, err := providers.NewMaildirProvider(providers.MaildirOptions{Path: root, Limit: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr, received := startSMTPStandIn(t)
+	pane := NewEmailPane(provider)
+	pane.SetMailer(services.NewMailer(services.MailerOptions{Addr: addr, From: "Me <me@example.com>", Insecure: true}))
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/email/{rest...}", func(w http.ResponseWriter, r *http.Request) {
+		if err := pane.HandleAPI(w, r); err != nil {
+			http.Error(w, err.Error(), 500)
+		}
+	})
+	recorder := httptest.NewRecorder()
+	mux.ServeHTTP(recorder, httptest.NewRequest("POST", "/api/email/reply?id=100.a.host", strings.NewReader(`{"body": "Sounds good", "all": true}`)))
+	if recorder.Code != http.StatusCreated {
+		t.Fatalf("Expected status 201, got %d: %s", recorder.Code, recorder.Body.String())
+	}
+
+	sent, err := mail.ParseBytes([]byte(<-received))
+	if err != nil {
+		t.Fatalf("Sent message doesn't parse: %v", err)
+	}
+	if sent.Subject != "Re: Plans" || sent.InReplyTo != "orig@example.com" {
+		t.Errorf("Unexpected reply headers %+v", sent)
+	}
+	if len(sent.References) != 2 || sent.References[1] != "orig@example.com" {
+		t.Errorf("Expected the original appended to References, got %v", sent.References)
+	}
+	if len(sent.To) != 1 || sent.To[0] != "Ann <ann@example.com>" {
+		t.Errorf("Expected the reply to go to Ann, got %v", sent.To)
+	}
+	if len(sent.Cc) != 2 || sent.Cc[0] != "Bob <bob@example.com>" || sent.Cc[1] != "carol@example.com" {
+		t.Errorf("Expected reply-all to copy everyone but me, got %v", sent.Cc)
+	}
+	if !strings.HasPrefix(sent.Text, "Sounds good\r\n\r\nOn ") || !strings.Contains(sent.Text, "> Lunch on Friday?") {
+		t.Errorf("Expected the original quoted, got %q", sent.Text)
+	}
+
+	copies, _ := filepath.Glob(filepath.Join(root, ".Sent", "cur", "*:2,S"))
+	if len(copies) != 1 {
+		t.Errorf("Expected one copy in Sent, got %v", copies)
+	}
+}
+
+func TestEmailPane_SendWithoutMailer(t *testing.T) {
+	pane := NewEmailPane(providers.NewMockProvider())
+
+	req := httptest.NewRequest("POST", "/api/email/send", strings.NewReader(`{"to": ["ann@example.com"], "body": "Hi"}`))
+	req.SetPathValue("rest", "send")
+	recorder := httptest.NewRecorder()
+	if err := pane.HandleAPI(recorder, req); err != nil {
+		t.Fatal(err)
+	}
+	if recorder.Code != http.StatusNotImplemented {
+		t.Errorf("Expected status 501, got %d", recorder.Code)
+	}
+}
@@ -2,22 +2,104 @@ package panes
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html/template"
+	"log"
+	"net/http"
+	"sort"
+	"time"
 
+	"flexpane/internal/mail"
 	"flexpane/internal/models"
 	"flexpane/internal/providers"
+	"flexpane/internal/services"
 )
 
 // EmailPane implements the Pane interface for email messages
 type EmailPane struct {
 	provider providers.DataProvider
+	location *time.Location
+	mailer   *services.Mailer
+	todos    *services.TodoService
+	rules    *services.EmailRules
+	snoozes  *services.SnoozeService
+	events   *services.EventBus
+	pollers  pollers
+}
+
+// EmailDetail is a full message prepared for the reading view. HTML has been
+// sanitized and is safe to embed.
+type EmailDetail struct {
+	models.Email
+	To            []string          `json:"to,omitempty"`
+	Cc            []string          `json:"cc,omitempty"`
+	Text          string            `json:"text"`
+	HTML          template.HTML     `json:"html,omitempty"`
+	BlockedImages int               `json:"blocked_images,omitempty"`
+	Attachments   []mail.Attachment `json:"attachments,omitempty"`
 }
 
 func NewEmailPane(provider providers.DataProvider) *EmailPane {
 	return &EmailPane{
 		provider: provider,
+		location: time.Local,
 	}
 }
 
+// SetTimeZone sets the zone message times are displayed in
+func (ep *EmailPane) SetTimeZone(loc *time.Location) {
+	ep.location = loc
+}
+
+// SetMailer enables compose, reply and forward
+func (ep *EmailPane) SetMailer(mailer *services.Mailer) {
+	ep.mailer = mailer
+}
+
+// SetTodos enables turning emails into todos
+func (ep *EmailPane) SetTodos(todos *services.TodoService) {
+	ep.todos = todos
+}
+
+// SetRules applies triage rules to the listing and enables the dry-run API
+func (ep *EmailPane) SetRules(rules *services.EmailRules) {
+	ep.rules = rules
+}
+
+// SetSnoozes enables snoozing messages
+func (ep *EmailPane) SetSnoozes(snoozes *services.SnoozeService) {
+	ep.snoozes = snoozes
+}
+
+// SetEvents sets where changes made through the pane are announced
+func (ep *EmailPane) SetEvents(events *services.EventBus) {
+	ep.events = events
+}
+
+// SetPollers sets background checks, such as email rules and snoozes, that
+// run while the pane is started
+func (ep *EmailPane) SetPollers(pollers ...*services.Poller) {
+	ep.pollers = pollers
+}
+
+// Start runs the pane's background checks
+func (ep *EmailPane) Start(ctx context.Context) error {
+	return ep.pollers.start(ctx)
+}
+
+// Stop ends the pane's background checks
+func (ep *EmailPane) Stop(ctx context.Context) error {
+	return ep.pollers.stop(ctx)
+}
+
+// Health reports whether the email provider can list messages
+func (ep *EmailPane) Health(ctx context.Context) error {
+	_, err := ep.provider.GetEmails()
+	return err
+}
+
 func (ep *EmailPane) ID() string {
 	return "email"
 }
@@ -26,7 +108,6 @@ func (ep *EmailPane) Title() string {
 	return "Email Preview"
 }
 
-
 func (ep *EmailPane) Template() string {
 	return "panes/email.html"
 }
@@ -40,8 +121,428 @@ func (ep *EmailPane) GetData(ctx context.Context) (interface{}, error) {
 		}, err
 	}
 
-	return map[string]interface{}{
-		"Emails": emails,
-		"Count":  len(emails),
-	}, nil
-}
\ No newline at end of file
+	if ep.rules != nil {
+		emails = ep.rules.Label(emails)
+	}
+	var woke map[string]bool
+	if ep.snoozes != nil {
+		emails, woke = ep.arrange(emails)
+	}
+	for i := range emails {
+		emails[i].Time = emails[i].Time.In(ep.location)
+	}
+
+	_, writable := ep.provider.(providers.EmailWriter)
+	data := map[string]interface{}{
+		"Emails":    emails,
+		"Count":     len(emails),
+		"Writable":  writable,
+		"CanSend":   ep.mailer != nil,
+		"CanTodo":   ep.todos != nil,
+		"CanSnooze": ep.snoozes != nil,
+		"Woke":      woke,
+		// Message links change only the open message, keeping the rest of the page
+		"Open": models.QueryWithout(ctx, ep.ID(), "message", "images") + "&email.message=",
+	}
+
+	// ?email.message=ID opens the reading view
+	if id := models.QueryParam(ctx, "email", "message"); id != "" {
+		detail, err := ep.Message(id, models.QueryParam(ctx, "email", "images") == "1")
+		if err != nil && !errors.Is(err, providers.ErrEmailNotFound) {
+			return data, err
+		}
+		if detail != nil {
+			data["Message"] = detail
+			data["Back"] = models.QueryWithout(ctx, ep.ID(), "message", "images")
+			data["LoadImages"] = models.QueryWithout(ctx, ep.ID(), "images") + "&email.images=1"
+		}
+	}
+
+	data["Threads"] = wokeFirst(services.GroupThreads(emails), woke)
+	return data, nil
+}
+
+// arrange leaves out snoozed messages and moves woken ones to the top
+func (ep *EmailPane) arrange(emails []models.Email) ([]models.Email, map[string]bool) {
+	ids := make([]string, len(emails))
+	for i, email := range emails {
+		ids[i] = email.ID
+	}
+	order, woke := ep.snoozes.Arrange(services.SnoozeEmail, ids)
+	arranged := make([]models.Email, len(order))
+	for i, index := range order {
+		arranged[i] = emails[index]
+	}
+	return arranged, woke
+}
+
+// wokeFirst moves conversations holding a woken message to the top
+func wokeFirst(threads []services.Thread, woke map[string]bool) []services.Thread {
+	if len(woke) == 0 {
+		return threads
+	}
+	hasWoken := func(thread services.Thread) bool {
+		for _, email := range thread.Emails {
+			if woke[email.ID] {
+				return true
+			}
+		}
+		return false
+	}
+	sort.SliceStable(threads, func(i, j int) bool {
+		return hasWoken(threads[i]) && !hasWoken(threads[j])
+	})
+	return threads
+}
+
+// clearSnooze forgets a message's snooze once it has been acted on
+func (ep *EmailPane) clearSnooze(id string) {
+	if ep.snoozes == nil {
+		return
+	}
+	if err := ep.snoozes.Clear(services.SnoozeEmail, id); err != nil {
+		log.Printf("Failed to clear snooze for email %s: %v", id, err)
+	}
+}
+
+// email looks up one message, directly when the provider can
+func (ep *EmailPane) email(id string) (models.Email, error) {
+	if getter, ok := ep.provider.(providers.EmailGetter); ok {
+		return getter.GetEmail(id)
+	}
+
+	emails, err := ep.provider.GetEmails()
+	if err != nil {
+		return models.Email{}, err
+	}
+	for _, email := range emails {
+		if email.ID == id {
+			return email, nil
+		}
+	}
+	return models.Email{}, providers.ErrEmailNotFound
+}
+
+// Message loads one message for reading. Providers that can't return full
+// messages fall back to the preview text.
+func (ep *EmailPane) Message(id string, allowRemoteImages bool) (*EmailDetail, error) {
+	email, err := ep.email(id)
+	if err != nil {
+		return nil, err
+	}
+	email.Time = email.Time.In(ep.location)
+	detail := &EmailDetail{Email: email, Text: email.Preview}
+
+	reader, ok := ep.provider.(providers.MessageReader)
+	if !ok {
+		return detail, nil
+	}
+
+	raw, err := reader.ReadMessage(id)
+	if err != nil {
+		return nil, err
+	}
+	msg, err := mail.ParseBytes(raw)
+	if err != nil {
+		return nil, err
+	}
+
+	detail.To = msg.To
+	detail.Cc = msg.Cc
+	detail.Attachments = msg.Attachments
+	if msg.Text != "" {
+		detail.Text = msg.Text
+	} else if msg.HTML != "" {
+		detail.Text = mail.PlainText(msg.HTML)
+	}
+	if msg.HTML != "" {
+		sanitized := mail.Sanitize(msg.HTML, allowRemoteImages)
+		detail.HTML = template.HTML(sanitized.HTML)
+		detail.BlockedImages = sanitized.BlockedImages
+	}
+
+	return detail, nil
+}
+
+// HandleAPI implements the APIHandler interface. GET /api/email lists
+// messages and GET /api/email/message?id= returns one message; add
+// images=1 to keep remote images. The reading view sends POST /opened?id=
+// once it shows a message, which marks it read and ends its snooze. On
+// writable providers PATCH ?id= with
+// {"read": bool, "flagged": bool} changes flags, POST /archive?id= archives
+// and DELETE ?id= deletes. With a mailer, POST /send, /reply?id= and
+// /forward?id= send mail. POST /todo?id= makes a todo from a message, and
+// GET /rules/test lists the messages the configured rules would match.
+// POST /snooze?id= with {"for": "3h"} or {"until": time} hides a message
+// until later, and DELETE /snooze?id= brings it back.
+func (ep *EmailPane) HandleAPI(w http.ResponseWriter, r *http.Request) error {
+	switch r.PathValue("rest") {
+	case "":
+	case "message":
+		if r.Method != "GET" {
+			http.Error(w, "Method Not Allowed", 405)
+			return nil
+		}
+		return ep.handleMessage(w, r)
+	case "opened":
+		if r.Method != "POST" {
+			http.Error(w, "Method Not Allowed", 405)
+			return nil
+		}
+		return ep.handleOpened(w, r)
+	case "archive":
+		if r.Method != "POST" {
+			http.Error(w, "Method Not Allowed", 405)
+			return nil
+		}
+		return ep.handleAction(w, r, "archived", providers.EmailWriter.Archive)
+	case "send", "reply", "forward":
+		if r.Method != "POST" {
+			http.Error(w, "Method Not Allowed", 405)
+			return nil
+		}
+		return ep.handleSend(w, r, r.PathValue("rest"))
+	case "todo":
+		if r.Method != "POST" {
+			http.Error(w, "Method Not Allowed", 405)
+			return nil
+		}
+		return ep.handleMakeTodo(w, r)
+	case "rules/test":
+		if r.Method != "GET" {
+			http.Error(w, "Method Not Allowed", 405)
+			return nil
+		}
+		return ep.handleRulesTest(w, r)
+	case "snooze":
+		return handleSnooze(w, r, ep.snoozes, services.SnoozeEmail, ep.subject)
+	default:
+		http.NotFound(w, r)
+		return nil
+	}
+
+	switch r.Method {
+	case "PATCH":
+		return ep.handleUpdate(w, r)
+
+	case "DELETE":
+		return ep.handleAction(w, r, "deleted", providers.EmailWriter.DeleteEmail)
+
+	default:
+		http.Error(w, "Method Not Allowed", 405)
+		return nil
+	}
+}
+
+func (ep *EmailPane) handleMessage(w http.ResponseWriter, r *http.Request) error {
+	id := r.URL.Query().Get("id")
+	if id == "" {
+		http.Error(w, "ID required", 400)
+		return nil
+	}
+	detail, err := ep.Message(id, r.URL.Query().Get("images") == "1")
+	if errors.Is(err, providers.ErrEmailNotFound) {
+		http.Error(w, "Email not found", 404)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	w.Header().Set("Content-Type", "application/json")
+	return json.NewEncoder(w).Encode(detail)
+}
+
+// handleOpened marks a message read and clears its snooze when the reading
+// view shows it. This is a separate request rather than part of GetData so
+// refreshing the page doesn't write to the mailbox again, or undo a snooze
+// set while the message was open.
+func (ep *EmailPane) handleOpened(w http.ResponseWriter, r *http.Request) error {
+	id := r.URL.Query().Get("id")
+	if id == "" {
+		http.Error(w, "ID required", 400)
+		return nil
+	}
+	email, err := ep.email(id)
+	if errors.Is(err, providers.ErrEmailNotFound) {
+		http.Error(w, "Email not found", 404)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	if writer, ok := ep.provider.(providers.EmailWriter); ok && !email.Read {
+		if err := writer.SetRead(id, true); err != nil {
+			return err
+		}
+		email.Read = true
+		ep.events.Publish(services.EventEmailChanged, ep.ID())
+	}
+	ep.clearSnooze(id)
+
+	w.Header().Set("Content-Type", "application/json")
+	return json.NewEncoder(w).Encode(map[string]interface{}{"status": "opened", "read": email.Read})
+}
+
+// emailUpdate changes the flags that are present and leaves the rest alone
+type emailUpdate struct {
+	Read    *bool `json:"read"`
+	Flagged *bool `json:"flagged"`
+}
+
+func (ep *EmailPane) handleUpdate(w http.ResponseWriter, r *http.Request) error {
+	writer, id, ok := ep.writerFor(w, r)
+	if !ok {
+		return nil
+	}
+
+	var update emailUpdate
+	if err := json.NewDecoder(r.Body).Decode(&update); err != nil {
+		http.Error(w, "Invalid JSON", 400)
+		return nil
+	}
+	if update.Read == nil && update.Flagged == nil {
+		http.Error(w, "Nothing to update", 400)
+		return nil
+	}
+
+	var err error
+	if update.Read != nil {
+		err = writer.SetRead(id, *update.Read)
+	}
+	if err == nil && update.Flagged != nil {
+		err = writer.SetFlagged(id, *update.Flagged)
+	}
+	if errors.Is(err, providers.ErrEmailNotFound) {
+		http.Error(w, "Email not found", 404)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	ep.clearSnooze(id)
+	ep.events.Publish(services.EventEmailChanged, ep.ID())
+
+	w.Header().Set("Content-Type", "application/json")
+	return json.NewEncoder(w).Encode(map[string]string{"status": "updated"})
+}
+
+func (ep *EmailPane) handleAction(w http.ResponseWriter, r *http.Request, status string, action func(providers.EmailWriter, string) error) error {
+	writer, id, ok := ep.writerFor(w, r)
+	if !ok {
+		return nil
+	}
+
+	err := action(writer, id)
+	if errors.Is(err, providers.ErrEmailNotFound) {
+		http.Error(w, "Email not found", 404)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	ep.clearSnooze(id)
+	ep.events.Publish(services.EventEmailChanged, ep.ID())
+
+	w.Header().Set("Content-Type", "application/json")
+	return json.NewEncoder(w).Encode(map[string]string{"status": status})
+}
+
+// handleMakeTodo creates a todo from a message, with the subject as its text
+// and the sender in its notes. {"archive": true} also archives the message.
+func (ep *EmailPane) handleMakeTodo(w http.ResponseWriter, r *http.Request) error {
+	if ep.todos == nil {
+		http.Error(w, "Todos are not configured", 501)
+		return nil
+	}
+	id := r.URL.Query().Get("id")
+	if id == "" {
+		http.Error(w, "ID required", 400)
+		return nil
+	}
+
+	var req struct {
+		Archive bool `json:"archive"`
+	}
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid JSON", 400)
+			return nil
+		}
+	}
+	writer, writable := ep.provider.(providers.EmailWriter)
+	if req.Archive && !writable {
+		http.Error(w, "Email is read-only", 501)
+		return nil
+	}
+
+	email, err := ep.email(id)
+	if errors.Is(err, providers.ErrEmailNotFound) {
+		http.Error(w, "Email not found", 404)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	todo, err := ep.todos.Add(services.TodoFromEmail(email))
+	if err != nil {
+		return err
+	}
+
+	if req.Archive {
+		if err := writer.Archive(id); err != nil {
+			return fmt.Errorf("todo created but archive failed: %w", err)
+		}
+		ep.events.Publish(services.EventEmailChanged, ep.ID())
+	}
+	ep.clearSnooze(id)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	return json.NewEncoder(w).Encode(todo)
+}
+
+// subject looks up a message's subject for snooze notifications
+func (ep *EmailPane) subject(id string) (string, bool, error) {
+	email, err := ep.email(id)
+	if errors.Is(err, providers.ErrEmailNotFound) {
+		return "", false, nil
+	}
+	if err != nil {
+		return "", false, err
+	}
+	return email.Subject, true, nil
+}
+
+// handleRulesTest is a dry run of the rules over the current mailbox
+func (ep *EmailPane) handleRulesTest(w http.ResponseWriter, r *http.Request) error {
+	if ep.rules == nil {
+		http.Error(w, "Email rules are not configured", 501)
+		return nil
+	}
+	matches, err := ep.rules.DryRun()
+	if err != nil {
+		return err
+	}
+	w.Header().Set("Content-Type", "application/json")
+	return json.NewEncoder(w).Encode(map[string]interface{}{
+		"matches": matches,
+		"count":   len(matches),
+	})
+}
+
+// writerFor checks the provider is writable and the request names a message
+func (ep *EmailPane) writerFor(w http.ResponseWriter, r *http.Request) (providers.EmailWriter, string, bool) {
+	writer, ok := ep.provider.(providers.EmailWriter)
+	if !ok {
+		http.Error(w, "Email is read-only", 501)
+		return nil, "", false
+	}
+	id := r.URL.Query().Get("id")
+	if id == "" {
+		http.Error(w, "ID required", 400)
+		return nil, "", false
+	}
+	return writer, id, true
+}
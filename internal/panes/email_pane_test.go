@@ -0,0 +1,191 @@
+package panes
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"flexpane/internal/models"
+	"flexpane/internal/providers"
+	"flexpane/internal/services"
+)
+
+func TestEmailPane_Message(t *testing.T) {
+	pane := NewEmailPane(providers.NewMockProvider())
+
+	// HTML-only newsletter with a tracking pixel and a script
+	detail, err := pane.Message("3", false)
+	if err != nil {
+		t.Fatalf("Message failed: %v", err)
+	}
+	if strings.Contains(string(detail.HTML), "script") || strings.Contains(string(detail.HTML), "pixel.gif") {
+		t.Errorf("Expected sanitized HTML, got %s", detail.HTML)
+	}
+	if detail.BlockedImages != 1 {
+		t.Errorf("Expected 1 blocked image, got %d", detail.BlockedImages)
+	}
+	if !strings.HasPrefix(detail.Text, "This week in AI") {
+		t.Errorf("Expected plaintext fallback from HTML, got %q", detail.Text)
+	}
+	if len(detail.To) != 1 || detail.To[0] != "me@company.com" {
+		t.Errorf("Unexpected recipients %v", detail.To)
+	}
+
+	if _, err := pane.Message("missing", false); err != providers.ErrEmailNotFound {
+		t.Errorf("Expected ErrEmailNotFound, got %v", err)
+	}
+}
+
+func TestEmailPane_MessageFallsBackToPreview(t *testing.T) {
+	provider := &stubProvider{emails: []models.Email{
+		{ID: "a", Subject: "Hello", Preview: "Short preview", Time: time.Now()},
+	}}
+	pane := NewEmailPane(provider)
+
+	ctx := models.WithQuery(context.Background(), url.Values{"email.message": {"a"}})
+	result, err := pane.GetData(ctx)
+	if err != nil {
+		t.Fatalf("GetData failed: %v", err)
+	}
+
+	detail := result.(map[string]interface{})["Message"].(*EmailDetail)
+	if detail.Text != "Short preview" || detail.HTML != "" {
+		t.Errorf("Expected preview as the body, got %+v", detail)
+	}
+}
+
+func TestEmailPane_OpeningMarksRead(t *testing.T) {
+	provider := providers.NewMockProvider()
+	snoozes := services.NewSnoozeService(filepath.Join(t.TempDir(), "snoozes.json"))
+	pane := NewEmailPane(provider)
+	pane.SetSnoozes(snoozes)
+	if err := snoozes.Snooze(services.SnoozeEmail, "1", "Budget Meeting", time.Now().Add(time.Hour), false); err != nil {
+		t.Fatal(err)
+	}
+
+	// Showing the message changes nothing, so refreshing the page is safe
+	query := url.Values{"email.message": {"1"}, "calendar.view": {"week"}}
+	result, err := pane.GetData(models.WithQuery(context.Background(), query))
+	if err != nil {
+		t.Fatalf("GetData failed: %v", err)
+	}
+	data := result.(map[string]interface{})
+	if !data["Writable"].(bool) {
+		t.Error("Expected the mock mailbox to be writable")
+	}
+	if data["Message"].(*EmailDetail).Read {
+		t.Error("Expected GetData to leave the message unread")
+	}
+	if data["Back"] != "?calendar.view=week" {
+		t.Errorf("Expected the back link to keep other panes' state, got %q", data["Back"])
+	}
+	if data["Open"] != "?calendar.view=week&email.message=" {
+		t.Errorf("Expected message links to keep other panes' state, got %q", data["Open"])
+	}
+	if order, _ := snoozes.Arrange(services.SnoozeEmail, []string{"1"}); len(order) != 0 {
+		t.Error("Expected GetData to leave the snooze alone")
+	}
+
+	req := httptest.NewRequest("POST", "/api/email/opened?id=1", nil)
+	req.SetPathValue("rest", "opened")
+	recorder := httptest.NewRecorder()
+	if err := pane.HandleAPI(recorder, req); err != nil {
+		t.Fatalf("HandleAPI failed: %v", err)
+	}
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %s", recorder.Code, recorder.Body.String())
+	}
+
+	email, _ := provider.GetEmail("1")
+	if !email.Read {
+		t.Error("Expected the provider to record the message as read")
+	}
+	if order, _ := snoozes.Arrange(services.SnoozeEmail, []string{"1"}); len(order) != 1 {
+		t.Error("Expected opening the message to end its snooze")
+	}
+}
+
+func TestEmailPane_MakeTodo(t *testing.T) {
+	provider := providers.NewMockProvider()
+	todos := services.NewTodoService(filepath.Join(t.TempDir(), "todos.json"))
+	pane := NewEmailPane(provider)
+	pane.SetTodos(todos)
+
+	req := httptest.NewRequest("POST", "/api/email/todo?id=1", strings.NewReader(`{"archive": true}`))
+	req.SetPathValue("rest", "todo")
+	recorder := httptest.NewRecorder()
+	if err := pane.HandleAPI(recorder, req); err != nil {
+		t.Fatalf("HandleAPI failed: %v", err)
+	}
+	if recorder.Code != http.StatusCreated {
+		t.Fatalf("Expected status 201, got %d: %s", recorder.Code, recorder.Body.String())
+	}
+
+	list := todos.GetTodos()
+	if len(list) != 1 || list[0].Message != "Budget Meeting" || list[0].Notes != "From sarah@company.com" || list[0].EmailID != "1" {
+		t.Errorf("Unexpected todo %+v", list)
+	}
+
+	emails, _ := provider.GetEmails()
+	for _, email := range emails {
+		if email.ID == "1" {
+			t.Error("Expected the email to be archived")
+		}
+	}
+
+	req = httptest.NewRequest("POST", "/api/email/todo?id=1", nil)
+	req.SetPathValue("rest", "todo")
+	recorder = httptest.NewRecorder()
+	pane.HandleAPI(recorder, req)
+	if recorder.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 for an archived email, got %d", recorder.Code)
+	}
+}
+
+func TestEmailPane_Rules(t *testing.T) {
+	provider := providers.NewMockProvider()
+	todos := services.NewTodoService(filepath.Join(t.TempDir(), "todos.json"))
+	pane := NewEmailPane(provider)
+
+	req := httptest.NewRequest("GET", "/api/email/rules/test", nil)
+	req.SetPathValue("rest", "rules/test")
+	recorder := httptest.NewRecorder()
+	pane.HandleAPI(recorder, req)
+	if recorder.Code != http.StatusNotImplemented {
+		t.Errorf("Expected 501 without rules, got %d", recorder.Code)
+	}
+
+	pane.SetRules(services.NewEmailRules(provider, []services.EmailRule{
+		{Name: "Newsletters", From: "news@", Hide: true},
+		{Subject: "budget", Label: "finance"},
+	}, todos, nil))
+
+	result, err := pane.GetData(context.Background())
+	if err != nil {
+		t.Fatalf("GetData failed: %v", err)
+	}
+	data := result.(map[string]interface{})
+	if data["Count"] != 3 {
+		t.Errorf("Expected the newsletter hidden, got %d emails", data["Count"])
+	}
+	for _, email := range data["Emails"].([]models.Email) {
+		if email.ID == "1" && (len(email.Labels) != 1 || email.Labels[0] != "finance") {
+			t.Errorf("Expected the budget email labelled, got %+v", email)
+		}
+	}
+
+	recorder = httptest.NewRecorder()
+	if err := pane.HandleAPI(recorder, req); err != nil {
+		t.Fatalf("HandleAPI failed: %v", err)
+	}
+	for _, want := range []string{`"count":3`, `"Newsletters"`, `"email_id":"4"`} {
+		if !strings.Contains(recorder.Body.String(), want) {
+			t.Errorf("Expected %s in %s", want, recorder.Body.String())
+		}
+	}
+}
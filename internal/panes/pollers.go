@@ -0,0 +1,29 @@
+package panes
+
+import (
+	"context"
+	"errors"
+
+	"flexpane/internal/services"
+)
+
+// pollers are the background checks a pane runs while it is started
+type pollers []*services.Poller
+
+func (ps pollers) start(ctx context.Context) error {
+	for _, poller := range ps {
+		poller.Start(ctx)
+	}
+	return nil
+}
+
+// stop stops the checks in reverse order, waiting at most until ctx is done
+func (ps pollers) stop(ctx context.Context) error {
+	var errs []error
+	for i := len(ps) - 1; i >= 0; i-- {
+		if err := ps[i].Stop(ctx); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
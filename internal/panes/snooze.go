@@ -0,0 +1,82 @@
+package panes
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"flexpane/internal/services"
+)
+
+// snoozeRequest names when a snoozed item comes back, either after a
+// duration ("for": "3h") or at a time ("until")
+type snoozeRequest struct {
+	For    string    `json:"for"`
+	Until  time.Time `json:"until"`
+	Notify bool      `json:"notify"`
+}
+
+// handleSnooze serves POST ?id= to snooze an item and DELETE ?id= to bring it
+// back early. lookup returns the item's title and whether it exists.
+func handleSnooze(w http.ResponseWriter, r *http.Request, snoozes *services.SnoozeService, kind string, lookup func(id string) (string, bool, error)) error {
+	if snoozes == nil {
+		http.Error(w, "Snooze is not configured", 501)
+		return nil
+	}
+	id := r.URL.Query().Get("id")
+	if id == "" {
+		http.Error(w, "ID required", 400)
+		return nil
+	}
+
+	switch r.Method {
+	case "POST":
+	case "DELETE":
+		if err := snoozes.Clear(kind, id); err != nil {
+			return err
+		}
+		w.Header().Set("Content-Type", "application/json")
+		return json.NewEncoder(w).Encode(map[string]string{"status": "unsnoozed"})
+	default:
+		http.Error(w, "Method Not Allowed", 405)
+		return nil
+	}
+
+	var req snoozeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid JSON", 400)
+		return nil
+	}
+	until := req.Until
+	if req.For != "" {
+		d, err := time.ParseDuration(req.For)
+		if err != nil || d <= 0 {
+			http.Error(w, "Invalid snooze duration", 400)
+			return nil
+		}
+		until = time.Now().Add(d)
+	}
+	if until.IsZero() {
+		http.Error(w, "Snooze time required", 400)
+		return nil
+	}
+	if !until.After(time.Now()) {
+		http.Error(w, "Snooze time must be in the future", 400)
+		return nil
+	}
+
+	title, exists, err := lookup(id)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		http.Error(w, "Item not found", 404)
+		return nil
+	}
+	if err := snoozes.Snooze(kind, id, title, until, req.Notify); err != nil {
+		return err
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	return json.NewEncoder(w).Encode(map[string]interface{}{"status": "snoozed", "until": until})
+}
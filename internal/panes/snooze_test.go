@@ -0,0 +1,89 @@
+package panes
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"flexpane/internal/models"
+	"flexpane/internal/providers"
+	"flexpane/internal/services"
+)
+
+func doSnooze(t *testing.T, pane models.APIHandler, method, target, body string) *httptest.ResponseRecorder {
+	t.Helper()
+	req := httptest.NewRequest(method, target, strings.NewReader(body))
+	req.SetPathValue("rest", "snooze")
+	recorder := httptest.NewRecorder()
+	if err := pane.HandleAPI(recorder, req); err != nil {
+		t.Fatalf("HandleAPI failed: %v", err)
+	}
+	return recorder
+}
+
+func TestEmailPane_Snooze(t *testing.T) {
+	pane := NewEmailPane(providers.NewMockProvider())
+	if recorder := doSnooze(t, pane, "POST", "/api/email/snooze?id=1", `{"for": "1h"}`); recorder.Code != http.StatusNotImplemented {
+		t.Errorf("Expected 501 without a snooze store, got %d", recorder.Code)
+	}
+
+	snoozes := services.NewSnoozeService(filepath.Join(t.TempDir(), "snoozes.json"))
+	pane.SetSnoozes(snoozes)
+
+	for body, code := range map[string]int{
+		`{"for": "soon"}`:                   http.StatusBadRequest,
+		`{}`:                                http.StatusBadRequest,
+		`{"until": "2000-01-01T00:00:00Z"}`: http.StatusBadRequest,
+	} {
+		if recorder := doSnooze(t, pane, "POST", "/api/email/snooze?id=1", body); recorder.Code != code {
+			t.Errorf("%s: expected %d, got %d", body, code, recorder.Code)
+		}
+	}
+	if recorder := doSnooze(t, pane, "POST", "/api/email/snooze?id=missing", `{"for": "1h"}`); recorder.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 for an unknown email, got %d", recorder.Code)
+	}
+
+	if recorder := doSnooze(t, pane, "POST", "/api/email/snooze?id=1", `{"for": "1h", "notify": true}`); recorder.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %s", recorder.Code, recorder.Body.String())
+	}
+	result, err := pane.GetData(context.Background())
+	if err != nil {
+		t.Fatalf("GetData failed: %v", err)
+	}
+	for _, email := range result.(map[string]interface{})["Emails"].([]models.Email) {
+		if email.ID == "1" {
+			t.Error("Expected the snoozed email hidden")
+		}
+	}
+
+	doSnooze(t, pane, "DELETE", "/api/email/snooze?id=1", "")
+	result, _ = pane.GetData(context.Background())
+	if count := result.(map[string]interface{})["Count"]; count != 4 {
+		t.Errorf("Expected the email back after unsnoozing, got %v emails", count)
+	}
+}
+
+func TestTodoPane_Snooze(t *testing.T) {
+	todos := services.NewTodoService(filepath.Join(t.TempDir(), "todos.json"))
+	todos.AddTodo("First")
+	todos.AddTodo("Second")
+	pane := NewTodoPane(todos)
+	pane.SetSnoozes(services.NewSnoozeService(filepath.Join(t.TempDir(), "snoozes.json")))
+
+	first := todos.GetTodos()[0]
+	if recorder := doSnooze(t, pane, "POST", "/api/todos/snooze?id="+first.ID, `{"for": "1h"}`); recorder.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %s", recorder.Code, recorder.Body.String())
+	}
+
+	result, err := pane.GetData(context.Background())
+	if err != nil {
+		t.Fatalf("GetData failed: %v", err)
+	}
+	items := result.(map[string]interface{})["Todos"].([]TodoItem)
+	if len(items) != 1 || items[0].Message != "Second" || items[0].Index != 1 {
+		t.Errorf("Expected only the second todo, keeping its index, got %+v", items)
+	}
+}
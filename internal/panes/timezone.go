@@ -0,0 +1,24 @@
+package panes
+
+import (
+	"time"
+
+	"flexpane/internal/models"
+)
+
+// eventInZone converts an event's times to loc for display. All-day events
+// keep their calendar dates, since a date has no zone of its own.
+func eventInZone(event models.Event, loc *time.Location) models.Event {
+	if event.AllDay {
+		event.Start = sameDateIn(event.Start, loc)
+		event.End = sameDateIn(event.End, loc)
+		return event
+	}
+	event.Start = event.Start.In(loc)
+	event.End = event.End.In(loc)
+	return event
+}
+
+func sameDateIn(t time.Time, loc *time.Location) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, loc)
+}
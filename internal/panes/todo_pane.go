@@ -5,13 +5,26 @@ import (
 	"encoding/json"
 	"net/http"
 	"strconv"
+	"strings"
+	"time"
 
+	"flexpane/internal/models"
 	"flexpane/internal/services"
 )
 
 // TodoPane implements the Pane interface for todo items
 type TodoPane struct {
 	todoService *services.TodoService
+	snoozes     *services.SnoozeService
+	pollers     pollers
+}
+
+// TodoItem is a todo as listed in the pane. Index is its position in the
+// store, which toggling addresses, since snoozing changes the order shown.
+type TodoItem struct {
+	models.Todo
+	Index int  `json:"index"`
+	Woke  bool `json:"woke,omitempty"` // Back from snooze
 }
 
 func NewTodoPane(todoService *services.TodoService) *TodoPane {
@@ -20,6 +33,27 @@ func NewTodoPane(todoService *services.TodoService) *TodoPane {
 	}
 }
 
+// SetSnoozes enables snoozing todos
+func (tp *TodoPane) SetSnoozes(snoozes *services.SnoozeService) {
+	tp.snoozes = snoozes
+}
+
+// SetPollers sets background checks, such as snoozes and reminders, that
+// run while the pane is started
+func (tp *TodoPane) SetPollers(pollers ...*services.Poller) {
+	tp.pollers = pollers
+}
+
+// Start runs the pane's background checks
+func (tp *TodoPane) Start(ctx context.Context) error {
+	return tp.pollers.start(ctx)
+}
+
+// Stop ends the pane's background checks
+func (tp *TodoPane) Stop(ctx context.Context) error {
+	return tp.pollers.stop(ctx)
+}
+
 func (tp *TodoPane) ID() string {
 	return "todos"
 }
@@ -28,7 +62,6 @@ func (tp *TodoPane) Title() string {
 	return "Todos"
 }
 
-
 func (tp *TodoPane) Template() string {
 	return "panes/todos.html"
 }
@@ -36,23 +69,50 @@ func (tp *TodoPane) Template() string {
 func (tp *TodoPane) GetData(ctx context.Context) (interface{}, error) {
 	todos := tp.todoService.GetTodos()
 
+	items := make([]TodoItem, 0, len(todos))
+	if tp.snoozes == nil {
+		for i, todo := range todos {
+			items = append(items, TodoItem{Todo: todo, Index: i})
+		}
+	} else {
+		ids := make([]string, len(todos))
+		for i, todo := range todos {
+			ids[i] = todo.ID
+		}
+		order, woke := tp.snoozes.Arrange(services.SnoozeTodo, ids)
+		for _, i := range order {
+			items = append(items, TodoItem{Todo: todos[i], Index: i, Woke: woke[todos[i].ID]})
+		}
+	}
+
 	return map[string]interface{}{
-		"Todos": todos,
-		"Count": len(todos),
+		"Todos":     items,
+		"Count":     len(items),
+		"CanSnooze": tp.snoozes != nil,
 	}, nil
 }
 
-// HandleAPI implements the APIHandler interface for todo-specific operations
+// HandleAPI implements the APIHandler interface for todo-specific operations.
+// GET /items/{id} returns one todo and PATCH /items/{id} with {"done": true}
+// sets whether it is done. POST /snooze?id= with {"for": "3h"} or
+// {"until": time} hides a todo until later, and DELETE /snooze?id= brings it
+// back.
 func (tp *TodoPane) HandleAPI(w http.ResponseWriter, r *http.Request) error {
-	switch r.Method {
-	case "GET":
-		data, err := tp.GetData(r.Context())
-		if err != nil {
-			return err
-		}
-		w.Header().Set("Content-Type", "application/json")
-		return json.NewEncoder(w).Encode(data)
+	rest := r.PathValue("rest")
+	if id, ok := strings.CutPrefix(rest, "items/"); ok && id != "" && !strings.Contains(id, "/") {
+		return tp.handleItem(w, r, id)
+	}
+
+	switch rest {
+	case "":
+	case "snooze":
+		return handleSnooze(w, r, tp.snoozes, services.SnoozeTodo, tp.todoMessage)
+	default:
+		http.NotFound(w, r)
+		return nil
+	}
 
+	switch r.Method {
 	case "POST":
 		return tp.handleAddTodo(w, r)
 
@@ -68,22 +128,36 @@ func (tp *TodoPane) HandleAPI(w http.ResponseWriter, r *http.Request) error {
 func (tp *TodoPane) handleAddTodo(w http.ResponseWriter, r *http.Request) error {
 	var req struct {
 		Message string `json:"message"`
+		Due     string `json:"due"` // Optional date (2006-01-02) or RFC 3339 time
 	}
-	
+
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		http.Error(w, "Invalid JSON", 400)
 		return nil
 	}
-	
+
 	if req.Message == "" {
 		http.Error(w, "Message required", 400)
 		return nil
 	}
-	
-	if err := tp.todoService.AddTodo(req.Message); err != nil {
+
+	var due *time.Time
+	allDay := false
+	if req.Due != "" {
+		if t, err := time.ParseInLocation("2006-01-02", req.Due, time.UTC); err == nil {
+			due, allDay = &t, true
+		} else if t, err := time.Parse(time.RFC3339, req.Due); err == nil {
+			due = &t
+		} else {
+			http.Error(w, "Invalid due date", 400)
+			return nil
+		}
+	}
+
+	if err := tp.todoService.AddTodoDue(req.Message, due, allDay); err != nil {
 		return err
 	}
-	
+
 	w.WriteHeader(201)
 	return json.NewEncoder(w).Encode(map[string]string{"status": "created"})
 }
@@ -94,16 +168,82 @@ func (tp *TodoPane) handleToggleTodo(w http.ResponseWriter, r *http.Request) err
 		http.Error(w, "Index required", 400)
 		return nil
 	}
-	
+
 	index, err := strconv.Atoi(indexStr)
 	if err != nil || index < 0 {
 		http.Error(w, "Invalid index", 400)
 		return nil
 	}
-	
+
+	todos := tp.todoService.GetTodos()
 	if err := tp.todoService.ToggleTodo(index); err != nil {
 		return err
 	}
-	
+	if tp.snoozes != nil && index < len(todos) {
+		// Acting on a todo that woke from a snooze settles it
+		if err := tp.snoozes.Clear(services.SnoozeTodo, todos[index].ID); err != nil {
+			return err
+		}
+	}
+
 	return json.NewEncoder(w).Encode(map[string]string{"status": "updated"})
-}
\ No newline at end of file
+}
+
+// handleItem serves one todo addressed by ID, which unlike an index stays
+// put when other todos are added or snoozed
+func (tp *TodoPane) handleItem(w http.ResponseWriter, r *http.Request, id string) error {
+	var todo models.Todo
+	exists := false
+	for _, t := range tp.todoService.GetTodos() {
+		if t.ID == id {
+			todo, exists = t, true
+			break
+		}
+	}
+	if !exists {
+		http.Error(w, "Todo not found", 404)
+		return nil
+	}
+
+	switch r.Method {
+	case "GET":
+	case "PATCH":
+		var req struct {
+			Done *bool `json:"done"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid JSON", 400)
+			return nil
+		}
+		if req.Done == nil {
+			http.Error(w, "Done required", 400)
+			return nil
+		}
+		if _, err := tp.todoService.SetDone(id, *req.Done); err != nil {
+			return err
+		}
+		if tp.snoozes != nil {
+			// Acting on a todo that woke from a snooze settles it
+			if err := tp.snoozes.Clear(services.SnoozeTodo, id); err != nil {
+				return err
+			}
+		}
+		todo.Done = *req.Done
+	default:
+		http.Error(w, "Method Not Allowed", 405)
+		return nil
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	return json.NewEncoder(w).Encode(todo)
+}
+
+// todoMessage looks up a todo's text for snooze notifications
+func (tp *TodoPane) todoMessage(id string) (string, bool, error) {
+	for _, todo := range tp.todoService.GetTodos() {
+		if todo.ID == id {
+			return todo.Message, true, nil
+		}
+	}
+	return "", false, nil
+}
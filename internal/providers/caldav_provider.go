@@ -0,0 +1,324 @@
+package providers
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"flexpane/internal/ical"
+	"flexpane/internal/models"
+	"flexpane/internal/secrets"
+)
+
+func init() {
+	Register("caldav", Registration{
+		New: func(settings Settings) (DataProvider, error) {
+			return NewCalDAVProvider(CalDAVOptions{
+				URL:      settings.String("url"),
+				Username: settings.String("username"),
+				Password: settings.Secret("password"),
+				Window:   settings.Duration("window"),
+				Timeout:  settings.Duration("timeout"),
+			})
+		},
+		Schema: ConfigSchema{
+			"url":      {Type: "string", Required: true, Description: "Calendar collection URL"},
+			"username": {Type: "string", Description: "Basic auth username"},
+			"password": {Type: "secret", Description: "Basic auth password, usually secret://name"},
+			"window":   {Type: "duration", Default: "720h", Description: "How far before and after now to fetch events"},
+			"timeout":  {Type: "duration", Default: "15s", Description: "HTTP request timeout"},
+		},
+	})
+}
+
+// CalDAVOptions configures a CalDAV calendar
+type CalDAVOptions struct {
+	URL      string
+	Username string
+	Password secrets.Value
+	Window   time.Duration
+	Timeout  time.Duration
+}
+
+// CalDAVProvider reads and writes events in a single CalDAV calendar
+// collection. It has no mailbox, so GetEmails always returns an empty list.
+type CalDAVProvider struct {
+	collection *url.URL
+	options    CalDAVOptions
+	client     *http.Client
+	now        func() time.Time
+
+	mutex     sync.Mutex
+	resources map[string]calDAVResource // Event ID to href and etag from the last fetch
+}
+
+type calDAVResource struct {
+	href string
+	etag string
+}
+
+func NewCalDAVProvider(options CalDAVOptions) (*CalDAVProvider, error) {
+	collection, err := url.Parse(options.URL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid CalDAV URL: %w", err)
+	}
+	if !strings.HasSuffix(collection.Path, "/") {
+		collection.Path += "/"
+	}
+	if options.Window <= 0 {
+		options.Window = 30 * 24 * time.Hour
+	}
+	if options.Timeout <= 0 {
+		options.Timeout = 15 * time.Second
+	}
+
+	return &CalDAVProvider{
+		collection: collection,
+		options:    options,
+		client:     &http.Client{Timeout: options.Timeout},
+		now:        time.Now,
+		resources:  make(map[string]calDAVResource),
+	}, nil
+}
+
+const calendarQuery = `<?xml version="1.0" encoding="utf-8"?>
+<c:calendar-query xmlns:d="DAV:" xmlns:c="urn:ietf:params:xml:ns:caldav">
+  <d:prop><d:getetag/><c:calendar-data/></d:prop>
+  <c:filter>
+    <c:comp-filter name="VCALENDAR">
+      <c:comp-filter name="VEVENT">
+        <c:time-range start="%s" end="%s"/>
+      </c:comp-filter>
+    </c:comp-filter>
+  </c:filter>
+</c:calendar-query>`
+
+type multistatus struct {
+	Responses []struct {
+		Href     string `xml:"href"`
+		Propstat []struct {
+			Status string `xml:"status"`
+			Prop   struct {
+				ETag         string `xml:"getetag"`
+				CalendarData string `xml:"calendar-data"`
+			} `xml:"prop"`
+		} `xml:"propstat"`
+	} `xml:"response"`
+}
+
+func (c *CalDAVProvider) GetCalendarEvents() ([]models.Event, error) {
+	now := c.now().UTC()
+	body := fmt.Sprintf(calendarQuery,
+		now.Add(-c.options.Window).Format("20060102T150405Z"),
+		now.Add(c.options.Window).Format("20060102T150405Z"))
+
+	resp, err := c.do("REPORT", c.collection.String(), strings.NewReader(body), map[string]string{
+		"Content-Type": "application/xml; charset=utf-8",
+		"Depth":        "1",
+	})
+	if err != nil {
+		return []models.Event{}, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusMultiStatus {
+		return []models.Event{}, newStatusError("caldav REPORT", resp)
+	}
+
+	var result multistatus
+	if err := xml.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return []models.Event{}, fmt.Errorf("caldav REPORT: %w", err)
+	}
+
+	events := []models.Event{}
+	resources := make(map[string]calDAVResource)
+	for _, response := range result.Responses {
+		for _, propstat := range response.Propstat {
+			if propstat.Prop.CalendarData == "" || !strings.Contains(propstat.Status, " 200 ") {
+				continue
+			}
+			decoded, err := ical.DecodeEvents(strings.NewReader(propstat.Prop.CalendarData))
+			if err != nil {
+				return []models.Event{}, fmt.Errorf("caldav %s: %w", response.Href, err)
+			}
+			for _, event := range decoded {
+				resources[event.ID] = calDAVResource{href: response.Href, etag: propstat.Prop.ETag}
+			}
+			events = append(events, decoded...)
+		}
+	}
+
+	c.mutex.Lock()
+	c.resources = resources
+	c.mutex.Unlock()
+
+	return events, nil
+}
+
+func (c *CalDAVProvider) GetEmails() ([]models.Email, error) {
+	return []models.Email{}, nil
+}
+
+func (c *CalDAVProvider) CreateEvent(event models.Event) (models.Event, error) {
+	if event.ID == "" {
+		event.ID = newEventID()
+	}
+
+	href := c.collection.JoinPath(url.PathEscape(event.ID) + ".ics").String()
+	etag, err := c.put(href, event, map[string]string{"If-None-Match": "*"})
+	if err != nil {
+		return event, err
+	}
+
+	c.mutex.Lock()
+	c.resources[event.ID] = calDAVResource{href: href, etag: etag}
+	c.mutex.Unlock()
+	return event, nil
+}
+
+// UpdateEvent replaces an event only if it is unchanged since it was last
+// fetched or written, or, for an event not seen yet, only if it exists
+func (c *CalDAVProvider) UpdateEvent(event models.Event) (models.Event, error) {
+	resource := c.resource(event.ID)
+
+	headers := map[string]string{"If-Match": "*"}
+	if resource.etag != "" {
+		headers["If-Match"] = resource.etag
+	}
+	etag, err := c.put(resource.href, event, headers)
+	if err != nil {
+		return event, err
+	}
+
+	// Without an ETag in the response the next update falls back to "*"
+	c.mutex.Lock()
+	c.resources[event.ID] = calDAVResource{href: resource.href, etag: etag}
+	c.mutex.Unlock()
+	return event, nil
+}
+
+func (c *CalDAVProvider) DeleteEvent(id string) error {
+	resp, err := c.do("DELETE", c.resource(id).href, nil, nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		return ErrEventNotFound
+	case resp.StatusCode >= 300:
+		return newStatusError("caldav DELETE", resp)
+	}
+
+	c.mutex.Lock()
+	delete(c.resources, id)
+	c.mutex.Unlock()
+	return nil
+}
+
+// resource returns where an event lives, assuming <uid>.ics for events that
+// weren't seen in the last fetch
+func (c *CalDAVProvider) resource(id string) calDAVResource {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	resource, known := c.resources[id]
+	if !known {
+		return calDAVResource{href: c.collection.JoinPath(url.PathEscape(id) + ".ics").String()}
+	}
+	// Hrefs from the server are usually absolute paths
+	if ref, err := url.Parse(resource.href); err == nil {
+		resource.href = c.collection.ResolveReference(ref).String()
+	}
+	return resource
+}
+
+// put writes an event and returns its new ETag, if the server sent one
+func (c *CalDAVProvider) put(href string, event models.Event, headers map[string]string) (string, error) {
+	var body bytes.Buffer
+	if err := ical.EncodeEvents(&body, []models.Event{event}); err != nil {
+		return "", err
+	}
+
+	headers["Content-Type"] = "text/calendar; charset=utf-8"
+	resp, err := c.do("PUT", href, &body, headers)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	precondition := resp.StatusCode == http.StatusPreconditionFailed
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		return "", ErrEventNotFound
+	case precondition && headers["If-None-Match"] == "*":
+		return "", ErrEventExists
+	case precondition && headers["If-Match"] == "*":
+		return "", ErrEventNotFound
+	case precondition && headers["If-Match"] != "":
+		return "", ErrEventConflict
+	case resp.StatusCode >= 300:
+		return "", newStatusError("caldav PUT", resp)
+	}
+	return resp.Header.Get("ETag"), nil
+}
+
+func (c *CalDAVProvider) do(method, target string, body io.Reader, headers map[string]string) (*http.Response, error) {
+	req, err := http.NewRequest(method, target, body)
+	if err != nil {
+		return nil, err
+	}
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
+	if c.options.Username != "" {
+		req.SetBasicAuth(c.options.Username, c.options.Password.Reveal())
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("caldav %s: %w", method, err)
+	}
+	return resp, nil
+}
+
+// StatusError is an unexpected HTTP response from a remote provider
+type StatusError struct {
+	Op     string
+	Status string
+	Code   int
+
+	retryAfter time.Duration
+}
+
+func newStatusError(op string, resp *http.Response) *StatusError {
+	err := &StatusError{Op: op, Status: resp.Status, Code: resp.StatusCode}
+	if resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode == http.StatusServiceUnavailable {
+		// Retry-After is either a number of seconds or an HTTP date
+		value := resp.Header.Get("Retry-After")
+		if seconds, parseErr := strconv.Atoi(value); parseErr == nil && seconds > 0 {
+			err.retryAfter = time.Duration(seconds) * time.Second
+		} else if when, parseErr := http.ParseTime(value); parseErr == nil && time.Until(when) > 0 {
+			err.retryAfter = time.Until(when).Round(time.Second)
+		}
+	}
+	return err
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("%s: unexpected status %s", e.Op, e.Status)
+}
+
+// RetryAfter implements models.RetryAfterError. It is zero unless the server
+// asked clients to back off.
+func (e *StatusError) RetryAfter() time.Duration {
+	return e.retryAfter
+}
@@ -0,0 +1,188 @@
+package providers
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"flexpane/internal/models"
+	"flexpane/internal/secrets"
+)
+
+// fakeCalDAV is an in-memory calendar collection at /cal/
+type fakeCalDAV struct {
+	mutex     sync.Mutex
+	resources map[string]string // Path to iCalendar body
+	versions  map[string]int    // Path to the version its ETag names
+}
+
+func (f *fakeCalDAV) etag(path string) string {
+	return `"` + strconv.Itoa(f.versions[path]) + `"`
+}
+
+func (f *fakeCalDAV) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	if user, pass, ok := r.BasicAuth(); !ok || user != "me" || pass != "caldav-pass" {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	switch r.Method {
+	case "REPORT":
+		w.WriteHeader(http.StatusMultiStatus)
+		io.WriteString(w, `<?xml version="1.0"?><d:multistatus xmlns:d="DAV:" xmlns:c="urn:ietf:params:xml:ns:caldav">`)
+		for path, body := range f.resources {
+			io.WriteString(w, `<d:response><d:href>`+path+`</d:href><d:propstat><d:prop><d:getetag>`+f.etag(path)+`</d:getetag><c:calendar-data>`)
+			io.WriteString(w, strings.NewReplacer("&", "&amp;", "<", "&lt;").Replace(body))
+			io.WriteString(w, `</c:calendar-data></d:prop><d:status>HTTP/1.1 200 OK</d:status></d:propstat></d:response>`)
+		}
+		io.WriteString(w, `</d:multistatus>`)
+
+	case "PUT":
+		_, exists := f.resources[r.URL.Path]
+		match := r.Header.Get("If-Match")
+		if r.Header.Get("If-None-Match") == "*" && exists ||
+			match == "*" && !exists ||
+			match != "" && match != "*" && match != f.etag(r.URL.Path) {
+			w.WriteHeader(http.StatusPreconditionFailed)
+			return
+		}
+		body, _ := io.ReadAll(r.Body)
+		f.resources[r.URL.Path] = string(body)
+		if f.versions == nil {
+			f.versions = map[string]int{}
+		}
+		f.versions[r.URL.Path]++
+		w.Header().Set("ETag", f.etag(r.URL.Path))
+		w.WriteHeader(http.StatusCreated)
+
+	case "DELETE":
+		if _, exists := f.resources[r.URL.Path]; !exists {
+			http.NotFound(w, r)
+			return
+		}
+		delete(f.resources, r.URL.Path)
+		w.WriteHeader(http.StatusNoContent)
+
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+func TestCalDAVProvider_CRUD(t *testing.T) {
+	server := httptest.NewServer(&fakeCalDAV{resources: map[string]string{}})
+	defer server.Close()
+
+	provider, err := NewCalDAVProvider(CalDAVOptions{
+		URL:      server.URL + "/cal",
+		Username: "me",
+		Password: secrets.NewValue("caldav-pass"),
+	})
+	if err != nil {
+		t.Fatalf("NewCalDAVProvider failed: %v", err)
+	}
+
+	start := time.Now().Truncate(time.Hour).Add(time.Hour)
+	created, err := provider.CreateEvent(models.Event{Title: "Sync & plan", Start: start, End: start.Add(time.Hour)})
+	if err != nil {
+		t.Fatalf("CreateEvent failed: %v", err)
+	}
+
+	events, err := provider.GetCalendarEvents()
+	if err != nil {
+		t.Fatalf("GetCalendarEvents failed: %v", err)
+	}
+	if len(events) != 1 || events[0].ID != created.ID || events[0].Title != "Sync & plan" {
+		t.Fatalf("Unexpected events: %+v", events)
+	}
+
+	created.Title = "Sync"
+	if _, err := provider.UpdateEvent(created); err != nil {
+		t.Fatalf("UpdateEvent failed: %v", err)
+	}
+	events, _ = provider.GetCalendarEvents()
+	if len(events) != 1 || events[0].Title != "Sync" {
+		t.Fatalf("Expected updated event, got %+v", events)
+	}
+
+	if err := provider.DeleteEvent(created.ID); err != nil {
+		t.Fatalf("DeleteEvent failed: %v", err)
+	}
+	events, _ = provider.GetCalendarEvents()
+	if len(events) != 0 {
+		t.Errorf("Expected no events after delete, got %+v", events)
+	}
+}
+
+func TestCalDAVProvider_UpdateChecksETag(t *testing.T) {
+	fake := &fakeCalDAV{resources: map[string]string{}}
+	server := httptest.NewServer(fake)
+	defer server.Close()
+
+	provider, err := NewCalDAVProvider(CalDAVOptions{
+		URL:      server.URL + "/cal",
+		Username: "me",
+		Password: secrets.NewValue("caldav-pass"),
+	})
+	if err != nil {
+		t.Fatalf("NewCalDAVProvider failed: %v", err)
+	}
+
+	// An ID the server doesn't have isn't created by an update
+	start := time.Now().Truncate(time.Hour).Add(time.Hour)
+	_, err = provider.UpdateEvent(models.Event{ID: "bogus", Title: "Ghost", Start: start, End: start.Add(time.Hour)})
+	if !errors.Is(err, ErrEventNotFound) {
+		t.Errorf("Expected ErrEventNotFound, got %v", err)
+	}
+	if len(fake.resources) != 0 {
+		t.Errorf("Expected nothing created, got %v", fake.resources)
+	}
+
+	// Edits in a row each use the ETag the last one returned
+	created, err := provider.CreateEvent(models.Event{Title: "Sync", Start: start, End: start.Add(time.Hour)})
+	if err != nil {
+		t.Fatalf("CreateEvent failed: %v", err)
+	}
+	for _, title := range []string{"Sync 2", "Sync 3"} {
+		created.Title = title
+		if _, err := provider.UpdateEvent(created); err != nil {
+			t.Fatalf("UpdateEvent %q failed: %v", title, err)
+		}
+	}
+
+	// A change made elsewhere is a conflict, not an overwrite
+	fake.mutex.Lock()
+	fake.versions["/cal/"+created.ID+".ics"]++
+	fake.mutex.Unlock()
+	created.Title = "Sync 4"
+	if _, err := provider.UpdateEvent(created); !errors.Is(err, ErrEventConflict) {
+		t.Errorf("Expected ErrEventConflict, got %v", err)
+	}
+}
+
+func TestCalDAVProvider_RateLimited(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Retry-After", "120")
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer server.Close()
+
+	provider, err := NewCalDAVProvider(CalDAVOptions{URL: server.URL + "/cal"})
+	if err != nil {
+		t.Fatalf("NewCalDAVProvider failed: %v", err)
+	}
+
+	_, err = provider.GetCalendarEvents()
+	var retry models.RetryAfterError
+	if !errors.As(err, &retry) || retry.RetryAfter() != 2*time.Minute {
+		t.Errorf("Expected a two minute retry-after, got %v", err)
+	}
+}
@@ -1,18 +1,10 @@
 package providers
 
-import (
-	"fmt"
-)
-
-// CreateProvider creates a data provider based on the specified provider type
-// This replaces the verbose factory pattern with a simple switch statement
+// CreateProvider creates a data provider of the given registered type using
+// its default settings. An empty type defaults to mock.
 func CreateProvider(providerType string) (DataProvider, error) {
-	switch providerType {
-	case "mock":
-		return NewMockProvider(), nil
-	case "": // Default to mock if empty
-		return NewMockProvider(), nil
-	default:
-		return nil, fmt.Errorf("unsupported provider type: %s", providerType)
+	if providerType == "" {
+		providerType = "mock"
 	}
+	return NewProvider(providerType, nil)
 }
@@ -0,0 +1,463 @@
+package providers
+
+import (
+	"bufio"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxIMAPLiteral bounds a single literal so a misbehaving server can't
+// exhaust memory
+const maxIMAPLiteral = 32 << 20
+
+// imapClient speaks just enough IMAP4rev1 (RFC 3501) for the email pane:
+// login, select, search, fetch, store, move and append. Responses are parsed
+// into nested []interface{} values where atoms, quoted strings and literals
+// are strings and parenthesized lists are slices.
+type imapClient struct {
+	conn    net.Conn
+	r       *bufio.Reader
+	tag     int
+	timeout time.Duration
+	caps    map[string]bool
+	eol     bool // Set when the parser consumed the end of the current line
+}
+
+// imapError is a NO or BAD completion
+type imapError struct {
+	Status string
+	Text   string
+}
+
+func (e *imapError) Error() string {
+	return fmt.Sprintf("imap: %s %s", e.Status, e.Text)
+}
+
+func dialIMAP(options IMAPOptions) (*imapClient, error) {
+	host, _, err := net.SplitHostPort(options.Addr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid IMAP address: %w", err)
+	}
+	tlsConfig := &tls.Config{ServerName: host}
+	dialer := &net.Dialer{Timeout: options.Timeout}
+
+	var conn net.Conn
+	if options.TLS {
+		conn, err = tls.DialWithDialer(dialer, "tcp", options.Addr, tlsConfig)
+	} else {
+		conn, err = dialer.Dial("tcp", options.Addr)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	c := &imapClient{conn: conn, r: bufio.NewReader(conn), timeout: options.Timeout}
+	c.conn.SetDeadline(time.Now().Add(c.timeout))
+	if _, err := c.readLine(); err != nil { // Greeting
+		conn.Close()
+		return nil, err
+	}
+
+	if options.StartTLS && !options.TLS {
+		if _, err := c.command("STARTTLS"); err != nil {
+			conn.Close()
+			return nil, err
+		}
+		tlsConn := tls.Client(conn, tlsConfig)
+		if err := tlsConn.Handshake(); err != nil {
+			conn.Close()
+			return nil, err
+		}
+		c.conn = tlsConn
+		c.r = bufio.NewReader(tlsConn)
+	}
+
+	if err := c.capability(); err != nil {
+		c.conn.Close()
+		return nil, err
+	}
+	return c, nil
+}
+
+func (c *imapClient) close() {
+	c.command("LOGOUT")
+	c.conn.Close()
+}
+
+func (c *imapClient) capability() error {
+	responses, err := c.command("CAPABILITY")
+	if err != nil {
+		return err
+	}
+	c.caps = map[string]bool{}
+	for _, fields := range responses {
+		if len(fields) > 0 && strings.EqualFold(atom(fields[0]), "CAPABILITY") {
+			for _, field := range fields[1:] {
+				c.caps[strings.ToUpper(atom(field))] = true
+			}
+		}
+	}
+	return nil
+}
+
+func (c *imapClient) login(username, password string) error {
+	_, err := c.command("LOGIN " + imapQuote(username) + " " + imapQuote(password))
+	return err
+}
+
+func (c *imapClient) selectMailbox(mailbox string) error {
+	_, err := c.command("SELECT " + imapQuote(mailbox))
+	return err
+}
+
+// uidSearch returns the UIDs matching criteria in ascending order
+func (c *imapClient) uidSearch(criteria string) ([]uint32, error) {
+	responses, err := c.command("UID SEARCH " + criteria)
+	if err != nil {
+		return nil, err
+	}
+	var uids []uint32
+	for _, fields := range responses {
+		if len(fields) == 0 || !strings.EqualFold(atom(fields[0]), "SEARCH") {
+			continue
+		}
+		for _, field := range fields[1:] {
+			if uid, err := strconv.ParseUint(atom(field), 10, 32); err == nil {
+				uids = append(uids, uint32(uid))
+			}
+		}
+	}
+	return uids, nil
+}
+
+// uidFetch returns one map of data items per message, keyed by the
+// uppercased item name as the server echoed it (e.g. "BODY[HEADER]")
+func (c *imapClient) uidFetch(set, items string) ([]map[string]interface{}, error) {
+	responses, err := c.command("UID FETCH " + set + " " + items)
+	if err != nil {
+		return nil, err
+	}
+	return fetchResults(responses), nil
+}
+
+// uidStore changes flags and returns the messages that were updated
+func (c *imapClient) uidStore(uid uint32, change, flags string) ([]map[string]interface{}, error) {
+	responses, err := c.command(fmt.Sprintf("UID STORE %d %s (%s)", uid, change, flags))
+	if err != nil {
+		return nil, err
+	}
+	return fetchResults(responses), nil
+}
+
+// uidMove moves a message, falling back to copy, delete and expunge on
+// servers without the MOVE extension
+func (c *imapClient) uidMove(uid uint32, mailbox string) error {
+	if c.caps["MOVE"] {
+		_, err := c.command(fmt.Sprintf("UID MOVE %d %s", uid, imapQuote(mailbox)))
+		return err
+	}
+	if _, err := c.command(fmt.Sprintf("UID COPY %d %s", uid, imapQuote(mailbox))); err != nil {
+		return err
+	}
+	return c.uidExpunge(uid)
+}
+
+// uidExpunge permanently removes one message
+func (c *imapClient) uidExpunge(uid uint32) error {
+	if _, err := c.uidStore(uid, "+FLAGS.SILENT", `\Deleted`); err != nil {
+		return err
+	}
+	if c.caps["UIDPLUS"] {
+		_, err := c.command(fmt.Sprintf("UID EXPUNGE %d", uid))
+		return err
+	}
+	_, err := c.command("EXPUNGE")
+	return err
+}
+
+// appendMessage stores a raw message in a mailbox with the given flags
+func (c *imapClient) appendMessage(mailbox, flags string, message []byte) error {
+	c.tag++
+	tag := fmt.Sprintf("a%03d", c.tag)
+	c.conn.SetDeadline(time.Now().Add(c.timeout))
+
+	if _, err := fmt.Fprintf(c.conn, "%s APPEND %s (%s) {%d}\r\n", tag, imapQuote(mailbox), flags, len(message)); err != nil {
+		return err
+	}
+	line, err := c.readLine()
+	if err != nil {
+		return err
+	}
+	if !strings.HasPrefix(line, "+") {
+		return c.completion(tag, line)
+	}
+	if _, err := c.conn.Write(message); err != nil {
+		return err
+	}
+	if _, err := io.WriteString(c.conn, "\r\n"); err != nil {
+		return err
+	}
+	_, err = c.readResponses(tag)
+	return err
+}
+
+// command sends one command and collects the untagged responses until its
+// tagged completion
+func (c *imapClient) command(cmd string) ([][]interface{}, error) {
+	c.tag++
+	tag := fmt.Sprintf("a%03d", c.tag)
+	c.conn.SetDeadline(time.Now().Add(c.timeout))
+
+	if _, err := io.WriteString(c.conn, tag+" "+cmd+"\r\n"); err != nil {
+		return nil, err
+	}
+	return c.readResponses(tag)
+}
+
+func (c *imapClient) readResponses(tag string) ([][]interface{}, error) {
+	var responses [][]interface{}
+	for {
+		prefix, err := c.readAtom()
+		if err != nil {
+			return nil, err
+		}
+
+		if prefix == "*" {
+			fields, err := c.readFields()
+			if err != nil {
+				return nil, err
+			}
+			responses = append(responses, fields)
+			continue
+		}
+
+		line, err := c.readLine()
+		if err != nil {
+			return nil, err
+		}
+		if prefix == tag {
+			return responses, c.completion(tag, tag+" "+line)
+		}
+		// Continuation requests and unrelated tags are ignored
+	}
+}
+
+// completion turns a tagged status line into an error unless it is OK
+func (c *imapClient) completion(tag, line string) error {
+	status, text, _ := strings.Cut(strings.TrimSpace(strings.TrimPrefix(line, tag)), " ")
+	if strings.EqualFold(status, "OK") {
+		return nil
+	}
+	return &imapError{Status: strings.ToUpper(status), Text: text}
+}
+
+func (c *imapClient) readLine() (string, error) {
+	line, err := c.r.ReadString('\n')
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
+
+// readAtom reads the first token of a response line and the space after
+// it, leaving a line ending for readLine
+func (c *imapClient) readAtom() (string, error) {
+	var b strings.Builder
+	for {
+		ch, err := c.r.ReadByte()
+		if err != nil {
+			return "", err
+		}
+		switch ch {
+		case ' ':
+			return b.String(), nil
+		case '\r', '\n':
+			c.r.UnreadByte()
+			return b.String(), nil
+		}
+		b.WriteByte(ch)
+	}
+}
+
+// readFields parses values up to the end of the line
+func (c *imapClient) readFields() ([]interface{}, error) {
+	c.eol = false
+	var fields []interface{}
+	for !c.eol {
+		value, err := c.readValue()
+		if err == errCloseList {
+			continue // Stray parenthesis in status text
+		}
+		if err != nil {
+			return nil, err
+		}
+		if value != nil {
+			fields = append(fields, value)
+		}
+	}
+	return fields, nil
+}
+
+// readValue parses one value. It returns nil after consuming whitespace, a
+// closing parenthesis or the end of the line; c.eol reports the latter.
+func (c *imapClient) readValue() (interface{}, error) {
+	b, err := c.r.ReadByte()
+	if err != nil {
+		return nil, err
+	}
+
+	switch b {
+	case ' ':
+		return nil, nil
+	case '\r':
+		return nil, nil
+	case '\n':
+		c.eol = true
+		return nil, nil
+	case ')':
+		return nil, errCloseList
+	case '(':
+		list := []interface{}{}
+		for !c.eol {
+			value, err := c.readValue()
+			if err == errCloseList {
+				return list, nil
+			}
+			if err != nil {
+				return nil, err
+			}
+			if value != nil {
+				list = append(list, value)
+			}
+		}
+		return list, nil // Unterminated list ends with the line
+	case '"':
+		return c.readQuoted()
+	case '{':
+		return c.readLiteral()
+	}
+
+	c.r.UnreadByte()
+	return c.readBareAtom()
+}
+
+// errCloseList signals the end of a parenthesized list to readValue
+var errCloseList = errors.New("imap: close list")
+
+func (c *imapClient) readQuoted() (string, error) {
+	var b strings.Builder
+	for {
+		ch, err := c.r.ReadByte()
+		if err != nil {
+			return "", err
+		}
+		switch ch {
+		case '"':
+			return b.String(), nil
+		case '\\':
+			ch, err = c.r.ReadByte()
+			if err != nil {
+				return "", err
+			}
+		case '\r', '\n':
+			return "", errors.New("imap: unterminated quoted string")
+		}
+		b.WriteByte(ch)
+	}
+}
+
+func (c *imapClient) readLiteral() (string, error) {
+	header, err := c.r.ReadString('}')
+	if err != nil {
+		return "", err
+	}
+	size, err := strconv.Atoi(strings.TrimSuffix(strings.TrimSuffix(header, "}"), "+"))
+	if err != nil || size < 0 || size > maxIMAPLiteral {
+		return "", fmt.Errorf("imap: invalid literal size %q", header)
+	}
+	if _, err := c.readLine(); err != nil {
+		return "", err
+	}
+
+	data := make([]byte, size)
+	if _, err := io.ReadFull(c.r, data); err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// readBareAtom reads an atom, keeping bracketed sections such as
+// BODY[HEADER.FIELDS (SUBJECT)] or [UIDVALIDITY 1] together
+func (c *imapClient) readBareAtom() (string, error) {
+	var b strings.Builder
+	depth := 0
+	for {
+		ch, err := c.r.ReadByte()
+		if err != nil {
+			return "", err
+		}
+		if ch == '\r' || ch == '\n' || (depth == 0 && (ch == ' ' || ch == '(' || ch == ')')) {
+			c.r.UnreadByte()
+			return b.String(), nil
+		}
+		switch ch {
+		case '[':
+			depth++
+		case ']':
+			if depth > 0 {
+				depth--
+			}
+		}
+		b.WriteByte(ch)
+	}
+}
+
+// fetchResults extracts the data items of FETCH responses
+func fetchResults(responses [][]interface{}) []map[string]interface{} {
+	var results []map[string]interface{}
+	for _, fields := range responses {
+		if len(fields) < 3 || !strings.EqualFold(atom(fields[1]), "FETCH") {
+			continue
+		}
+		items, ok := fields[2].([]interface{})
+		if !ok {
+			continue
+		}
+		result := map[string]interface{}{}
+		for i := 0; i+1 < len(items); i += 2 {
+			result[strings.ToUpper(atom(items[i]))] = items[i+1]
+		}
+		results = append(results, result)
+	}
+	return results
+}
+
+// fetchItem returns the first item whose name starts with prefix, so
+// "BODY[TEXT]" matches a server's "BODY[TEXT]<0>"
+func fetchItem(result map[string]interface{}, prefix string) interface{} {
+	if value, exists := result[prefix]; exists {
+		return value
+	}
+	for key, value := range result {
+		if strings.HasPrefix(key, prefix) {
+			return value
+		}
+	}
+	return nil
+}
+
+func atom(value interface{}) string {
+	s, _ := value.(string)
+	return s
+}
+
+// imapQuote renders a quoted string
+func imapQuote(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s) + `"`
+}
@@ -0,0 +1,354 @@
+package providers
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"flexpane/internal/mail"
+	"flexpane/internal/models"
+	"flexpane/internal/secrets"
+)
+
+func init() {
+	Register("imap", Registration{
+		New: func(settings Settings) (DataProvider, error) {
+			return NewIMAPProvider(IMAPOptions{
+				Addr:     settings.String("addr"),
+				Username: settings.String("username"),
+				Password: settings.Secret("password"),
+				TLS:      settings.Bool("tls"),
+				StartTLS: settings.Bool("starttls"),
+				Mailbox:  settings.String("mailbox"),
+				Archive:  settings.String("archive"),
+				Trash:    settings.String("trash"),
+				Sent:     settings.String("sent"),
+				Expunge:  settings.Bool("expunge"),
+				Limit:    settings.Int("limit"),
+				Timeout:  settings.Duration("timeout"),
+			})
+		},
+		Schema: ConfigSchema{
+			"addr":     {Type: "string", Required: true, Description: "Server host:port, e.g. imap.example.com:993"},
+			"username": {Type: "string", Required: true, Description: "Login name"},
+			"password": {Type: "secret", Required: true, Description: "Login password, usually secret://name"},
+			"tls":      {Type: "bool", Default: true, Description: "Connect with implicit TLS (port 993)"},
+			"starttls": {Type: "bool", Description: "Upgrade a plain connection with STARTTLS when tls is false"},
+			"mailbox":  {Type: "string", Default: "INBOX", Description: "Mailbox listed in the email pane"},
+			"archive":  {Type: "string", Default: "Archive", Description: "Mailbox archived messages move to"},
+			"trash":    {Type: "string", Default: "Trash", Description: "Mailbox deleted messages move to"},
+			"sent":     {Type: "string", Default: "Sent", Description: "Mailbox copies of sent messages are stored in"},
+			"expunge":  {Type: "bool", Description: "Delete messages permanently instead of moving them to trash"},
+			"limit":    {Type: "number", Default: 50, Description: "Maximum number of messages listed"},
+			"timeout":  {Type: "duration", Default: "30s", Description: "Network timeout per command"},
+		},
+	})
+}
+
+// IMAPOptions configures an IMAP mailbox
+type IMAPOptions struct {
+	Addr     string
+	Username string
+	Password secrets.Value
+	TLS      bool
+	StartTLS bool
+	Mailbox  string
+	Archive  string
+	Trash    string
+	Sent     string
+	Expunge  bool
+	Limit    int
+	Timeout  time.Duration
+}
+
+// imapPreviewBytes is how much of each body is fetched for list previews
+const imapPreviewBytes = 2048
+
+// IMAPProvider reads and manages one IMAP mailbox. Each call opens its own
+// connection, so there is no long-lived session to keep alive. Message IDs
+// are UIDs. It has no calendar, so GetCalendarEvents always returns an
+// empty list.
+type IMAPProvider struct {
+	options IMAPOptions
+}
+
+func NewIMAPProvider(options IMAPOptions) (*IMAPProvider, error) {
+	if options.Addr == "" {
+		return nil, errors.New("imap addr is required")
+	}
+	if options.Mailbox == "" {
+		options.Mailbox = "INBOX"
+	}
+	if options.Archive == "" {
+		options.Archive = "Archive"
+	}
+	if options.Trash == "" {
+		options.Trash = "Trash"
+	}
+	if options.Sent == "" {
+		options.Sent = "Sent"
+	}
+	if options.Limit <= 0 {
+		options.Limit = 50
+	}
+	if options.Timeout <= 0 {
+		options.Timeout = 30 * time.Second
+	}
+	return &IMAPProvider{options: options}, nil
+}
+
+func (p *IMAPProvider) GetCalendarEvents() ([]models.Event, error) {
+	return []models.Event{}, nil
+}
+
+func (p *IMAPProvider) GetEmails() ([]models.Email, error) {
+	emails := []models.Email{}
+	err := p.session(func(c *imapClient) error {
+		uids, err := c.uidSearch("NOT DELETED")
+		if err != nil {
+			return err
+		}
+		if len(uids) == 0 {
+			return nil
+		}
+		if len(uids) > p.options.Limit {
+			uids = uids[len(uids)-p.options.Limit:]
+		}
+
+		results, err := c.uidFetch(uidSet(uids), imapSummaryItems)
+		if err != nil {
+			return err
+		}
+
+		for _, result := range results {
+			if email, ok := imapEmail(result); ok {
+				emails = append(emails, email)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(emails, func(i, j int) bool {
+		return emails[i].Time.After(emails[j].Time)
+	})
+	return emails, nil
+}
+
+// GetEmail implements EmailGetter
+func (p *IMAPProvider) GetEmail(id string) (models.Email, error) {
+	uid, err := parseUID(id)
+	if err != nil {
+		return models.Email{}, err
+	}
+
+	var email models.Email
+	err = p.session(func(c *imapClient) error {
+		results, err := c.uidFetch(strconv.FormatUint(uint64(uid), 10), imapSummaryItems)
+		if err != nil {
+			return err
+		}
+		for _, result := range results {
+			flags, _ := result["FLAGS"].([]interface{})
+			if hasFlag(flags, `\Deleted`) {
+				return ErrEmailNotFound
+			}
+			if found, ok := imapEmail(result); ok {
+				email = found
+				return nil
+			}
+		}
+		return ErrEmailNotFound
+	})
+	return email, err
+}
+
+// imapSummaryItems fetches what a listing needs: flags, headers and the start
+// of the body for the preview
+var imapSummaryItems = fmt.Sprintf("(UID FLAGS INTERNALDATE BODY.PEEK[HEADER] BODY.PEEK[TEXT]<0.%d>)", imapPreviewBytes)
+
+// imapEmail builds a listing entry from a fetch of imapSummaryItems
+func imapEmail(result map[string]interface{}) (models.Email, bool) {
+	raw := atom(fetchItem(result, "BODY[HEADER]")) + atom(fetchItem(result, "BODY[TEXT]"))
+	msg, err := mail.Summarize([]byte(raw))
+	if err != nil {
+		return models.Email{}, false
+	}
+
+	flags, _ := result["FLAGS"].([]interface{})
+	email := models.Email{
+		ID:         atom(result["UID"]),
+		Subject:    msg.Subject,
+		From:       msg.From,
+		Preview:    msg.Snippet(100),
+		Time:       msg.Date,
+		Read:       hasFlag(flags, `\Seen`),
+		Flagged:    hasFlag(flags, `\Flagged`),
+		MessageID:  msg.MessageID,
+		InReplyTo:  msg.InReplyTo,
+		References: msg.References,
+	}
+	if email.Time.IsZero() {
+		email.Time, _ = time.Parse("02-Jan-2006 15:04:05 -0700", strings.TrimSpace(atom(result["INTERNALDATE"])))
+	}
+	return email, true
+}
+
+// ReadMessage implements MessageReader
+func (p *IMAPProvider) ReadMessage(id string) ([]byte, error) {
+	uid, err := parseUID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	var raw []byte
+	err = p.session(func(c *imapClient) error {
+		results, err := c.uidFetch(strconv.FormatUint(uint64(uid), 10), "(UID BODY.PEEK[])")
+		if err != nil {
+			return err
+		}
+		for _, result := range results {
+			if body, ok := fetchItem(result, "BODY[]").(string); ok {
+				raw = []byte(body)
+				return nil
+			}
+		}
+		return ErrEmailNotFound
+	})
+	return raw, err
+}
+
+// SetRead implements EmailWriter
+func (p *IMAPProvider) SetRead(id string, read bool) error {
+	return p.setFlag(id, `\Seen`, read)
+}
+
+// SetFlagged implements EmailWriter
+func (p *IMAPProvider) SetFlagged(id string, flagged bool) error {
+	return p.setFlag(id, `\Flagged`, flagged)
+}
+
+// Archive implements EmailWriter
+func (p *IMAPProvider) Archive(id string) error {
+	return p.move(id, p.options.Archive)
+}
+
+// DeleteEmail implements EmailWriter
+func (p *IMAPProvider) DeleteEmail(id string) error {
+	if !p.options.Expunge {
+		return p.move(id, p.options.Trash)
+	}
+
+	uid, err := parseUID(id)
+	if err != nil {
+		return err
+	}
+	return p.session(func(c *imapClient) error {
+		if err := p.exists(c, uid); err != nil {
+			return err
+		}
+		return c.uidExpunge(uid)
+	})
+}
+
+// SaveSent implements SentSaver
+func (p *IMAPProvider) SaveSent(message []byte) error {
+	return p.session(func(c *imapClient) error {
+		return c.appendMessage(p.options.Sent, `\Seen`, message)
+	})
+}
+
+func (p *IMAPProvider) setFlag(id, flag string, on bool) error {
+	uid, err := parseUID(id)
+	if err != nil {
+		return err
+	}
+
+	change := "-FLAGS"
+	if on {
+		change = "+FLAGS"
+	}
+	return p.session(func(c *imapClient) error {
+		// Without .SILENT the server answers with the updated message, which
+		// tells us whether the UID exists
+		results, err := c.uidStore(uid, change, flag)
+		if err != nil {
+			return err
+		}
+		if len(results) == 0 {
+			return ErrEmailNotFound
+		}
+		return nil
+	})
+}
+
+func (p *IMAPProvider) move(id, mailbox string) error {
+	uid, err := parseUID(id)
+	if err != nil {
+		return err
+	}
+	return p.session(func(c *imapClient) error {
+		if err := p.exists(c, uid); err != nil {
+			return err
+		}
+		return c.uidMove(uid, mailbox)
+	})
+}
+
+func (p *IMAPProvider) exists(c *imapClient, uid uint32) error {
+	results, err := c.uidFetch(strconv.FormatUint(uint64(uid), 10), "(UID)")
+	if err != nil {
+		return err
+	}
+	if len(results) == 0 {
+		return ErrEmailNotFound
+	}
+	return nil
+}
+
+// session connects, logs in and selects the mailbox around fn
+func (p *IMAPProvider) session(fn func(c *imapClient) error) error {
+	c, err := dialIMAP(p.options)
+	if err != nil {
+		return fmt.Errorf("imap connect: %w", err)
+	}
+	defer c.close()
+
+	if err := c.login(p.options.Username, p.options.Password.Reveal()); err != nil {
+		return fmt.Errorf("imap login: %w", err)
+	}
+	if err := c.selectMailbox(p.options.Mailbox); err != nil {
+		return fmt.Errorf("imap select %s: %w", p.options.Mailbox, err)
+	}
+	return fn(c)
+}
+
+func parseUID(id string) (uint32, error) {
+	uid, err := strconv.ParseUint(id, 10, 32)
+	if err != nil || uid == 0 {
+		return 0, ErrEmailNotFound
+	}
+	return uint32(uid), nil
+}
+
+func uidSet(uids []uint32) string {
+	parts := make([]string, len(uids))
+	for i, uid := range uids {
+		parts[i] = strconv.FormatUint(uint64(uid), 10)
+	}
+	return strings.Join(parts, ",")
+}
+
+func hasFlag(flags []interface{}, flag string) bool {
+	for _, f := range flags {
+		if strings.EqualFold(atom(f), flag) {
+			return true
+		}
+	}
+	return false
+}
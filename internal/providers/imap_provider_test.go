@@ -0,0 +1,393 @@
+package providers
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"flexpane/internal/secrets"
+)
+
+// fakeIMAPServer is an in-memory IMAP server covering the commands the
+// provider sends
+type fakeIMAPServer struct {
+	listener net.Listener
+	noMove   bool
+
+	mutex     sync.Mutex
+	mailboxes map[string][]*fakeIMAPMessage
+	nextUID   uint32
+}
+
+type fakeIMAPMessage struct {
+	uid   uint32
+	flags map[string]bool
+	raw   string
+}
+
+func newFakeIMAPServer(t *testing.T) *fakeIMAPServer {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &fakeIMAPServer{listener: listener, mailboxes: map[string][]*fakeIMAPMessage{}, nextUID: 1}
+	t.Cleanup(func() { listener.Close() })
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go s.serve(conn)
+		}
+	}()
+	return s
+}
+
+func (s *fakeIMAPServer) add(mailbox, raw string, flags ...string) uint32 {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	msg := &fakeIMAPMessage{uid: s.nextUID, flags: map[string]bool{}, raw: raw}
+	for _, flag := range flags {
+		msg.flags[flag] = true
+	}
+	s.nextUID++
+	s.mailboxes[mailbox] = append(s.mailboxes[mailbox], msg)
+	return msg.uid
+}
+
+func (s *fakeIMAPServer) find(mailbox string, uid uint32) (*fakeIMAPMessage, int) {
+	for i, msg := range s.mailboxes[mailbox] {
+		if msg.uid == uid {
+			return msg, i
+		}
+	}
+	return nil, -1
+}
+
+func (s *fakeIMAPServer) provider(t *testing.T, expunge bool) *IMAPProvider {
+	t.Helper()
+	provider, err := NewIMAPProvider(IMAPOptions{
+		Addr:     s.listener.Addr().String(),
+		Username: "me",
+		Password: secrets.NewValue("hunter2"),
+		Expunge:  expunge,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return provider
+}
+
+func (s *fakeIMAPServer) serve(conn net.Conn) {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	fmt.Fprint(conn, "* OK fake IMAP ready\r\n")
+
+	selected := ""
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		tag, cmd, _ := strings.Cut(strings.TrimRight(line, "\r\n"), " ")
+		fields := strings.Fields(cmd)
+		if len(fields) == 0 {
+			fmt.Fprintf(conn, "%s BAD empty command\r\n", tag)
+			continue
+		}
+
+		verb := strings.ToUpper(fields[0])
+		if verb == "UID" && len(fields) > 1 {
+			verb += " " + strings.ToUpper(fields[1])
+		}
+
+		s.mutex.Lock()
+		switch verb {
+		case "CAPABILITY":
+			if s.noMove {
+				fmt.Fprint(conn, "* CAPABILITY IMAP4rev1\r\n")
+			} else {
+				fmt.Fprint(conn, "* CAPABILITY IMAP4rev1 MOVE UIDPLUS\r\n")
+			}
+			fmt.Fprintf(conn, "%s OK done\r\n", tag)
+
+		case "LOGIN":
+			if cmd != `LOGIN "me" "hunter2"` {
+				fmt.Fprintf(conn, "%s NO [AUTHENTICATIONFAILED] bad credentials\r\n", tag)
+			} else {
+				fmt.Fprintf(conn, "%s OK logged in\r\n", tag)
+			}
+
+		case "SELECT":
+			selected = strings.Trim(fields[1], `"`)
+			fmt.Fprintf(conn, "* %d EXISTS\r\n%s OK [READ-WRITE] selected\r\n", len(s.mailboxes[selected]), tag)
+
+		case "UID SEARCH":
+			var uids []string
+			for _, msg := range s.mailboxes[selected] {
+				if !msg.flags[`\Deleted`] {
+					uids = append(uids, strconv.Itoa(int(msg.uid)))
+				}
+			}
+			fmt.Fprintf(conn, "* SEARCH %s\r\n%s OK done\r\n", strings.Join(uids, " "), tag)
+
+		case "UID FETCH":
+			items := strings.Join(fields[3:], " ")
+			for _, set := range strings.Split(fields[2], ",") {
+				uid, _ := strconv.Atoi(set)
+				if msg, i := s.find(selected, uint32(uid)); msg != nil {
+					fmt.Fprintf(conn, "* %d FETCH (%s)\r\n", i+1, fakeFetchItems(msg, items))
+				}
+			}
+			fmt.Fprintf(conn, "%s OK done\r\n", tag)
+
+		case "UID STORE":
+			uid, _ := strconv.Atoi(fields[2])
+			flags := strings.Fields(strings.Trim(strings.Join(fields[4:], " "), "()"))
+			if msg, i := s.find(selected, uint32(uid)); msg != nil {
+				for _, flag := range flags {
+					msg.flags[flag] = strings.HasPrefix(fields[3], "+")
+				}
+				if !strings.HasSuffix(strings.ToUpper(fields[3]), ".SILENT") {
+					fmt.Fprintf(conn, "* %d FETCH (UID %d FLAGS (%s))\r\n", i+1, msg.uid, fakeFlags(msg))
+				}
+			}
+			fmt.Fprintf(conn, "%s OK done\r\n", tag)
+
+		case "UID MOVE", "UID COPY":
+			if verb == "UID MOVE" && s.noMove {
+				fmt.Fprintf(conn, "%s BAD unknown command\r\n", tag)
+				break
+			}
+			uid, _ := strconv.Atoi(fields[2])
+			target := strings.Trim(fields[3], `"`)
+			if msg, i := s.find(selected, uint32(uid)); msg != nil {
+				moved := &fakeIMAPMessage{uid: s.nextUID, flags: map[string]bool{}, raw: msg.raw}
+				for flag := range msg.flags {
+					moved.flags[flag] = true
+				}
+				s.nextUID++
+				s.mailboxes[target] = append(s.mailboxes[target], moved)
+				if verb == "UID MOVE" {
+					s.mailboxes[selected] = append(s.mailboxes[selected][:i], s.mailboxes[selected][i+1:]...)
+				}
+			}
+			fmt.Fprintf(conn, "%s OK done\r\n", tag)
+
+		case "UID EXPUNGE", "EXPUNGE":
+			kept := s.mailboxes[selected][:0]
+			for _, msg := range s.mailboxes[selected] {
+				if !msg.flags[`\Deleted`] {
+					kept = append(kept, msg)
+				}
+			}
+			s.mailboxes[selected] = kept
+			fmt.Fprintf(conn, "%s OK done\r\n", tag)
+
+		case "APPEND":
+			// APPEND "mailbox" (flags) {size}
+			mailbox := strings.Trim(fields[1], `"`)
+			open, close := strings.Index(cmd, "("), strings.Index(cmd, ")")
+			size, _ := strconv.Atoi(strings.Trim(fields[len(fields)-1], "{}"))
+			fmt.Fprint(conn, "+ go ahead\r\n")
+			data := make([]byte, size)
+			s.mutex.Unlock()
+			_, err := io.ReadFull(r, data)
+			r.ReadString('\n')
+			s.mutex.Lock()
+			if err == nil {
+				msg := &fakeIMAPMessage{uid: s.nextUID, flags: map[string]bool{}, raw: string(data)}
+				for _, flag := range strings.Fields(cmd[open+1 : close]) {
+					msg.flags[flag] = true
+				}
+				s.nextUID++
+				s.mailboxes[mailbox] = append(s.mailboxes[mailbox], msg)
+			}
+			fmt.Fprintf(conn, "%s OK appended\r\n", tag)
+
+		case "LOGOUT":
+			s.mutex.Unlock()
+			fmt.Fprintf(conn, "* BYE\r\n%s OK bye\r\n", tag)
+			return
+
+		default:
+			fmt.Fprintf(conn, "%s BAD unknown command\r\n", tag)
+		}
+		s.mutex.Unlock()
+	}
+}
+
+func fakeFlags(msg *fakeIMAPMessage) string {
+	var flags []string
+	for flag, set := range msg.flags {
+		if set {
+			flags = append(flags, flag)
+		}
+	}
+	sort.Strings(flags)
+	return strings.Join(flags, " ")
+}
+
+func fakeFetchItems(msg *fakeIMAPMessage, items string) string {
+	parts := []string{fmt.Sprintf("UID %d", msg.uid)}
+	if strings.Contains(items, "FLAGS") {
+		parts = append(parts, "FLAGS ("+fakeFlags(msg)+")")
+	}
+	if strings.Contains(items, "INTERNALDATE") {
+		parts = append(parts, `INTERNALDATE "02-Jan-2006 15:04:05 +0000"`)
+	}
+
+	header, text, _ := strings.Cut(msg.raw, "\r\n\r\n")
+	literal := func(name, value string) string {
+		return fmt.Sprintf("%s {%d}\r\n%s", name, len(value), value)
+	}
+	if strings.Contains(items, "BODY.PEEK[HEADER]") {
+		parts = append(parts, literal("BODY[HEADER]", header+"\r\n\r\n"))
+	}
+	if strings.Contains(items, "BODY.PEEK[TEXT]") {
+		parts = append(parts, literal("BODY[TEXT]<0>", text))
+	}
+	if strings.Contains(items, "BODY.PEEK[]") {
+		parts = append(parts, literal("BODY[]", msg.raw))
+	}
+	return strings.Join(parts, " ")
+}
+
+func imapTestMessage(subject, date string) string {
+	return "From: Ann <ann@example.com>\r\nSubject: " + subject + "\r\nDate: " + date + "\r\n\r\nBody of " + subject + "\r\n"
+}
+
+func TestIMAPProvider_GetEmails(t *testing.T) {
+	server := newFakeIMAPServer(t)
+	server.add("INBOX", imapTestMessage("Older", "Sun, 01 Jan 2006 10:00:00 +0000"), `\Seen`, `\Flagged`)
+	server.add("INBOX", imapTestMessage("Fresh", "Mon, 02 Jan 2006 15:04:05 +0000"))
+	server.add("INBOX", imapTestMessage("Gone", "Mon, 02 Jan 2006 16:00:00 +0000"), `\Deleted`)
+	server.add("INBOX", "Subject: (no date)\r\n\r\nUndated\r\n")
+
+	emails, err := server.provider(t, false).GetEmails()
+	if err != nil {
+		t.Fatalf("GetEmails failed: %v", err)
+	}
+	if len(emails) != 3 {
+		t.Fatalf("Expected 3 emails, got %+v", emails)
+	}
+
+	fresh, older := emails[0], emails[2]
+	if fresh.Subject != "Fresh" || fresh.ID != "2" || fresh.Read || fresh.Preview != "Body of Fresh" {
+		t.Errorf("Unexpected new message %+v", fresh)
+	}
+	if older.Subject != "Older" || !older.Read || !older.Flagged {
+		t.Errorf("Unexpected flagged message %+v", older)
+	}
+	if undated := emails[1]; undated.Time.IsZero() {
+		t.Errorf("Expected INTERNALDATE for a message without Date, got %+v", undated)
+	}
+
+	provider := server.provider(t, false)
+	if email, err := provider.GetEmail(older.ID); err != nil || email.Subject != "Older" || !email.Flagged {
+		t.Errorf("GetEmail returned %+v, %v", email, err)
+	}
+	if _, err := provider.GetEmail("3"); err != ErrEmailNotFound {
+		t.Errorf("Expected deleted message to be missing, got %v", err)
+	}
+	if _, err := provider.GetEmail("99"); err != ErrEmailNotFound {
+		t.Errorf("Expected unknown UID to be missing, got %v", err)
+	}
+}
+
+func TestIMAPProvider_Actions(t *testing.T) {
+	server := newFakeIMAPServer(t)
+	first := server.add("INBOX", imapTestMessage("First", "Mon, 02 Jan 2006 15:04:05 +0000"))
+	second := server.add("INBOX", imapTestMessage("Second", "Mon, 02 Jan 2006 16:04:05 +0000"))
+	provider := server.provider(t, false)
+	id := strconv.Itoa(int(first))
+
+	if err := provider.SetRead(id, true); err != nil {
+		t.Fatalf("SetRead failed: %v", err)
+	}
+	if err := provider.SetFlagged(id, true); err != nil {
+		t.Fatalf("SetFlagged failed: %v", err)
+	}
+	if msg, _ := server.find("INBOX", first); !msg.flags[`\Seen`] || !msg.flags[`\Flagged`] {
+		t.Errorf("Expected \\Seen and \\Flagged, got %v", msg.flags)
+	}
+
+	raw, err := provider.ReadMessage(id)
+	if err != nil || !strings.Contains(string(raw), "Body of First") {
+		t.Errorf("ReadMessage returned %q, %v", raw, err)
+	}
+
+	if err := provider.Archive(id); err != nil {
+		t.Fatalf("Archive failed: %v", err)
+	}
+	if err := provider.DeleteEmail(strconv.Itoa(int(second))); err != nil {
+		t.Fatalf("DeleteEmail failed: %v", err)
+	}
+	if len(server.mailboxes["INBOX"]) != 0 || len(server.mailboxes["Archive"]) != 1 || len(server.mailboxes["Trash"]) != 1 {
+		t.Errorf("Unexpected mailboxes after archive and delete: %v", server.mailboxes)
+	}
+
+	for _, missing := range []string{id, "abc", "0"} {
+		if err := provider.SetRead(missing, true); err != ErrEmailNotFound {
+			t.Errorf("SetRead(%q): expected ErrEmailNotFound, got %v", missing, err)
+		}
+		if err := provider.Archive(missing); err != ErrEmailNotFound {
+			t.Errorf("Archive(%q): expected ErrEmailNotFound, got %v", missing, err)
+		}
+	}
+}
+
+func TestIMAPProvider_WithoutMoveExtension(t *testing.T) {
+	server := newFakeIMAPServer(t)
+	server.noMove = true
+	archived := server.add("INBOX", imapTestMessage("Archived", "Mon, 02 Jan 2006 15:04:05 +0000"))
+	expunged := server.add("INBOX", imapTestMessage("Expunged", "Mon, 02 Jan 2006 16:04:05 +0000"))
+	provider := server.provider(t, true)
+
+	if err := provider.Archive(strconv.Itoa(int(archived))); err != nil {
+		t.Fatalf("Archive failed: %v", err)
+	}
+	if err := provider.DeleteEmail(strconv.Itoa(int(expunged))); err != nil {
+		t.Fatalf("DeleteEmail failed: %v", err)
+	}
+	if len(server.mailboxes["INBOX"]) != 0 || len(server.mailboxes["Archive"]) != 1 || len(server.mailboxes["Trash"]) != 0 {
+		t.Errorf("Expected copy and expunge fallbacks, got %v", server.mailboxes)
+	}
+}
+
+func TestIMAPProvider_LoginFailure(t *testing.T) {
+	server := newFakeIMAPServer(t)
+	provider := server.provider(t, false)
+	provider.options.Password = secrets.NewValue("wrong")
+
+	_, err := provider.GetEmails()
+	if err == nil || !strings.Contains(err.Error(), "imap login") {
+		t.Errorf("Expected a login error, got %v", err)
+	}
+}
+
+func TestIMAPProvider_SaveSent(t *testing.T) {
+	server := newFakeIMAPServer(t)
+	message := imapTestMessage("Sent one", "Mon, 02 Jan 2006 15:04:05 +0000")
+
+	if err := server.provider(t, false).SaveSent([]byte(message)); err != nil {
+		t.Fatalf("SaveSent failed: %v", err)
+	}
+
+	server.mutex.Lock()
+	defer server.mutex.Unlock()
+	sent := server.mailboxes["Sent"]
+	if len(sent) != 1 || sent[0].raw != message || !sent[0].flags[`\Seen`] {
+		t.Errorf("Expected the message appended to Sent as seen, got %+v", sent)
+	}
+}
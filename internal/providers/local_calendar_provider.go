@@ -0,0 +1,177 @@
+package providers
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+
+	"flexpane/internal/ical"
+	"flexpane/internal/models"
+)
+
+func init() {
+	Register("local", Registration{
+		New: func(settings Settings) (DataProvider, error) {
+			return NewLocalCalendarProvider(settings.String("path"))
+		},
+		Schema: ConfigSchema{
+			"path": {Type: "string", Default: "data/calendar.json", Description: "Calendar file; .ics files are stored as iCalendar, anything else as JSON"},
+		},
+	})
+}
+
+// LocalCalendarProvider keeps a writable calendar in a local JSON or ICS file.
+// It has no mailbox, so GetEmails always returns an empty list.
+type LocalCalendarProvider struct {
+	path   string
+	events []models.Event
+	mutex  sync.RWMutex
+}
+
+func NewLocalCalendarProvider(path string) (*LocalCalendarProvider, error) {
+	provider := &LocalCalendarProvider{path: path}
+	if err := provider.load(); err != nil {
+		return nil, err
+	}
+	return provider, nil
+}
+
+func (l *LocalCalendarProvider) GetCalendarEvents() ([]models.Event, error) {
+	l.mutex.RLock()
+	defer l.mutex.RUnlock()
+
+	events := make([]models.Event, len(l.events))
+	copy(events, l.events)
+	return events, nil
+}
+
+func (l *LocalCalendarProvider) GetEmails() ([]models.Email, error) {
+	return []models.Email{}, nil
+}
+
+func (l *LocalCalendarProvider) CreateEvent(event models.Event) (models.Event, error) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	if event.ID == "" {
+		event.ID = newEventID()
+	}
+	for _, existing := range l.events {
+		if existing.ID == event.ID {
+			return event, ErrEventExists
+		}
+	}
+
+	previous := slices.Clone(l.events)
+	l.events = append(l.events, event)
+	l.sort()
+	return event, l.saveOrRestore(previous)
+}
+
+func (l *LocalCalendarProvider) UpdateEvent(event models.Event) (models.Event, error) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	for i := range l.events {
+		if l.events[i].ID == event.ID {
+			previous := slices.Clone(l.events)
+			l.events[i] = event
+			l.sort()
+			return event, l.saveOrRestore(previous)
+		}
+	}
+	return event, ErrEventNotFound
+}
+
+func (l *LocalCalendarProvider) DeleteEvent(id string) error {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	for i := range l.events {
+		if l.events[i].ID == id {
+			previous := slices.Clone(l.events)
+			l.events = slices.Delete(l.events, i, i+1)
+			return l.saveOrRestore(previous)
+		}
+	}
+	return ErrEventNotFound
+}
+
+func (l *LocalCalendarProvider) isICS() bool {
+	return strings.EqualFold(filepath.Ext(l.path), ".ics")
+}
+
+// sort must be called with the mutex held
+func (l *LocalCalendarProvider) sort() {
+	sort.SliceStable(l.events, func(i, j int) bool {
+		return l.events[i].Start.Before(l.events[j].Start)
+	})
+}
+
+func (l *LocalCalendarProvider) load() error {
+	data, err := os.ReadFile(l.path)
+	if os.IsNotExist(err) {
+		l.events = []models.Event{}
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	if l.isICS() {
+		l.events, err = ical.DecodeEvents(bytes.NewReader(data))
+	} else {
+		err = json.Unmarshal(data, &l.events)
+	}
+	if err != nil {
+		return err
+	}
+	l.sort()
+	return nil
+}
+
+// save must be called with the mutex held
+func (l *LocalCalendarProvider) save() error {
+	if err := os.MkdirAll(filepath.Dir(l.path), 0755); err != nil {
+		return err
+	}
+
+	var buf bytes.Buffer
+	if l.isICS() {
+		if err := ical.EncodeEvents(&buf, l.events); err != nil {
+			return err
+		}
+	} else {
+		data, err := json.MarshalIndent(l.events, "", "  ")
+		if err != nil {
+			return err
+		}
+		buf.Write(data)
+	}
+
+	return os.WriteFile(l.path, buf.Bytes(), 0644)
+}
+
+// saveOrRestore saves, putting the events back to previous if that fails so
+// memory never holds a change the file doesn't. The mutex must be held.
+func (l *LocalCalendarProvider) saveOrRestore(previous []models.Event) error {
+	if err := l.save(); err != nil {
+		l.events = previous
+		return err
+	}
+	return nil
+}
+
+// newEventID returns a random identifier suitable for an iCalendar UID
+func newEventID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b) + "@flexpane"
+}
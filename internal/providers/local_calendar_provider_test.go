@@ -0,0 +1,121 @@
+package providers
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"flexpane/internal/models"
+)
+
+func TestLocalCalendarProvider_CRUD(t *testing.T) {
+	for _, name := range []string{"calendar.json", "calendar.ics"} {
+		t.Run(name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), name)
+
+			provider, err := NewLocalCalendarProvider(path)
+			if err != nil {
+				t.Fatalf("NewLocalCalendarProvider failed: %v", err)
+			}
+
+			start := time.Date(2025, 1, 6, 9, 0, 0, 0, time.UTC)
+			created, err := provider.CreateEvent(models.Event{Title: "Standup", Start: start, End: start.Add(15 * time.Minute)})
+			if err != nil {
+				t.Fatalf("CreateEvent failed: %v", err)
+			}
+			if created.ID == "" {
+				t.Fatal("Expected an ID to be assigned")
+			}
+
+			created.Title = "Daily Standup"
+			if _, err := provider.UpdateEvent(created); err != nil {
+				t.Fatalf("UpdateEvent failed: %v", err)
+			}
+
+			// Reload from disk to check persistence
+			reloaded, err := NewLocalCalendarProvider(path)
+			if err != nil {
+				t.Fatalf("Reload failed: %v", err)
+			}
+			events, _ := reloaded.GetCalendarEvents()
+			if len(events) != 1 || events[0].Title != "Daily Standup" || !events[0].Start.Equal(start) {
+				t.Fatalf("Unexpected events after reload: %+v", events)
+			}
+
+			if err := reloaded.DeleteEvent(created.ID); err != nil {
+				t.Fatalf("DeleteEvent failed: %v", err)
+			}
+			if err := reloaded.DeleteEvent(created.ID); !errors.Is(err, ErrEventNotFound) {
+				t.Errorf("Expected ErrEventNotFound, got %v", err)
+			}
+
+			if strings.HasSuffix(name, ".ics") {
+				data, _ := os.ReadFile(path)
+				if !strings.HasPrefix(string(data), "BEGIN:VCALENDAR") {
+					t.Errorf("Expected iCalendar file, got %q", data)
+				}
+			}
+		})
+	}
+}
+
+func TestLocalCalendarProvider_DuplicateID(t *testing.T) {
+	provider, err := NewLocalCalendarProvider(filepath.Join(t.TempDir(), "calendar.json"))
+	if err != nil {
+		t.Fatalf("NewLocalCalendarProvider failed: %v", err)
+	}
+
+	event := models.Event{ID: "standup", Title: "Standup"}
+	if _, err := provider.CreateEvent(event); err != nil {
+		t.Fatalf("CreateEvent failed: %v", err)
+	}
+	event.Title = "Impostor"
+	if _, err := provider.CreateEvent(event); !errors.Is(err, ErrEventExists) {
+		t.Fatalf("Expected ErrEventExists, got %v", err)
+	}
+
+	events, _ := provider.GetCalendarEvents()
+	if len(events) != 1 || events[0].Title != "Standup" {
+		t.Errorf("Expected the original event only, got %+v", events)
+	}
+}
+
+func TestLocalCalendarProvider_FailedSave(t *testing.T) {
+	dir := t.TempDir()
+	provider, err := NewLocalCalendarProvider(filepath.Join(dir, "data", "calendar.json"))
+	if err != nil {
+		t.Fatalf("NewLocalCalendarProvider failed: %v", err)
+	}
+	created, err := provider.CreateEvent(models.Event{Title: "Standup"})
+	if err != nil {
+		t.Fatalf("CreateEvent failed: %v", err)
+	}
+
+	// Replace the data directory with a file so saving fails
+	if err := os.RemoveAll(filepath.Join(dir, "data")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "data"), nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := provider.CreateEvent(models.Event{Title: "Retro"}); err == nil {
+		t.Error("Expected CreateEvent to fail")
+	}
+	updated := created
+	updated.Title = "Renamed"
+	if _, err := provider.UpdateEvent(updated); err == nil {
+		t.Error("Expected UpdateEvent to fail")
+	}
+	if err := provider.DeleteEvent(created.ID); err == nil {
+		t.Error("Expected DeleteEvent to fail")
+	}
+
+	events, _ := provider.GetCalendarEvents()
+	if len(events) != 1 || events[0].Title != "Standup" {
+		t.Errorf("Expected failed saves to leave the calendar unchanged, got %+v", events)
+	}
+}
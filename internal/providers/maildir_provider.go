@@ -0,0 +1,322 @@
+package providers
+
+import (
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"flexpane/internal/mail"
+	"flexpane/internal/models"
+)
+
+func init() {
+	Register("maildir", Registration{
+		New: func(settings Settings) (DataProvider, error) {
+			return NewMaildirProvider(MaildirOptions{
+				Path:    settings.String("path"),
+				Archive: settings.String("archive"),
+				Trash:   settings.String("trash"),
+				Sent:    settings.String("sent"),
+				Expunge: settings.Bool("expunge"),
+				Limit:   settings.Int("limit"),
+			})
+		},
+		Schema: ConfigSchema{
+			"path":    {Type: "string", Required: true, Description: "Maildir root containing cur, new and tmp"},
+			"archive": {Type: "string", Default: "Archive", Description: "Maildir++ folder archived messages move to"},
+			"trash":   {Type: "string", Default: "Trash", Description: "Maildir++ folder deleted messages move to"},
+			"sent":    {Type: "string", Default: "Sent", Description: "Maildir++ folder copies of sent messages are stored in"},
+			"expunge": {Type: "bool", Description: "Delete messages permanently instead of moving them to trash"},
+			"limit":   {Type: "number", Default: 50, Description: "Maximum number of messages listed"},
+		},
+	})
+}
+
+// MaildirOptions configures a Maildir mailbox
+type MaildirOptions struct {
+	Path    string
+	Archive string
+	Trash   string
+	Sent    string
+	Expunge bool
+	Limit   int
+}
+
+// MaildirProvider reads the inbox of a local Maildir, as written by
+// offlineimap, mbsync or a local MDA. Message IDs are the unique part of the
+// file name, which stays stable as flags change. It has no calendar, so
+// GetCalendarEvents always returns an empty list.
+type MaildirProvider struct {
+	options MaildirOptions
+	mutex   sync.Mutex
+}
+
+func NewMaildirProvider(options MaildirOptions) (*MaildirProvider, error) {
+	if options.Path == "" {
+		return nil, errors.New("maildir path is required")
+	}
+	if options.Archive == "" {
+		options.Archive = "Archive"
+	}
+	if options.Trash == "" {
+		options.Trash = "Trash"
+	}
+	if options.Sent == "" {
+		options.Sent = "Sent"
+	}
+	if options.Limit <= 0 {
+		options.Limit = 50
+	}
+	for _, sub := range []string{"cur", "new", "tmp"} {
+		if _, err := os.Stat(filepath.Join(options.Path, sub)); err != nil {
+			return nil, fmt.Errorf("not a maildir: %w", err)
+		}
+	}
+	return &MaildirProvider{options: options}, nil
+}
+
+func (p *MaildirProvider) GetCalendarEvents() ([]models.Event, error) {
+	return []models.Event{}, nil
+}
+
+func (p *MaildirProvider) GetEmails() ([]models.Email, error) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	emails := []models.Email{}
+	for _, sub := range []string{"new", "cur"} {
+		entries, err := os.ReadDir(filepath.Join(p.options.Path, sub))
+		if err != nil {
+			return nil, err
+		}
+		for _, entry := range entries {
+			if entry.IsDir() || strings.HasPrefix(entry.Name(), ".") {
+				continue
+			}
+			id, flags := splitMaildirName(entry.Name())
+			if strings.ContainsRune(flags, 'T') {
+				continue // Marked for deletion by another client
+			}
+
+			email, err := p.email(filepath.Join(p.options.Path, sub, entry.Name()), id, flags)
+			if err != nil {
+				continue // Moved by another client since the listing, or unreadable
+			}
+			emails = append(emails, email)
+		}
+	}
+
+	sort.Slice(emails, func(i, j int) bool {
+		return emails[i].Time.After(emails[j].Time)
+	})
+	if len(emails) > p.options.Limit {
+		emails = emails[:p.options.Limit]
+	}
+	return emails, nil
+}
+
+// GetEmail implements EmailGetter
+func (p *MaildirProvider) GetEmail(id string) (models.Email, error) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	path, flags, err := p.find(id)
+	if err != nil {
+		return models.Email{}, err
+	}
+	if strings.ContainsRune(flags, 'T') {
+		return models.Email{}, ErrEmailNotFound
+	}
+	return p.email(path, id, flags)
+}
+
+// email summarizes the message file at path
+func (p *MaildirProvider) email(path, id, flags string) (models.Email, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return models.Email{}, err
+	}
+	msg, err := mail.Summarize(raw)
+	if err != nil {
+		return models.Email{}, err
+	}
+
+	email := models.Email{
+		ID:         id,
+		Subject:    msg.Subject,
+		From:       msg.From,
+		Preview:    msg.Snippet(100),
+		Time:       msg.Date,
+		Read:       strings.ContainsRune(flags, 'S'),
+		Flagged:    strings.ContainsRune(flags, 'F'),
+		MessageID:  msg.MessageID,
+		InReplyTo:  msg.InReplyTo,
+		References: msg.References,
+	}
+	if email.Time.IsZero() {
+		if info, err := os.Stat(path); err == nil {
+			email.Time = info.ModTime()
+		}
+	}
+	return email, nil
+}
+
+// ReadMessage implements MessageReader
+func (p *MaildirProvider) ReadMessage(id string) ([]byte, error) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	path, _, err := p.find(id)
+	if err != nil {
+		return nil, err
+	}
+	return os.ReadFile(path)
+}
+
+// SetRead implements EmailWriter
+func (p *MaildirProvider) SetRead(id string, read bool) error {
+	return p.setFlag(id, 'S', read)
+}
+
+// SetFlagged implements EmailWriter
+func (p *MaildirProvider) SetFlagged(id string, flagged bool) error {
+	return p.setFlag(id, 'F', flagged)
+}
+
+// Archive implements EmailWriter
+func (p *MaildirProvider) Archive(id string) error {
+	return p.moveTo(id, p.options.Archive)
+}
+
+// DeleteEmail implements EmailWriter
+func (p *MaildirProvider) DeleteEmail(id string) error {
+	if !p.options.Expunge {
+		return p.moveTo(id, p.options.Trash)
+	}
+
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	path, _, err := p.find(id)
+	if err != nil {
+		return err
+	}
+	return os.Remove(path)
+}
+
+// SaveSent implements SentSaver. The message is written to tmp first so
+// other clients never see a partial file.
+func (p *MaildirProvider) SaveSent(message []byte) error {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	dir, err := p.folder(p.options.Sent)
+	if err != nil {
+		return err
+	}
+
+	random := make([]byte, 8)
+	if _, err := rand.Read(random); err != nil {
+		return err
+	}
+	host, _ := os.Hostname()
+	id := fmt.Sprintf("%d.%x.%s", time.Now().UnixNano(), random, strings.NewReplacer("/", "\\057", ":", "\\072").Replace(host))
+
+	tmp := filepath.Join(dir, "tmp", id)
+	if err := os.WriteFile(tmp, message, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, filepath.Join(dir, "cur", maildirName(id, "S")))
+}
+
+func (p *MaildirProvider) setFlag(id string, flag rune, on bool) error {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	path, flags, err := p.find(id)
+	if err != nil {
+		return err
+	}
+
+	flags = strings.ReplaceAll(flags, string(flag), "")
+	if on {
+		flags += string(flag)
+	}
+	// Messages with flags belong in cur
+	return os.Rename(path, filepath.Join(p.options.Path, "cur", maildirName(id, flags)))
+}
+
+func (p *MaildirProvider) moveTo(id, folder string) error {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	path, flags, err := p.find(id)
+	if err != nil {
+		return err
+	}
+
+	dir, err := p.folder(folder)
+	if err != nil {
+		return err
+	}
+	return os.Rename(path, filepath.Join(dir, "cur", maildirName(id, flags)))
+}
+
+// folder returns a Maildir++ subfolder, creating it if needed
+func (p *MaildirProvider) folder(name string) (string, error) {
+	dir := filepath.Join(p.options.Path, "."+name)
+	for _, sub := range []string{"cur", "new", "tmp"} {
+		if err := os.MkdirAll(filepath.Join(dir, sub), 0700); err != nil {
+			return "", err
+		}
+	}
+	return dir, nil
+}
+
+// find locates a message in the inbox by ID
+func (p *MaildirProvider) find(id string) (string, string, error) {
+	if id == "" || strings.ContainsAny(id, `/\:`) || strings.HasPrefix(id, ".") {
+		return "", "", ErrEmailNotFound
+	}
+
+	for _, sub := range []string{"new", "cur"} {
+		dir := filepath.Join(p.options.Path, sub)
+		matches, err := filepath.Glob(filepath.Join(dir, globEscape(id)+"*"))
+		if err != nil {
+			return "", "", err
+		}
+		for _, match := range matches {
+			name := filepath.Base(match)
+			if candidate, flags := splitMaildirName(name); candidate == id {
+				return match, flags, nil
+			}
+		}
+	}
+	return "", "", ErrEmailNotFound
+}
+
+// splitMaildirName separates the unique part of a file name from its flags
+func splitMaildirName(name string) (string, string) {
+	id, info, found := strings.Cut(name, ":")
+	if !found {
+		return id, ""
+	}
+	return id, strings.TrimPrefix(info, "2,")
+}
+
+// maildirName builds a file name with flags in the required ASCII order
+func maildirName(id, flags string) string {
+	letters := strings.Split(flags, "")
+	sort.Strings(letters)
+	return id + ":2," + strings.Join(letters, "")
+}
+
+func globEscape(s string) string {
+	return strings.NewReplacer(`*`, `\*`, `?`, `\?`, `[`, `\[`).Replace(s)
+}
@@ -0,0 +1,136 @@
+package providers
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func writeMaildirMessage(t *testing.T, root, sub, name, subject, date string) {
+	t.Helper()
+	raw := "From: Ann <ann@example.com>\r\nSubject: " + subject + "\r\nDate: " + date + "\r\n\r\nBody of " + subject + "\r\n"
+	if err := os.WriteFile(filepath.Join(root, sub, name), []byte(raw), 0600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func newTestMaildir(t *testing.T, expunge bool) (*MaildirProvider, string) {
+	t.Helper()
+	root := t.TempDir()
+	for _, sub := range []string{"cur", "new", "tmp"} {
+		if err := os.Mkdir(filepath.Join(root, sub), 0700); err != nil {
+			t.Fatal(err)
+		}
+	}
+	writeMaildirMessage(t, root, "new", "1001.a.host", "Fresh", "Mon, 02 Jan 2006 15:04:05 +0000")
+	writeMaildirMessage(t, root, "cur", "1000.b.host:2,FS", "Older", "Sun, 01 Jan 2006 10:00:00 +0000")
+	writeMaildirMessage(t, root, "cur", "999.c.host:2,ST", "Trashed", "Sat, 31 Dec 2005 10:00:00 +0000")
+
+	provider, err := NewMaildirProvider(MaildirOptions{Path: root, Expunge: expunge})
+	if err != nil {
+		t.Fatalf("NewMaildirProvider failed: %v", err)
+	}
+	return provider, root
+}
+
+func TestMaildirProvider_GetEmails(t *testing.T) {
+	provider, _ := newTestMaildir(t, false)
+
+	emails, err := provider.GetEmails()
+	if err != nil {
+		t.Fatalf("GetEmails failed: %v", err)
+	}
+	if len(emails) != 2 {
+		t.Fatalf("Expected 2 emails without the trashed one, got %+v", emails)
+	}
+
+	fresh, older := emails[0], emails[1]
+	if fresh.ID != "1001.a.host" || fresh.Subject != "Fresh" || fresh.Read || fresh.Flagged {
+		t.Errorf("Unexpected new message %+v", fresh)
+	}
+	if fresh.Preview != "Body of Fresh" || fresh.From != "Ann <ann@example.com>" {
+		t.Errorf("Unexpected preview or sender %+v", fresh)
+	}
+	if older.ID != "1000.b.host" || !older.Read || !older.Flagged {
+		t.Errorf("Unexpected flagged message %+v", older)
+	}
+
+	raw, err := provider.ReadMessage("1000.b.host")
+	if err != nil || len(raw) == 0 {
+		t.Errorf("ReadMessage failed: %v", err)
+	}
+
+	email, err := provider.GetEmail("1000.b.host")
+	if err != nil || email.Subject != "Older" || !email.Read || !email.Flagged {
+		t.Errorf("GetEmail returned %+v, %v", email, err)
+	}
+	if _, err := provider.GetEmail("999.c.host"); err != ErrEmailNotFound {
+		t.Errorf("Expected trashed message to be missing, got %v", err)
+	}
+}
+
+func TestMaildirProvider_Actions(t *testing.T) {
+	provider, root := newTestMaildir(t, false)
+
+	if err := provider.SetRead("1001.a.host", true); err != nil {
+		t.Fatalf("SetRead failed: %v", err)
+	}
+	if err := provider.SetFlagged("1001.a.host", true); err != nil {
+		t.Fatalf("SetFlagged failed: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(root, "cur", "1001.a.host:2,FS")); err != nil {
+		t.Errorf("Expected the message in cur with flags FS: %v", err)
+	}
+
+	if err := provider.SetFlagged("1000.b.host", false); err != nil {
+		t.Fatalf("SetFlagged failed: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(root, "cur", "1000.b.host:2,S")); err != nil {
+		t.Errorf("Expected the F flag removed: %v", err)
+	}
+
+	if err := provider.Archive("1001.a.host"); err != nil {
+		t.Fatalf("Archive failed: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(root, ".Archive", "cur", "1001.a.host:2,FS")); err != nil {
+		t.Errorf("Expected the message in the archive folder: %v", err)
+	}
+
+	if err := provider.DeleteEmail("1000.b.host"); err != nil {
+		t.Fatalf("DeleteEmail failed: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(root, ".Trash", "cur", "1000.b.host:2,S")); err != nil {
+		t.Errorf("Expected the message in the trash folder: %v", err)
+	}
+
+	emails, _ := provider.GetEmails()
+	if len(emails) != 0 {
+		t.Errorf("Expected an empty inbox, got %+v", emails)
+	}
+
+	for _, id := range []string{"1001.a.host", "../cur/1000.b.host", ".Trash", ""} {
+		if err := provider.SetRead(id, true); err != ErrEmailNotFound {
+			t.Errorf("SetRead(%q): expected ErrEmailNotFound, got %v", id, err)
+		}
+	}
+}
+
+func TestMaildirProvider_Expunge(t *testing.T) {
+	provider, root := newTestMaildir(t, true)
+
+	if err := provider.DeleteEmail("1001.a.host"); err != nil {
+		t.Fatalf("DeleteEmail failed: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(root, "new", "1001.a.host")); !os.IsNotExist(err) {
+		t.Errorf("Expected the message removed, got %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(root, ".Trash")); !os.IsNotExist(err) {
+		t.Errorf("Expected no trash folder when expunging, got %v", err)
+	}
+}
+
+func TestNewMaildirProvider_RejectsNonMaildir(t *testing.T) {
+	if _, err := NewMaildirProvider(MaildirOptions{Path: t.TempDir()}); err == nil {
+		t.Error("Expected an error for a directory without cur, new and tmp")
+	}
+}
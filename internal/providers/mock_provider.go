@@ -2,29 +2,182 @@ package providers
 
 import (
 	"flexpane/internal/models"
+	"fmt"
+	"strings"
+	"sync"
 	"time"
 )
 
-type MockProvider struct{}
+func init() {
+	Register("mock", Registration{
+		New: func(settings Settings) (DataProvider, error) {
+			return NewMockProvider(), nil
+		},
+		Schema: ConfigSchema{},
+	})
+}
+
+// MockProvider serves sample data. Email actions are kept in memory, so each
+// instance starts from the same mailbox.
+type MockProvider struct {
+	mutex   sync.Mutex
+	changes map[string]mockEmailState
+}
+
+// mockEmailState overrides the sample flags of one email
+type mockEmailState struct {
+	read     *bool
+	flagged  bool
+	archived bool
+	deleted  bool
+}
 
 func NewMockProvider() *MockProvider {
-	return &MockProvider{}
+	return &MockProvider{changes: map[string]mockEmailState{}}
 }
 
 func (m *MockProvider) GetCalendarEvents() ([]models.Event, error) {
 	now := time.Now()
 	return []models.Event{
 		{ID: "1", Title: "Team Standup", Start: now.Add(time.Hour), End: now.Add(time.Hour + 30*time.Minute), Location: "Conference Room A"},
-		{ID: "2", Title: "Product Review", Start: now.Add(2 * time.Hour), End: now.Add(3 * time.Hour), Location: "Zoom"},
+		{ID: "2", Title: "Product Review", Start: now.Add(2 * time.Hour), End: now.Add(3 * time.Hour), Location: "Zoom", Description: "Join: https://zoom.us/j/5551234567"},
 		{ID: "3", Title: "Client Call", Start: now.Add(4 * time.Hour), End: now.Add(4*time.Hour + 45*time.Minute), Location: "Phone"},
 	}, nil
 }
 
 func (m *MockProvider) GetEmails() ([]models.Email, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	emails := []models.Email{}
+	for _, email := range m.sampleEmails() {
+		state := m.changes[email.ID]
+		if state.archived || state.deleted {
+			continue
+		}
+		if state.read != nil {
+			email.Read = *state.read
+		}
+		email.Flagged = state.flagged
+		emails = append(emails, email)
+	}
+	return emails, nil
+}
+
+// GetEmail implements EmailGetter
+func (m *MockProvider) GetEmail(id string) (models.Email, error) {
+	emails, _ := m.GetEmails()
+	for _, email := range emails {
+		if email.ID == id {
+			return email, nil
+		}
+	}
+	return models.Email{}, ErrEmailNotFound
+}
+
+func (m *MockProvider) sampleEmails() []models.Email {
 	now := time.Now()
 	return []models.Email{
-		{ID: "1", Subject: "Budget Meeting", From: "sarah@company.com", Preview: "Q4 planning...", Time: now.Add(-2 * time.Hour), Read: false},
-		{ID: "2", Subject: "Project Update", From: "mike@company.com", Preview: "Latest build ready...", Time: now.Add(-4 * time.Hour), Read: true},
-		{ID: "3", Subject: "Newsletter", From: "news@tech.com", Preview: "AI developments...", Time: now.Add(-30 * time.Minute), Read: false},
-	}, nil
-}
\ No newline at end of file
+		{ID: "1", Subject: "Budget Meeting", From: "sarah@company.com", Preview: "Q4 planning...", Time: now.Add(-2 * time.Hour), Read: false, MessageID: "mock-1@flexpane"},
+		{ID: "2", Subject: "Project Update", From: "mike@company.com", Preview: "Latest build ready...", Time: now.Add(-4 * time.Hour), Read: true, MessageID: "mock-2@flexpane"},
+		{ID: "3", Subject: "Newsletter", From: "news@tech.com", Preview: "AI developments...", Time: now.Add(-30 * time.Minute), Read: false, MessageID: "mock-3@flexpane"},
+		{ID: "4", Subject: "Re: Budget Meeting", From: "mike@company.com", Preview: "Estimates attached...", Time: now.Add(-time.Hour), Read: false, MessageID: "mock-4@flexpane", InReplyTo: "mock-1@flexpane", References: []string{"mock-1@flexpane"}},
+	}
+}
+
+// SetRead implements EmailWriter
+func (m *MockProvider) SetRead(id string, read bool) error {
+	return m.change(id, func(state *mockEmailState) { state.read = &read })
+}
+
+// SetFlagged implements EmailWriter
+func (m *MockProvider) SetFlagged(id string, flagged bool) error {
+	return m.change(id, func(state *mockEmailState) { state.flagged = flagged })
+}
+
+// Archive implements EmailWriter
+func (m *MockProvider) Archive(id string) error {
+	return m.change(id, func(state *mockEmailState) { state.archived = true })
+}
+
+// DeleteEmail implements EmailWriter
+func (m *MockProvider) DeleteEmail(id string) error {
+	return m.change(id, func(state *mockEmailState) { state.deleted = true })
+}
+
+func (m *MockProvider) change(id string, apply func(state *mockEmailState)) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	state := m.changes[id]
+	if _, known := mockBodies[id]; !known || state.archived || state.deleted {
+		return ErrEmailNotFound
+	}
+	apply(&state)
+	if m.changes == nil {
+		m.changes = map[string]mockEmailState{}
+	}
+	m.changes[id] = state
+	return nil
+}
+
+// mockBodies are the full messages behind the mock emails. The newsletter is
+// HTML-only with a tracking pixel and a script, to exercise sanitization.
+var mockBodies = map[string]string{
+	"1": `Content-Type: multipart/alternative; boundary="b1"
+
+--b1
+Content-Type: text/plain; charset=utf-8
+
+Hi,
+
+Q4 planning kicks off next week. Please send your budget estimates by Friday.
+
+Thanks,
+Sarah
+--b1
+Content-Type: text/html; charset=utf-8
+
+<p>Hi,</p><p>Q4 planning kicks off next week. Please send your <b>budget estimates</b> by Friday.</p><p>Thanks,<br>Sarah</p>
+--b1--
+`,
+	"2": `Content-Type: text/plain; charset=utf-8
+Content-Transfer-Encoding: quoted-printable
+
+The latest build is ready for testing on staging.=0A=0AChangelog is attached t=
+o the release ticket.
+`,
+	"3": `Content-Type: text/html; charset=utf-8
+
+<html><head><style>body { color: red; }</style><script>track()</script></head>
+<body><h1>This week in AI</h1><img src="https://news.tech.com/pixel.gif?u=123" width="1" height="1">
+<p>AI developments you might have missed. <a href="https://news.tech.com/issue/42">Read online</a></p></body></html>
+`,
+	"4": `Content-Type: text/plain; charset=utf-8
+
+Estimates attached. Marketing still needs to send theirs.
+
+> Please send your budget estimates by Friday.
+`,
+}
+
+// ReadMessage implements MessageReader
+func (m *MockProvider) ReadMessage(id string) ([]byte, error) {
+	body, exists := mockBodies[id]
+	if !exists {
+		return nil, ErrEmailNotFound
+	}
+
+	for _, email := range m.sampleEmails() {
+		if email.ID != id {
+			continue
+		}
+		header := fmt.Sprintf("Message-ID: <%s>\r\nFrom: %s\r\nTo: me@company.com\r\nSubject: %s\r\nDate: %s\r\nMIME-Version: 1.0\r\n",
+			email.MessageID, email.From, email.Subject, email.Time.Format(time.RFC1123Z))
+		if email.InReplyTo != "" {
+			header += fmt.Sprintf("In-Reply-To: <%s>\r\nReferences: <%s>\r\n", email.InReplyTo, strings.Join(email.References, "> <"))
+		}
+		return []byte(header + strings.ReplaceAll(body, "\n", "\r\n")), nil
+	}
+	return nil, ErrEmailNotFound
+}
@@ -0,0 +1,342 @@
+package providers
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os/exec"
+	"sync"
+	"time"
+
+	"flexpane/internal/models"
+)
+
+// PluginProvider runs an external executable and talks to it with
+// newline-delimited JSON-RPC 2.0 over stdin/stdout. See docs/plugins.md for
+// the protocol. The process is started lazily, restarted after a crash and
+// killed when a call exceeds its timeout. Only failed starts and exits count
+// against the restart budget; once it is spent the provider waits out a
+// cooldown before trying again.
+type PluginProvider struct {
+	command     string
+	args        []string
+	options     map[string]interface{}
+	timeout     time.Duration
+	maxRestarts int
+
+	mutex       sync.Mutex
+	process     *pluginProcess
+	nextID      int64
+	failures    int // Consecutive failed starts or crashes
+	lastFailure time.Time
+	cooldown    time.Duration
+}
+
+// pluginCooldown is how long a plugin that used up its restarts is left
+// alone before it gets another try
+const pluginCooldown = time.Minute
+
+func init() {
+	Register("plugin", Registration{
+		New: func(settings Settings) (DataProvider, error) {
+			return NewPluginProvider(settings.String("command"), settings.Strings("args"), PluginOptions{
+				Timeout:     settings.Duration("timeout"),
+				MaxRestarts: settings.Int("max_restarts"),
+				Options:     settings.Object("options"),
+			}), nil
+		},
+		Schema: ConfigSchema{
+			"command":      {Type: "string", Required: true, Description: "Executable to launch"},
+			"args":         {Type: "list", Description: "Arguments passed to the executable"},
+			"timeout":      {Type: "duration", Default: "10s", Description: "Per-call timeout"},
+			"max_restarts": {Type: "number", Default: 3.0, Description: "Consecutive restarts allowed before giving up"},
+			"options":      {Type: "object", Description: "Plugin-specific settings sent with initialize; may contain secret:// references"},
+		},
+	})
+}
+
+// PluginOptions tunes plugin lifecycle management
+type PluginOptions struct {
+	Timeout     time.Duration
+	MaxRestarts int
+	Options     map[string]interface{} // Sent to the plugin in the initialize call
+}
+
+func NewPluginProvider(command string, args []string, options PluginOptions) *PluginProvider {
+	if options.Timeout <= 0 {
+		options.Timeout = 10 * time.Second
+	}
+	return &PluginProvider{
+		command:     command,
+		args:        args,
+		options:     options.Options,
+		timeout:     options.Timeout,
+		maxRestarts: options.MaxRestarts,
+		cooldown:    pluginCooldown,
+	}
+}
+
+// ErrPluginUnavailable is returned once a plugin has crashed more times in a
+// row than its restart budget allows, until the cooldown has passed
+var ErrPluginUnavailable = errors.New("plugin unavailable")
+
+// RPCError is an error object returned by the plugin
+type RPCError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *RPCError) Error() string {
+	return fmt.Sprintf("plugin error %d: %s", e.Code, e.Message)
+}
+
+type rpcRequest struct {
+	JSONRPC string      `json:"jsonrpc"`
+	ID      *int64      `json:"id,omitempty"` // Omitted for notifications
+	Method  string      `json:"method"`
+	Params  interface{} `json:"params,omitempty"`
+}
+
+type rpcResponse struct {
+	ID     int64           `json:"id"`
+	Result json.RawMessage `json:"result"`
+	Error  *RPCError       `json:"error"`
+}
+
+type pluginProcess struct {
+	cmd       *exec.Cmd
+	stdin     io.WriteCloser
+	responses chan rpcResponse
+	done      chan struct{} // Closed when stdout reaches EOF
+}
+
+func (p *PluginProvider) GetCalendarEvents() ([]models.Event, error) {
+	var events []models.Event
+	if err := p.call("getCalendarEvents", nil, &events); err != nil {
+		return []models.Event{}, err
+	}
+	return events, nil
+}
+
+func (p *PluginProvider) GetEmails() ([]models.Email, error) {
+	var emails []models.Email
+	if err := p.call("getEmails", nil, &emails); err != nil {
+		return []models.Email{}, err
+	}
+	return emails, nil
+}
+
+// Close asks the plugin to shut down and kills it if it doesn't exit promptly
+func (p *PluginProvider) Close() error {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	if p.process == nil {
+		return nil
+	}
+	process := p.process
+	p.process = nil
+
+	process.notify("shutdown")
+	process.stdin.Close()
+
+	select {
+	case <-process.done:
+	case <-time.After(2 * time.Second):
+		process.cmd.Process.Kill()
+	}
+	return process.cmd.Wait()
+}
+
+// call sends one request and waits for its response. Calls are serialized so
+// a plugin never has to handle concurrent requests.
+func (p *PluginProvider) call(method string, params interface{}, result interface{}) error {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	process, err := p.ensureStarted()
+	if err != nil {
+		return err
+	}
+
+	response, err := p.request(process, method, params)
+	if err != nil {
+		return err
+	}
+	p.failures = 0
+	if response.Error != nil {
+		return response.Error
+	}
+	return json.Unmarshal(response.Result, result)
+}
+
+// request sends a request and waits for the response with its ID. A process
+// that exits counts as a crash. One that doesn't answer in time is killed,
+// but a slow backend isn't a broken plugin, so it doesn't count.
+func (p *PluginProvider) request(process *pluginProcess, method string, params interface{}) (rpcResponse, error) {
+	p.nextID++
+	id := p.nextID
+	if err := process.send(rpcRequest{JSONRPC: "2.0", ID: &id, Method: method, Params: params}); err != nil {
+		p.crashed(process)
+		return rpcResponse{}, fmt.Errorf("plugin %s: %s: %w", p.command, method, err)
+	}
+
+	timer := time.NewTimer(p.timeout)
+	defer timer.Stop()
+
+	for {
+		select {
+		case response := <-process.responses:
+			if response.ID != id {
+				continue // Stale response from a call that timed out
+			}
+			return response, nil
+
+		case <-process.done:
+			p.crashed(process)
+			return rpcResponse{}, fmt.Errorf("plugin %s exited during %s", p.command, method)
+
+		case <-timer.C:
+			p.kill(process)
+			return rpcResponse{}, fmt.Errorf("plugin %s: %s timed out after %v", p.command, method, p.timeout)
+		}
+	}
+}
+
+// ensureStarted must be called with the mutex held
+func (p *PluginProvider) ensureStarted() (*pluginProcess, error) {
+	if p.process != nil {
+		select {
+		case <-p.process.done:
+			p.crashed(p.process)
+		default:
+			return p.process, nil
+		}
+	}
+
+	if p.failures > p.maxRestarts {
+		if time.Since(p.lastFailure) < p.cooldown {
+			return nil, fmt.Errorf("plugin %s: %w after %d consecutive failures", p.command, ErrPluginUnavailable, p.failures)
+		}
+		// One more try; another failure starts a new cooldown
+		p.failures = p.maxRestarts
+	}
+
+	process, err := startPlugin(p.command, p.args)
+	if err != nil {
+		p.failed()
+		return nil, err
+	}
+	p.process = process
+
+	// The handshake is an ordinary request so it gets the same timeout and
+	// crash handling
+	response, err := p.request(process, "initialize", map[string]interface{}{"settings": revealSecrets(p.options)})
+	if err != nil {
+		return nil, err
+	}
+	if response.Error != nil {
+		// The plugin refused to start
+		p.failed()
+		p.kill(process)
+		return nil, response.Error
+	}
+
+	return process, nil
+}
+
+// crashed records a process exit and forgets the process so the next call
+// restarts it
+func (p *PluginProvider) crashed(process *pluginProcess) {
+	p.failed()
+	p.kill(process)
+}
+
+func (p *PluginProvider) failed() {
+	p.failures++
+	p.lastFailure = time.Now()
+}
+
+// kill stops a process and forgets it
+func (p *PluginProvider) kill(process *pluginProcess) {
+	process.cmd.Process.Kill()
+	process.cmd.Wait()
+	if p.process == process {
+		p.process = nil
+	}
+}
+
+func startPlugin(command string, args []string) (*pluginProcess, error) {
+	cmd := exec.Command(command, args...)
+
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	stderr, err := cmd.StderrPipe()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("starting plugin %s: %w", command, err)
+	}
+
+	process := &pluginProcess{
+		cmd:       cmd,
+		stdin:     stdin,
+		responses: make(chan rpcResponse, 1),
+		done:      make(chan struct{}),
+	}
+
+	go process.readResponses(stdout)
+	go func() {
+		// Plugins log to stderr
+		scanner := bufio.NewScanner(stderr)
+		for scanner.Scan() {
+			log.Printf("plugin %s: %s", command, scanner.Text())
+		}
+	}()
+
+	return process, nil
+}
+
+func (pp *pluginProcess) readResponses(stdout io.Reader) {
+	defer close(pp.done)
+
+	scanner := bufio.NewScanner(stdout)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		var response rpcResponse
+		if err := json.Unmarshal(scanner.Bytes(), &response); err != nil {
+			log.Printf("plugin %s: ignoring invalid response: %v", pp.cmd.Path, err)
+			continue
+		}
+
+		select {
+		case pp.responses <- response:
+		default:
+			// Nobody is waiting (the call timed out); drop the response
+		}
+	}
+}
+
+func (pp *pluginProcess) send(request rpcRequest) error {
+	data, err := json.Marshal(request)
+	if err != nil {
+		return err
+	}
+	_, err = pp.stdin.Write(append(data, '\n'))
+	return err
+}
+
+func (pp *pluginProcess) notify(method string) {
+	pp.send(rpcRequest{JSONRPC: "2.0", Method: method})
+}
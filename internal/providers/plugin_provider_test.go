@@ -0,0 +1,163 @@
+package providers
+
+import (
+	"errors"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// buildReferencePlugin compiles plugins/reference into a temporary directory
+func buildReferencePlugin(t *testing.T) string {
+	t.Helper()
+
+	binary := filepath.Join(t.TempDir(), "reference-plugin")
+	cmd := exec.Command("go", "build", "-o", binary, "flexpane/plugins/reference")
+	if output, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("Failed to build reference plugin: %v\n%s", err, output)
+	}
+	return binary
+}
+
+func TestPluginProvider_FetchesData(t *testing.T) {
+	binary := buildReferencePlugin(t)
+
+	provider, err := NewProvider("plugin", Settings{"command": binary})
+	if err != nil {
+		t.Fatalf("NewProvider failed: %v", err)
+	}
+	plugin := provider.(*PluginProvider)
+	defer plugin.Close()
+
+	events, err := plugin.GetCalendarEvents()
+	if err != nil {
+		t.Fatalf("GetCalendarEvents failed: %v", err)
+	}
+	if len(events) != 2 || events[0].Title != "Plugin Sync" {
+		t.Errorf("Unexpected events: %+v", events)
+	}
+
+	emails, err := plugin.GetEmails()
+	if err != nil {
+		t.Fatalf("GetEmails failed: %v", err)
+	}
+	if len(emails) != 1 || emails[0].From != "cron@internal" {
+		t.Errorf("Unexpected emails: %+v", emails)
+	}
+}
+
+func TestPluginProvider_RestartsAfterCrash(t *testing.T) {
+	binary := buildReferencePlugin(t)
+
+	plugin := NewPluginProvider(binary, []string{"-exit-after", "1"}, PluginOptions{MaxRestarts: 3})
+	defer plugin.Close()
+
+	// Each process answers one request and then exits
+	for i := 0; i < 3; i++ {
+		if _, err := plugin.GetCalendarEvents(); err != nil {
+			t.Fatalf("Call %d failed: %v", i, err)
+		}
+		// Give the process a moment to exit before the next call
+		time.Sleep(50 * time.Millisecond)
+	}
+}
+
+func TestPluginProvider_Timeout(t *testing.T) {
+	binary := buildReferencePlugin(t)
+
+	plugin := NewPluginProvider(binary, []string{"-delay", "2s"}, PluginOptions{Timeout: 200 * time.Millisecond, MaxRestarts: 1})
+	defer plugin.Close()
+
+	start := time.Now()
+	_, err := plugin.GetEmails()
+	if err == nil || !strings.Contains(err.Error(), "timed out") {
+		t.Fatalf("Expected timeout error, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Expected call to give up quickly, took %v", elapsed)
+	}
+
+	// Timeouts kill the process but don't use up the restart budget
+	plugin.GetEmails()
+	if _, err := plugin.GetEmails(); err == nil || !strings.Contains(err.Error(), "timed out") {
+		t.Errorf("Expected another timeout, got %v", err)
+	}
+}
+
+func TestPluginProvider_CrashBudget(t *testing.T) {
+	plugin := NewPluginProvider("sh", []string{"-c", "exit 1"}, PluginOptions{MaxRestarts: 1})
+	plugin.cooldown = 200 * time.Millisecond
+	defer plugin.Close()
+
+	for i := 0; i < 2; i++ {
+		if _, err := plugin.GetEmails(); err == nil || errors.Is(err, ErrPluginUnavailable) {
+			t.Fatalf("Call %d: expected the plugin to be tried, got %v", i, err)
+		}
+	}
+	if _, err := plugin.GetEmails(); !errors.Is(err, ErrPluginUnavailable) {
+		t.Fatalf("Expected ErrPluginUnavailable, got %v", err)
+	}
+
+	// After the cooldown it gets one more try
+	time.Sleep(250 * time.Millisecond)
+	if _, err := plugin.GetEmails(); err == nil || errors.Is(err, ErrPluginUnavailable) {
+		t.Fatalf("Expected another try after the cooldown, got %v", err)
+	}
+	if _, err := plugin.GetEmails(); !errors.Is(err, ErrPluginUnavailable) {
+		t.Errorf("Expected ErrPluginUnavailable again, got %v", err)
+	}
+}
+
+func TestPluginProvider_InitializeID(t *testing.T) {
+	// Answers initialize with the wrong ID and then hangs
+	script := `read line; echo '{"jsonrpc": "2.0", "id": 99, "result": {}}'; sleep 5`
+	plugin := NewPluginProvider("sh", []string{"-c", script}, PluginOptions{Timeout: 200 * time.Millisecond})
+	defer plugin.Close()
+
+	if _, err := plugin.GetEmails(); err == nil || !strings.Contains(err.Error(), "initialize timed out") {
+		t.Errorf("Expected a response for another request ignored, got %v", err)
+	}
+}
+
+func TestPluginProvider_Close(t *testing.T) {
+	binary := buildReferencePlugin(t)
+
+	plugin := NewPluginProvider(binary, nil, PluginOptions{})
+	if _, err := plugin.GetEmails(); err != nil {
+		t.Fatalf("GetEmails failed: %v", err)
+	}
+	process := plugin.process
+
+	start := time.Now()
+	if err := plugin.Close(); err != nil {
+		t.Errorf("Expected the plugin to exit cleanly on shutdown, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Expected the plugin to exit promptly, took %v", elapsed)
+	}
+	if process.cmd.ProcessState == nil || !process.cmd.ProcessState.Exited() {
+		t.Error("Expected the plugin process to have exited")
+	}
+
+	// Closing provider instances closes the plugin inside a recorder too
+	recorded := NewRecordingProvider(NewPluginProvider(binary, nil, PluginOptions{}), filepath.Join(t.TempDir(), "cassette.json"))
+	if _, err := recorded.GetEmails(); err != nil {
+		t.Fatalf("GetEmails failed: %v", err)
+	}
+	if err := CloseInstances(map[string]DataProvider{"recorded": recorded}); err != nil {
+		t.Errorf("CloseInstances failed: %v", err)
+	}
+	if recorded.inner.(*PluginProvider).process != nil {
+		t.Error("Expected the recorded plugin to be closed")
+	}
+}
+
+func TestPluginProvider_MissingExecutable(t *testing.T) {
+	plugin := NewPluginProvider(filepath.Join(t.TempDir(), "does-not-exist"), nil, PluginOptions{})
+
+	if _, err := plugin.GetCalendarEvents(); err == nil {
+		t.Error("Expected error for missing executable")
+	}
+}
@@ -1,6 +1,10 @@
 package providers
 
-import "flexpane/internal/models"
+import (
+	"errors"
+
+	"flexpane/internal/models"
+)
 
 // DataProvider defines the interface for calendar and email data sources
 type DataProvider interface {
@@ -8,4 +12,50 @@ type DataProvider interface {
 	GetEmails() ([]models.Email, error)
 }
 
-// This interface allows easy swapping between mock and real providers
\ No newline at end of file
+// This interface allows easy swapping between mock and real providers
+
+// CalendarWriter is implemented by providers whose calendar can be edited
+type CalendarWriter interface {
+	CreateEvent(event models.Event) (models.Event, error)
+	UpdateEvent(event models.Event) (models.Event, error)
+	DeleteEvent(id string) error
+}
+
+// ErrEventNotFound is returned when updating or deleting an unknown event
+var ErrEventNotFound = errors.New("event not found")
+
+// ErrEventExists is returned when creating an event with an ID already in use
+var ErrEventExists = errors.New("event already exists")
+
+// ErrEventConflict is returned when an event changed elsewhere since it was
+// loaded, so an update would overwrite that change
+var ErrEventConflict = errors.New("event changed since it was loaded")
+
+// MessageReader is implemented by providers that can return a full message
+// as raw RFC 5322 bytes, for the email reading view
+type MessageReader interface {
+	ReadMessage(id string) ([]byte, error)
+}
+
+// EmailGetter is implemented by providers that can look up one message
+// without listing the whole mailbox
+type EmailGetter interface {
+	GetEmail(id string) (models.Email, error)
+}
+
+// EmailWriter is implemented by providers whose mailbox can be changed.
+// Archived and deleted messages no longer appear in GetEmails.
+type EmailWriter interface {
+	SetRead(id string, read bool) error
+	SetFlagged(id string, flagged bool) error
+	Archive(id string) error
+	DeleteEmail(id string) error
+}
+
+// SentSaver is implemented by providers that can keep a copy of sent mail
+type SentSaver interface {
+	SaveSent(message []byte) error
+}
+
+// ErrEmailNotFound is returned for an unknown message ID
+var ErrEmailNotFound = errors.New("email not found")
@@ -0,0 +1,343 @@
+package providers
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+	"time"
+
+	"flexpane/internal/secrets"
+)
+
+// FieldSchema describes a single provider setting
+type FieldSchema struct {
+	Type        string      `json:"type"` // "string", "secret", "number", "bool", "duration", "list" or "object"
+	Required    bool        `json:"required,omitempty"`
+	Default     interface{} `json:"default,omitempty"`
+	Description string      `json:"description,omitempty"`
+}
+
+// ConfigSchema describes the settings a provider type accepts
+type ConfigSchema map[string]FieldSchema
+
+// Settings holds the configured settings for one provider instance
+type Settings map[string]interface{}
+
+// Constructor builds a provider from validated settings
+type Constructor func(settings Settings) (DataProvider, error)
+
+// Registration is what a provider package registers for its type
+type Registration struct {
+	New    Constructor
+	Schema ConfigSchema
+}
+
+var (
+	registryMutex sync.RWMutex
+	registrations = make(map[string]Registration)
+)
+
+// Register makes a provider type available by name. Provider implementations
+// call it from init, and registering the same type twice panics.
+func Register(providerType string, registration Registration) {
+	registryMutex.Lock()
+	defer registryMutex.Unlock()
+
+	if registration.New == nil {
+		panic("providers: Register constructor is nil for " + providerType)
+	}
+	if _, exists := registrations[providerType]; exists {
+		panic("providers: Register called twice for " + providerType)
+	}
+	registrations[providerType] = registration
+}
+
+// Types returns the registered provider types in sorted order
+func Types() []string {
+	registryMutex.RLock()
+	defer registryMutex.RUnlock()
+
+	types := make([]string, 0, len(registrations))
+	for providerType := range registrations {
+		types = append(types, providerType)
+	}
+	sort.Strings(types)
+	return types
+}
+
+// SchemaFor returns the settings schema of a registered provider type
+func SchemaFor(providerType string) (ConfigSchema, bool) {
+	registryMutex.RLock()
+	defer registryMutex.RUnlock()
+
+	registration, exists := registrations[providerType]
+	return registration.Schema, exists
+}
+
+// NewProvider validates settings against the type's schema and builds the provider
+func NewProvider(providerType string, settings Settings) (DataProvider, error) {
+	registryMutex.RLock()
+	registration, exists := registrations[providerType]
+	registryMutex.RUnlock()
+
+	if !exists {
+		return nil, fmt.Errorf("unsupported provider type: %s", providerType)
+	}
+
+	validated, err := registration.Schema.Validate(settings)
+	if err != nil {
+		return nil, fmt.Errorf("provider type %s: %w", providerType, err)
+	}
+	return registration.New(validated)
+}
+
+// Validate checks settings against the schema and fills in defaults
+func (s ConfigSchema) Validate(settings Settings) (Settings, error) {
+	validated := make(Settings, len(s))
+
+	for key := range settings {
+		if _, known := s[key]; !known {
+			return nil, fmt.Errorf("unknown setting %q", key)
+		}
+	}
+
+	for key, field := range s {
+		value, present := settings[key]
+		if !present || value == nil {
+			if field.Required {
+				return nil, fmt.Errorf("missing required setting %q", key)
+			}
+			if field.Default != nil {
+				validated[key] = field.Default
+			}
+			continue
+		}
+
+		if err := field.check(value); err != nil {
+			return nil, fmt.Errorf("setting %q: %w", key, err)
+		}
+		validated[key] = value
+	}
+
+	return validated, nil
+}
+
+func (f FieldSchema) check(value interface{}) error {
+	switch f.Type {
+	case "string", "secret":
+		switch value.(type) {
+		case string, secrets.Value:
+		default:
+			return fmt.Errorf("expected string, got %T", value)
+		}
+	case "number":
+		switch value.(type) {
+		case float64, int:
+		default:
+			return fmt.Errorf("expected number, got %T", value)
+		}
+	case "bool":
+		if _, ok := value.(bool); !ok {
+			return fmt.Errorf("expected bool, got %T", value)
+		}
+	case "duration":
+		text, ok := value.(string)
+		if !ok {
+			return fmt.Errorf("expected duration string, got %T", value)
+		}
+		if _, err := time.ParseDuration(text); err != nil {
+			return err
+		}
+	case "list":
+		items, ok := value.([]interface{})
+		if !ok {
+			return fmt.Errorf("expected list, got %T", value)
+		}
+		for _, item := range items {
+			if _, ok := item.(string); !ok {
+				return fmt.Errorf("expected list of strings, got %T item", item)
+			}
+		}
+	case "object":
+		if _, ok := value.(map[string]interface{}); !ok {
+			return fmt.Errorf("expected object, got %T", value)
+		}
+	default:
+		return fmt.Errorf("unknown schema type %q", f.Type)
+	}
+	return nil
+}
+
+// String returns a string setting or "" when unset. Secrets are revealed.
+func (s Settings) String(key string) string {
+	switch value := s[key].(type) {
+	case string:
+		return value
+	case secrets.Value:
+		return value.Reveal()
+	}
+	return ""
+}
+
+// Secret returns a setting as a secret, wrapping plain strings
+func (s Settings) Secret(key string) secrets.Value {
+	switch value := s[key].(type) {
+	case secrets.Value:
+		return value
+	case string:
+		return secrets.NewValue(value)
+	}
+	return secrets.Value{}
+}
+
+// Object returns a nested object setting or nil when unset
+func (s Settings) Object(key string) map[string]interface{} {
+	value, _ := s[key].(map[string]interface{})
+	return value
+}
+
+// Bool returns a bool setting or false when unset
+func (s Settings) Bool(key string) bool {
+	value, _ := s[key].(bool)
+	return value
+}
+
+// Int returns a numeric setting as an int or 0 when unset
+func (s Settings) Int(key string) int {
+	switch value := s[key].(type) {
+	case float64:
+		return int(value)
+	case int:
+		return value
+	}
+	return 0
+}
+
+// Strings returns a list setting or nil when unset
+func (s Settings) Strings(key string) []string {
+	items, _ := s[key].([]interface{})
+	values := make([]string, 0, len(items))
+	for _, item := range items {
+		if value, ok := item.(string); ok {
+			values = append(values, value)
+		}
+	}
+	return values
+}
+
+// Duration returns a duration setting or 0 when unset
+func (s Settings) Duration(key string) time.Duration {
+	duration, _ := time.ParseDuration(s.String(key))
+	return duration
+}
+
+// ResolveSecrets replaces secret:// references anywhere in the settings,
+// including nested objects and lists, with values from the store
+func (s Settings) ResolveSecrets(store *secrets.Store) error {
+	for key, value := range s {
+		resolved, err := resolveSecrets(value, store)
+		if err != nil {
+			return fmt.Errorf("setting %q: %w", key, err)
+		}
+		s[key] = resolved
+	}
+	return nil
+}
+
+func resolveSecrets(value interface{}, store *secrets.Store) (interface{}, error) {
+	switch v := value.(type) {
+	case string:
+		if secrets.IsReference(v) {
+			return store.Resolve(v)
+		}
+	case []interface{}:
+		for i, item := range v {
+			resolved, err := resolveSecrets(item, store)
+			if err != nil {
+				return nil, err
+			}
+			v[i] = resolved
+		}
+	case map[string]interface{}:
+		for key, item := range v {
+			resolved, err := resolveSecrets(item, store)
+			if err != nil {
+				return nil, err
+			}
+			v[key] = resolved
+		}
+	}
+	return value, nil
+}
+
+// revealSecrets returns a copy of value with every secret replaced by its
+// plaintext, for handing settings to code outside this process
+func revealSecrets(value interface{}) interface{} {
+	switch v := value.(type) {
+	case secrets.Value:
+		return v.Reveal()
+	case []interface{}:
+		revealed := make([]interface{}, len(v))
+		for i, item := range v {
+			revealed[i] = revealSecrets(item)
+		}
+		return revealed
+	case map[string]interface{}:
+		revealed := make(map[string]interface{}, len(v))
+		for key, item := range v {
+			revealed[key] = revealSecrets(item)
+		}
+		return revealed
+	}
+	return value
+}
+
+// InstanceConfig declares one named provider instance
+type InstanceConfig struct {
+	Type     string   `json:"type"`
+	Settings Settings `json:"settings,omitempty"`
+	Record   string   `json:"record,omitempty"` // Optional cassette path to record responses to
+}
+
+// CreateInstances builds every configured provider instance, keyed by name
+func CreateInstances(configs map[string]InstanceConfig) (map[string]DataProvider, error) {
+	names := make([]string, 0, len(configs))
+	for name := range configs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	instances := make(map[string]DataProvider, len(configs))
+	for _, name := range names {
+		config := configs[name]
+
+		provider, err := NewProvider(config.Type, config.Settings)
+		if err != nil {
+			return nil, fmt.Errorf("provider %q: %w", name, err)
+		}
+		if config.Record != "" {
+			if provider, err = Record(provider, config.Record); err != nil {
+				return nil, fmt.Errorf("provider %q: %w", name, err)
+			}
+		}
+		instances[name] = provider
+	}
+
+	return instances, nil
+}
+
+// CloseInstances releases the providers that hold resources, such as plugin
+// processes. Every instance is closed even if an earlier one fails.
+func CloseInstances(instances map[string]DataProvider) error {
+	var errs []error
+	for name, provider := range instances {
+		if closer, ok := provider.(io.Closer); ok {
+			if err := closer.Close(); err != nil {
+				errs = append(errs, fmt.Errorf("provider %q: %w", name, err))
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
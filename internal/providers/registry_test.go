@@ -0,0 +1,126 @@
+package providers
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"flexpane/internal/models"
+)
+
+func TestConfigSchema_Validate(t *testing.T) {
+	schema := ConfigSchema{
+		"url":      {Type: "string", Required: true},
+		"timeout":  {Type: "duration", Default: "5s"},
+		"insecure": {Type: "bool"},
+	}
+
+	settings, err := schema.Validate(Settings{"url": "https://example.com"})
+	if err != nil {
+		t.Fatalf("Validate failed: %v", err)
+	}
+	if settings.String("url") != "https://example.com" {
+		t.Errorf("Expected url to be kept, got %q", settings.String("url"))
+	}
+	if settings.Duration("timeout") != 5*time.Second {
+		t.Errorf("Expected default timeout 5s, got %v", settings.Duration("timeout"))
+	}
+
+	tests := []struct {
+		name     string
+		settings Settings
+		want     string
+	}{
+		{"missing required", Settings{}, "missing required"},
+		{"wrong type", Settings{"url": "x", "insecure": "yes"}, "expected bool"},
+		{"bad duration", Settings{"url": "x", "timeout": "soon"}, "timeout"},
+		{"unknown key", Settings{"url": "x", "colour": "blue"}, "unknown setting"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := schema.Validate(tt.settings)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Expected error containing %q, got %v", tt.want, err)
+			}
+		})
+	}
+}
+
+func TestCreateInstances(t *testing.T) {
+	instances, err := CreateInstances(map[string]InstanceConfig{
+		"personal": {Type: "mock"},
+		"work":     {Type: "replay", Settings: Settings{"cassette": "../../testdata/cassette.json"}},
+	})
+	if err != nil {
+		t.Fatalf("CreateInstances failed: %v", err)
+	}
+
+	if _, ok := instances["personal"].(*MockProvider); !ok {
+		t.Errorf("Expected personal to be a mock provider, got %T", instances["personal"])
+	}
+	if _, ok := instances["work"].(*ReplayProvider); !ok {
+		t.Errorf("Expected work to be a replay provider, got %T", instances["work"])
+	}
+}
+
+func TestCreateInstances_RecordKeepsCapabilities(t *testing.T) {
+	dir := t.TempDir()
+	instances, err := CreateInstances(map[string]InstanceConfig{
+		"calendar": {
+			Type:     "local",
+			Settings: Settings{"path": filepath.Join(dir, "calendar.json")},
+			Record:   filepath.Join(dir, "calendar-cassette.json"),
+		},
+		"mail": {Type: "mock", Record: filepath.Join(dir, "mail-cassette.json")},
+	})
+	if err != nil {
+		t.Fatalf("CreateInstances failed: %v", err)
+	}
+
+	writer, ok := instances["calendar"].(CalendarWriter)
+	if !ok {
+		t.Fatalf("Expected the recorded calendar to stay writable, got %T", instances["calendar"])
+	}
+	start := time.Date(2026, 3, 2, 9, 0, 0, 0, time.UTC)
+	if _, err := writer.CreateEvent(models.Event{ID: "standup", Title: "Standup", Start: start, End: start.Add(15 * time.Minute)}); err != nil {
+		t.Fatalf("CreateEvent failed: %v", err)
+	}
+	events, err := instances["calendar"].GetCalendarEvents()
+	if err != nil || len(events) != 1 {
+		t.Fatalf("Expected the new event, got %v %v", events, err)
+	}
+	cassette, err := LoadCassette(filepath.Join(dir, "calendar-cassette.json"))
+	if err != nil || len(cassette.Events) != 1 {
+		t.Errorf("Expected the read to be recorded, got %v %v", cassette, err)
+	}
+
+	mail := instances["mail"]
+	if _, ok := mail.(EmailWriter); !ok {
+		t.Errorf("Expected the recorded mailbox to stay writable, got %T", mail)
+	}
+	if _, ok := mail.(MessageReader); !ok {
+		t.Errorf("Expected the recorded mailbox to read messages, got %T", mail)
+	}
+	if _, ok := mail.(SentSaver); ok {
+		t.Error("Expected no sent folder where the provider has none")
+	}
+}
+
+func TestCreateInstances_UnknownType(t *testing.T) {
+	_, err := CreateInstances(map[string]InstanceConfig{
+		"broken": {Type: "carrier-pigeon"},
+	})
+	if err == nil || !strings.Contains(err.Error(), `provider "broken"`) {
+		t.Errorf("Expected error naming the instance, got %v", err)
+	}
+}
+
+func TestRegister_Duplicate(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("Expected panic when registering mock twice")
+		}
+	}()
+	Register("mock", Registration{New: func(Settings) (DataProvider, error) { return NewMockProvider(), nil }})
+}
@@ -0,0 +1,236 @@
+package providers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"flexpane/internal/models"
+)
+
+// DefaultCassettePath is where the replay provider looks for a cassette when
+// FLEXPANE_CASSETTE is not set
+const DefaultCassettePath = "data/cassette.json"
+
+func init() {
+	Register("replay", Registration{
+		New: func(settings Settings) (DataProvider, error) {
+			path := settings.String("cassette")
+			if path == "" {
+				path = cassettePath()
+			}
+			return NewReplayProvider(path)
+		},
+		Schema: ConfigSchema{
+			"cassette": {Type: "string", Description: "Path to the cassette file (defaults to FLEXPANE_CASSETTE or " + DefaultCassettePath + ")"},
+		},
+	})
+}
+
+// Cassette is a recorded snapshot of provider responses
+type Cassette struct {
+	RecordedAt time.Time      `json:"recorded_at"`
+	Events     []models.Event `json:"events"`
+	Emails     []models.Email `json:"emails"`
+}
+
+// LoadCassette reads a cassette from disk
+func LoadCassette(path string) (*Cassette, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var cassette Cassette
+	if err := json.Unmarshal(data, &cassette); err != nil {
+		return nil, fmt.Errorf("invalid cassette %s: %w", path, err)
+	}
+	return &cassette, nil
+}
+
+// Save writes the cassette to disk, creating the parent directory if needed
+func (c *Cassette) Save(path string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0644)
+}
+
+// RecordingProvider wraps another provider and writes every successful
+// response to a cassette file
+type RecordingProvider struct {
+	inner    DataProvider
+	path     string
+	cassette Cassette
+	mutex    sync.Mutex
+	now      func() time.Time
+}
+
+func NewRecordingProvider(inner DataProvider, path string) *RecordingProvider {
+	return &RecordingProvider{
+		inner: inner,
+		path:  path,
+		now:   time.Now,
+	}
+}
+
+func (r *RecordingProvider) GetCalendarEvents() ([]models.Event, error) {
+	events, err := r.inner.GetCalendarEvents()
+	if err != nil {
+		return events, err
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.cassette.Events = events
+	return events, r.save()
+}
+
+func (r *RecordingProvider) GetEmails() ([]models.Email, error) {
+	emails, err := r.inner.GetEmails()
+	if err != nil {
+		return emails, err
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.cassette.Emails = emails
+	return emails, r.save()
+}
+
+// Close closes the wrapped provider if it holds resources
+func (r *RecordingProvider) Close() error {
+	if closer, ok := r.inner.(io.Closer); ok {
+		return closer.Close()
+	}
+	return nil
+}
+
+// mailbox is what a provider needs to read and change mail
+type mailbox interface {
+	MessageReader
+	EmailGetter
+	EmailWriter
+}
+
+// recordingCalendar records an editable calendar and keeps it editable
+type recordingCalendar struct {
+	*RecordingProvider
+	CalendarWriter
+}
+
+// recordingMailbox records a mailbox and keeps reading and changing mail
+type recordingMailbox struct {
+	*RecordingProvider
+	mailbox
+}
+
+// recordingSentMailbox is a recordingMailbox that also keeps sent mail
+type recordingSentMailbox struct {
+	recordingMailbox
+	SentSaver
+}
+
+// Record wraps provider in a RecordingProvider that still offers the
+// optional capabilities provider has, so panes can go on editing and
+// reading through it. Only reads are recorded. A provider whose mix of
+// capabilities can't be passed through is refused rather than quietly made
+// read-only.
+func Record(provider DataProvider, path string) (DataProvider, error) {
+	recorder := NewRecordingProvider(provider, path)
+	calendar, writesCalendar := provider.(CalendarWriter)
+	mail, readsMail := provider.(mailbox)
+	saver, savesSent := provider.(SentSaver)
+	partialMail := !readsMail && hasMailCapability(provider)
+
+	switch {
+	case partialMail || (writesCalendar && readsMail):
+		return nil, errors.New("recording is not supported for this provider")
+	case writesCalendar:
+		return recordingCalendar{recorder, calendar}, nil
+	case readsMail && savesSent:
+		return recordingSentMailbox{recordingMailbox{recorder, mail}, saver}, nil
+	case readsMail:
+		return recordingMailbox{recorder, mail}, nil
+	default:
+		return recorder, nil
+	}
+}
+
+func hasMailCapability(provider DataProvider) bool {
+	switch provider.(type) {
+	case MessageReader, EmailGetter, EmailWriter, SentSaver:
+		return true
+	}
+	return false
+}
+
+// save must be called with the mutex held
+func (r *RecordingProvider) save() error {
+	r.cassette.RecordedAt = r.now()
+	return r.cassette.Save(r.path)
+}
+
+// ReplayProvider serves responses from a cassette, shifting every timestamp
+// so the recording appears to have been made just now
+type ReplayProvider struct {
+	cassette *Cassette
+	now      func() time.Time
+}
+
+func NewReplayProvider(path string) (*ReplayProvider, error) {
+	cassette, err := LoadCassette(path)
+	if err != nil {
+		return nil, err
+	}
+	return NewReplayProviderFromCassette(cassette), nil
+}
+
+func NewReplayProviderFromCassette(cassette *Cassette) *ReplayProvider {
+	return &ReplayProvider{
+		cassette: cassette,
+		now:      time.Now,
+	}
+}
+
+func (r *ReplayProvider) offset() time.Duration {
+	return r.now().Sub(r.cassette.RecordedAt)
+}
+
+func (r *ReplayProvider) GetCalendarEvents() ([]models.Event, error) {
+	shift := r.offset()
+	events := make([]models.Event, len(r.cassette.Events))
+	for i, event := range r.cassette.Events {
+		event.Start = event.Start.Add(shift)
+		event.End = event.End.Add(shift)
+		events[i] = event
+	}
+	return events, nil
+}
+
+func (r *ReplayProvider) GetEmails() ([]models.Email, error) {
+	shift := r.offset()
+	emails := make([]models.Email, len(r.cassette.Emails))
+	for i, email := range r.cassette.Emails {
+		email.Time = email.Time.Add(shift)
+		emails[i] = email
+	}
+	return emails, nil
+}
+
+func cassettePath() string {
+	if path := os.Getenv("FLEXPANE_CASSETTE"); path != "" {
+		return path
+	}
+	return DefaultCassettePath
+}
@@ -0,0 +1,83 @@
+package providers
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestRecordingProvider_ReplayShiftsToNow(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cassette.json")
+
+	recordedAt := time.Date(2025, 1, 6, 8, 0, 0, 0, time.UTC)
+	recorder := NewRecordingProvider(NewMockProvider(), path)
+	recorder.now = func() time.Time { return recordedAt }
+
+	recorded, err := recorder.GetCalendarEvents()
+	if err != nil {
+		t.Fatalf("GetCalendarEvents failed: %v", err)
+	}
+	if _, err := recorder.GetEmails(); err != nil {
+		t.Fatalf("GetEmails failed: %v", err)
+	}
+
+	replay, err := NewReplayProvider(path)
+	if err != nil {
+		t.Fatalf("NewReplayProvider failed: %v", err)
+	}
+	replayNow := recordedAt.Add(48 * time.Hour)
+	replay.now = func() time.Time { return replayNow }
+
+	events, err := replay.GetCalendarEvents()
+	if err != nil {
+		t.Fatalf("replay GetCalendarEvents failed: %v", err)
+	}
+	if len(events) != len(recorded) {
+		t.Fatalf("Expected %d events, got %d", len(recorded), len(events))
+	}
+	for i := range events {
+		if want := recorded[i].Start.Add(48 * time.Hour); !events[i].Start.Equal(want) {
+			t.Errorf("Event %d: expected start %v, got %v", i, want, events[i].Start)
+		}
+		if events[i].End.Sub(events[i].Start) != recorded[i].End.Sub(recorded[i].Start) {
+			t.Errorf("Event %d: duration changed during replay", i)
+		}
+	}
+
+	emails, err := replay.GetEmails()
+	if err != nil {
+		t.Fatalf("replay GetEmails failed: %v", err)
+	}
+	if len(emails) != 4 {
+		t.Errorf("Expected 4 emails, got %d", len(emails))
+	}
+}
+
+func TestCreateProvider_Replay(t *testing.T) {
+	t.Setenv("FLEXPANE_CASSETTE", "../../testdata/cassette.json")
+
+	provider, err := CreateProvider("replay")
+	if err != nil {
+		t.Fatalf("CreateProvider(replay) failed: %v", err)
+	}
+
+	events, err := provider.GetCalendarEvents()
+	if err != nil {
+		t.Fatalf("GetCalendarEvents failed: %v", err)
+	}
+	if len(events) != 3 || events[0].Title != "Team Standup" {
+		t.Errorf("Unexpected replayed events: %+v", events)
+	}
+	// Cassette was recorded an hour before the first event
+	if until := time.Until(events[0].Start); until < 59*time.Minute || until > 61*time.Minute {
+		t.Errorf("Expected first event about an hour from now, got %v", until)
+	}
+}
+
+func TestCreateProvider_ReplayMissingCassette(t *testing.T) {
+	t.Setenv("FLEXPANE_CASSETTE", filepath.Join(t.TempDir(), "missing.json"))
+
+	if _, err := CreateProvider("replay"); err == nil {
+		t.Error("Expected error for missing cassette")
+	}
+}
@@ -0,0 +1,126 @@
+package secrets
+
+import (
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// minRedactLength avoids redacting trivially short strings that would mangle
+// unrelated output
+const minRedactLength = 4
+
+// Redactor replaces known secret values in text
+type Redactor struct {
+	mutex    sync.RWMutex
+	values   map[string]struct{}
+	replacer *strings.Replacer
+}
+
+func NewRedactor() *Redactor {
+	return &Redactor{values: make(map[string]struct{})}
+}
+
+var defaultRedactor = NewRedactor()
+
+// Add registers a secret value to be redacted
+func (r *Redactor) Add(value string) {
+	if len(value) < minRedactLength {
+		return
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if _, exists := r.values[value]; exists {
+		return
+	}
+	r.values[value] = struct{}{}
+
+	pairs := make([]string, 0, len(r.values)*2)
+	for known := range r.values {
+		pairs = append(pairs, known, Redacted)
+	}
+	r.replacer = strings.NewReplacer(pairs...)
+}
+
+// Redact replaces every registered secret in s
+func (r *Redactor) Redact(s string) string {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	if r.replacer == nil {
+		return s
+	}
+	return r.replacer.Replace(s)
+}
+
+// Redact replaces every secret handed out by this package in s
+func Redact(s string) string {
+	return defaultRedactor.Redact(s)
+}
+
+// redactingWriter redacts each write before passing it on. The log package
+// issues one write per entry, so secrets are never split across writes there.
+type redactingWriter struct {
+	w        io.Writer
+	redactor *Redactor
+}
+
+// RedactingWriter wraps w so secrets are removed from everything written,
+// e.g. log.SetOutput(secrets.RedactingWriter(os.Stderr))
+func RedactingWriter(w io.Writer) io.Writer {
+	return &redactingWriter{w: w, redactor: defaultRedactor}
+}
+
+func (rw *redactingWriter) Write(p []byte) (int, error) {
+	if _, err := io.WriteString(rw.w, rw.redactor.Redact(string(p))); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// RedactHandler removes secrets from HTTP response bodies
+func RedactHandler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		next.ServeHTTP(&redactingResponseWriter{ResponseWriter: w, redactor: defaultRedactor}, r)
+	})
+}
+
+type redactingResponseWriter struct {
+	http.ResponseWriter
+	redactor    *Redactor
+	wroteHeader bool
+}
+
+// WriteHeader drops Content-Length since redaction can change the body length
+func (rw *redactingResponseWriter) WriteHeader(status int) {
+	if !rw.wroteHeader {
+		rw.wroteHeader = true
+		rw.Header().Del("Content-Length")
+	}
+	rw.ResponseWriter.WriteHeader(status)
+}
+
+func (rw *redactingResponseWriter) Write(p []byte) (int, error) {
+	if !rw.wroteHeader {
+		rw.WriteHeader(http.StatusOK)
+	}
+	if _, err := io.WriteString(rw.ResponseWriter, rw.redactor.Redact(string(p))); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// Flush keeps streaming responses working through the wrapper
+func (rw *redactingResponseWriter) Flush() {
+	if flusher, ok := rw.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer
+func (rw *redactingResponseWriter) Unwrap() http.ResponseWriter {
+	return rw.ResponseWriter
+}
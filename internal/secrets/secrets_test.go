@@ -0,0 +1,130 @@
+package secrets
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestStore_RoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "secrets.enc")
+
+	store, err := Open(path, []byte("correct horse"))
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	if err := store.Set("imap-password", "hunter2-imap"); err != nil {
+		t.Fatalf("Set failed: %v", err)
+	}
+
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Failed to read secrets file: %v", err)
+	}
+	if bytes.Contains(raw, []byte("hunter2-imap")) {
+		t.Error("Secrets file contains the plaintext value")
+	}
+
+	reopened, err := Open(path, []byte("correct horse"))
+	if err != nil {
+		t.Fatalf("Reopen failed: %v", err)
+	}
+	value, err := reopened.Resolve("secret://imap-password")
+	if err != nil {
+		t.Fatalf("Resolve failed: %v", err)
+	}
+	if value.Reveal() != "hunter2-imap" {
+		t.Errorf("Expected stored value, got %q", value.Reveal())
+	}
+}
+
+func TestStore_WrongPassphrase(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "secrets.enc")
+
+	store, _ := Open(path, []byte("right"))
+	if err := store.Set("token", "abc123"); err != nil {
+		t.Fatalf("Set failed: %v", err)
+	}
+
+	if _, err := Open(path, []byte("wrong")); err == nil {
+		t.Error("Expected error with the wrong passphrase")
+	}
+	if _, err := Open(path, nil); !errors.Is(err, ErrNoKey) {
+		t.Errorf("Expected ErrNoKey without a passphrase, got %v", err)
+	}
+}
+
+func TestStore_EnvironmentOverride(t *testing.T) {
+	store, err := Open(filepath.Join(t.TempDir(), "missing.enc"), nil)
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+
+	if _, err := store.Resolve("secret://oauth.refresh-token"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound, got %v", err)
+	}
+
+	t.Setenv("FLEXPANE_SECRET_OAUTH_REFRESH_TOKEN", "from-env-token")
+	value, err := store.Resolve("secret://oauth.refresh-token")
+	if err != nil {
+		t.Fatalf("Resolve failed: %v", err)
+	}
+	if value.Reveal() != "from-env-token" {
+		t.Errorf("Expected environment value, got %q", value.Reveal())
+	}
+}
+
+func TestValue_NeverPrintsPlaintext(t *testing.T) {
+	value := NewValue("super-secret-value")
+
+	data, _ := json.Marshal(map[string]interface{}{"password": value})
+	printed := fmt.Sprintf("%v %s %+v %#v", value, value, value, value)
+
+	for _, output := range []string{string(data), printed} {
+		if strings.Contains(output, "super-secret-value") {
+			t.Errorf("Plaintext leaked: %s", output)
+		}
+	}
+}
+
+func TestRedactingWriter(t *testing.T) {
+	NewValue("log-secret-123")
+
+	var buf bytes.Buffer
+	logger := log.New(RedactingWriter(&buf), "", 0)
+	logger.Printf("connecting with password=%s", "log-secret-123")
+
+	if strings.Contains(buf.String(), "log-secret-123") {
+		t.Errorf("Secret leaked into log: %s", buf.String())
+	}
+	if !strings.Contains(buf.String(), Redacted) {
+		t.Errorf("Expected redaction marker in log: %s", buf.String())
+	}
+}
+
+func TestRedactHandler(t *testing.T) {
+	NewValue("api-secret-456")
+
+	handler := RedactHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Length", "25")
+		w.Write([]byte(`{"error":"api-secret-456"}`))
+	}))
+
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest("GET", "/", nil))
+
+	if strings.Contains(recorder.Body.String(), "api-secret-456") {
+		t.Errorf("Secret leaked into response: %s", recorder.Body.String())
+	}
+	if recorder.Header().Get("Content-Length") != "" {
+		t.Error("Expected Content-Length to be dropped")
+	}
+}
@@ -0,0 +1,244 @@
+package secrets
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/pbkdf2"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+const (
+	fileVersion = 1
+	kdfName     = "pbkdf2-sha256"
+	iterations  = 600000
+	saltSize    = 16
+	keySize     = 32
+)
+
+// ErrNotFound is returned when a secret reference can't be resolved
+var ErrNotFound = errors.New("secret not found")
+
+// ErrNoKey is returned when an encrypted file exists but no passphrase or key
+// file was configured
+var ErrNoKey = errors.New("secrets file is encrypted but no passphrase or key file is configured")
+
+// sealedFile is the on-disk format. Everything except the KDF parameters is
+// encrypted with AES-256-GCM.
+type sealedFile struct {
+	Version    int    `json:"version"`
+	KDF        string `json:"kdf"`
+	Iterations int    `json:"iterations"`
+	Salt       []byte `json:"salt"`
+	Nonce      []byte `json:"nonce"`
+	Ciphertext []byte `json:"ciphertext"`
+}
+
+// Store holds named secrets, encrypted at rest in a local file. Environment
+// variables of the form FLEXPANE_SECRET_<NAME> override stored values.
+type Store struct {
+	path       string
+	passphrase []byte
+	secrets    map[string]string
+	mutex      sync.RWMutex
+	getenv     func(string) string
+}
+
+// Open loads the secrets file at path. A missing file yields an empty store.
+// The passphrase may be nil when only environment overrides are used.
+func Open(path string, passphrase []byte) (*Store, error) {
+	store := &Store{
+		path:       path,
+		passphrase: passphrase,
+		secrets:    make(map[string]string),
+		getenv:     os.Getenv,
+	}
+
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) || path == "" {
+		return store, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(passphrase) == 0 {
+		return nil, ErrNoKey
+	}
+
+	var sealed sealedFile
+	if err := json.Unmarshal(data, &sealed); err != nil {
+		return nil, fmt.Errorf("invalid secrets file %s: %w", path, err)
+	}
+	if sealed.Version != fileVersion || sealed.KDF != kdfName {
+		return nil, fmt.Errorf("unsupported secrets file %s (version %d, kdf %q)", path, sealed.Version, sealed.KDF)
+	}
+
+	gcm, err := newGCM(passphrase, sealed.Salt, sealed.Iterations)
+	if err != nil {
+		return nil, err
+	}
+	plaintext, err := gcm.Open(nil, sealed.Nonce, sealed.Ciphertext, nil)
+	if err != nil {
+		return nil, fmt.Errorf("decrypting %s: wrong passphrase or corrupted file", path)
+	}
+
+	if err := json.Unmarshal(plaintext, &store.secrets); err != nil {
+		return nil, fmt.Errorf("invalid secrets payload in %s: %w", path, err)
+	}
+	for _, value := range store.secrets {
+		defaultRedactor.Add(value)
+	}
+
+	return store, nil
+}
+
+// LoadKey returns the passphrase from FLEXPANE_SECRETS_PASSPHRASE, or the
+// contents of keyFile when it is set
+func LoadKey(keyFile string) ([]byte, error) {
+	if passphrase := os.Getenv("FLEXPANE_SECRETS_PASSPHRASE"); passphrase != "" {
+		return []byte(passphrase), nil
+	}
+	if keyFile == "" {
+		return nil, nil
+	}
+
+	key, err := os.ReadFile(keyFile)
+	if err != nil {
+		return nil, fmt.Errorf("reading key file: %w", err)
+	}
+	return []byte(strings.TrimSpace(string(key))), nil
+}
+
+// EnvName returns the environment variable that overrides a secret
+func EnvName(name string) string {
+	var b strings.Builder
+	b.WriteString("FLEXPANE_SECRET_")
+	for _, r := range strings.ToUpper(name) {
+		if (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') {
+			b.WriteRune(r)
+		} else {
+			b.WriteByte('_')
+		}
+	}
+	return b.String()
+}
+
+// Get returns a secret by name, preferring an environment override
+func (s *Store) Get(name string) (Value, bool) {
+	if value := s.getenv(EnvName(name)); value != "" {
+		return NewValue(value), true
+	}
+
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	value, exists := s.secrets[name]
+	if !exists {
+		return Value{}, false
+	}
+	return NewValue(value), true
+}
+
+// Resolve looks up a secret:// reference
+func (s *Store) Resolve(reference string) (Value, error) {
+	name := ReferenceName(reference)
+	value, exists := s.Get(name)
+	if !exists {
+		return Value{}, fmt.Errorf("%w: %s (set %s or add it to the secrets file)", ErrNotFound, name, EnvName(name))
+	}
+	return value, nil
+}
+
+// Names returns the stored secret names in sorted order
+func (s *Store) Names() []string {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	names := make([]string, 0, len(s.secrets))
+	for name := range s.secrets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Set stores a secret and writes the file
+func (s *Store) Set(name, value string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.secrets[name] = value
+	defaultRedactor.Add(value)
+	return s.save()
+}
+
+// Delete removes a secret and writes the file
+func (s *Store) Delete(name string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	delete(s.secrets, name)
+	return s.save()
+}
+
+// save must be called with the mutex held
+func (s *Store) save() error {
+	if len(s.passphrase) == 0 {
+		return ErrNoKey
+	}
+
+	plaintext, err := json.Marshal(s.secrets)
+	if err != nil {
+		return err
+	}
+
+	salt := make([]byte, saltSize)
+	if _, err := rand.Read(salt); err != nil {
+		return err
+	}
+	gcm, err := newGCM(s.passphrase, salt, iterations)
+	if err != nil {
+		return err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(sealedFile{
+		Version:    fileVersion,
+		KDF:        kdfName,
+		Iterations: iterations,
+		Salt:       salt,
+		Nonce:      nonce,
+		Ciphertext: gcm.Seal(nil, nonce, plaintext, nil),
+	}, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(s.path), 0700); err != nil {
+		return err
+	}
+	return os.WriteFile(s.path, data, 0600)
+}
+
+func newGCM(passphrase, salt []byte, iterations int) (cipher.AEAD, error) {
+	key, err := pbkdf2.Key(sha256.New, string(passphrase), salt, iterations, keySize)
+	if err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
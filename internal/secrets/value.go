@@ -0,0 +1,56 @@
+package secrets
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+// Redacted is what a secret looks like in logs and API output
+const Redacted = "[REDACTED]"
+
+// ReferencePrefix marks a config value that names a secret, e.g. secret://imap-password
+const ReferencePrefix = "secret://"
+
+// Value wraps a secret so it can't end up in logs or JSON by accident. Use
+// Reveal at the point the plaintext is actually needed.
+type Value struct {
+	plaintext string
+}
+
+// NewValue wraps plaintext and registers it for redaction
+func NewValue(plaintext string) Value {
+	defaultRedactor.Add(plaintext)
+	return Value{plaintext: plaintext}
+}
+
+// Reveal returns the plaintext secret
+func (v Value) Reveal() string {
+	return v.plaintext
+}
+
+// IsZero reports whether the secret is empty
+func (v Value) IsZero() bool {
+	return v.plaintext == ""
+}
+
+func (v Value) String() string {
+	return Redacted
+}
+
+func (v Value) GoString() string {
+	return Redacted
+}
+
+func (v Value) MarshalJSON() ([]byte, error) {
+	return json.Marshal(Redacted)
+}
+
+// IsReference reports whether a config string refers to a stored secret
+func IsReference(s string) bool {
+	return strings.HasPrefix(s, ReferencePrefix)
+}
+
+// ReferenceName extracts the secret name from a secret:// reference
+func ReferenceName(s string) string {
+	return strings.TrimPrefix(s, ReferencePrefix)
+}
@@ -0,0 +1,366 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	netmail "net/mail"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"flexpane/internal/models"
+)
+
+// EmailRule matches incoming mail and acts on it. Every condition that is set
+// must match; matching is a case-insensitive substring test. Headers maps
+// header names to the text their value must contain.
+type EmailRule struct {
+	Name    string            `json:"name,omitempty"`
+	From    string            `json:"from,omitempty"`
+	Subject string            `json:"subject,omitempty"`
+	Headers map[string]string `json:"headers,omitempty"`
+
+	Label    string   `json:"label,omitempty"`     // Shown next to the message
+	MarkRead bool     `json:"mark_read,omitempty"` // Needs a writable provider
+	Hide     bool     `json:"hide,omitempty"`      // Leave out of the pane
+	Todo     bool     `json:"todo,omitempty"`      // Create a todo for the message
+	Notify   []string `json:"notify,omitempty"`    // Reminder channels to alert
+}
+
+// Validate checks the rule has something to match and something to do
+func (r EmailRule) Validate() error {
+	if r.From == "" && r.Subject == "" && len(r.Headers) == 0 {
+		return errors.New("email rule needs from, subject or headers")
+	}
+	if r.Label == "" && !r.MarkRead && !r.Hide && !r.Todo && len(r.Notify) == 0 {
+		return errors.New("email rule needs at least one action")
+	}
+	return nil
+}
+
+// DisplayName names the rule in dry-run results
+func (r EmailRule) DisplayName(index int) string {
+	if r.Name != "" {
+		return r.Name
+	}
+	return fmt.Sprintf("rule %d", index+1)
+}
+
+// RuleMatch is what the rules do to one message
+type RuleMatch struct {
+	EmailID  string   `json:"email_id"`
+	Subject  string   `json:"subject"`
+	From     string   `json:"from"`
+	Rules    []string `json:"rules"`
+	Labels   []string `json:"labels,omitempty"`
+	MarkRead bool     `json:"mark_read,omitempty"`
+	Hide     bool     `json:"hide,omitempty"`
+	Todo     bool     `json:"todo,omitempty"`
+	Notify   []string `json:"notify,omitempty"`
+}
+
+// EmailSource is a provider of email messages
+type EmailSource interface {
+	GetEmails() ([]models.Email, error)
+}
+
+// Optional provider capabilities the rules use when present
+type (
+	messageSource interface {
+		ReadMessage(id string) ([]byte, error)
+	}
+	readMarker interface {
+		SetRead(id string, read bool) error
+	}
+)
+
+// EmailRules evaluates rules against the mailbox. Labels and hiding apply
+// every time messages are listed. Marking read, todos and notifications run
+// from the background check, once per message, and only for messages that
+// arrive after the first check,
+// so starting Flexpane doesn't act on the whole existing inbox. New messages
+// that aren't hidden are announced as EventEmailNew, so with no rules at all
+// it still watches for new mail.
+type EmailRules struct {
+	source   EmailSource
+	rules    []EmailRule
+	todos    *TodoService
+	channels map[string]Notifier
+	events   *EventBus
+
+	mutex   sync.Mutex
+	primed  bool
+	seen    map[string]bool
+	headers map[string]netmail.Header // Cached by message ID
+}
+
+func NewEmailRules(source EmailSource, rules []EmailRule, todos *TodoService, channels map[string]Notifier) *EmailRules {
+	return &EmailRules{
+		source:   source,
+		rules:    rules,
+		todos:    todos,
+		channels: channels,
+		seen:     map[string]bool{},
+		headers:  map[string]netmail.Header{},
+	}
+}
+
+// SetEvents sets where new mail is announced
+func (s *EmailRules) SetEvents(events *EventBus) {
+	s.events = events
+}
+
+// Run checks for new mail every interval until ctx is cancelled, so rules
+// act even when no browser has the pane open
+func (s *EmailRules) Run(ctx context.Context, interval time.Duration) {
+	if interval <= 0 {
+		interval = DefaultReminderInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if err := s.Check(ctx); err != nil {
+			log.Printf("email rules: %v", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Check fetches the mailbox and runs the rules' actions on new messages.
+// This is the only place actions run, so a slow notifier holds up the
+// background check rather than a page load. A message counts as handled
+// once its actions have run; one not reached before ctx is cancelled is
+// tried again on the next check.
+func (s *EmailRules) Check(ctx context.Context) error {
+	emails, err := s.source.GetEmails()
+	if err != nil {
+		return err
+	}
+	matches := s.evaluate(emails)
+
+	s.mutex.Lock()
+	primed := s.primed
+	s.primed = true
+	var fresh []int
+	current := make(map[string]bool, len(emails))
+	for i, email := range emails {
+		current[email.ID] = true
+		if s.seen[email.ID] {
+			continue
+		}
+		if primed {
+			fresh = append(fresh, i)
+		} else {
+			s.seen[email.ID] = true
+		}
+	}
+	// Forget messages that have left the mailbox
+	for id := range s.seen {
+		if !current[id] {
+			delete(s.seen, id)
+			delete(s.headers, id)
+		}
+	}
+	s.mutex.Unlock()
+
+	announce := false
+	for _, i := range fresh {
+		if ctx.Err() != nil {
+			break
+		}
+		if matches[i] != nil {
+			s.act(ctx, &emails[i], matches[i])
+		}
+		s.mutex.Lock()
+		s.seen[emails[i].ID] = true
+		s.mutex.Unlock()
+		announce = announce || matches[i] == nil || !matches[i].Hide
+	}
+	if announce {
+		s.events.Publish(EventEmailNew, "email")
+	}
+	return nil
+}
+
+// Label returns a listing with labels added and hidden messages removed. It
+// changes nothing, so it's safe to call on every page load.
+func (s *EmailRules) Label(emails []models.Email) []models.Email {
+	matches := s.evaluate(emails)
+
+	visible := make([]models.Email, 0, len(emails))
+	for i, email := range emails {
+		if match := matches[i]; match != nil {
+			if match.Hide {
+				continue
+			}
+			email.Labels = match.Labels
+		}
+		visible = append(visible, email)
+	}
+	return visible
+}
+
+// DryRun reports which messages currently in the mailbox the rules would
+// match, without changing anything
+func (s *EmailRules) DryRun() ([]RuleMatch, error) {
+	emails, err := s.source.GetEmails()
+	if err != nil {
+		return nil, err
+	}
+	results := []RuleMatch{}
+	for _, match := range s.evaluate(emails) {
+		if match != nil {
+			results = append(results, *match)
+		}
+	}
+	return results, nil
+}
+
+// evaluate returns the combined match for each email, nil where no rule
+// matched
+func (s *EmailRules) evaluate(emails []models.Email) []*RuleMatch {
+	matches := make([]*RuleMatch, len(emails))
+	for i, email := range emails {
+		for index, rule := range s.rules {
+			if !s.matches(rule, email) {
+				continue
+			}
+			match := matches[i]
+			if match == nil {
+				match = &RuleMatch{EmailID: email.ID, Subject: email.Subject, From: email.From}
+				matches[i] = match
+			}
+			match.Rules = append(match.Rules, rule.DisplayName(index))
+			if rule.Label != "" {
+				match.Labels = appendUnique(match.Labels, rule.Label)
+			}
+			match.MarkRead = match.MarkRead || rule.MarkRead
+			match.Hide = match.Hide || rule.Hide
+			match.Todo = match.Todo || rule.Todo
+			for _, channel := range rule.Notify {
+				match.Notify = appendUnique(match.Notify, channel)
+			}
+		}
+	}
+	return matches
+}
+
+func (s *EmailRules) matches(rule EmailRule, email models.Email) bool {
+	if rule.From != "" && !containsFold(email.From, rule.From) {
+		return false
+	}
+	if rule.Subject != "" && !containsFold(email.Subject, rule.Subject) {
+		return false
+	}
+	if len(rule.Headers) == 0 {
+		return true
+	}
+
+	header := s.header(email.ID)
+	if header == nil {
+		return false
+	}
+	for name, want := range rule.Headers {
+		if !containsFold(header.Get(name), want) {
+			return false
+		}
+	}
+	return true
+}
+
+// header loads a message's headers through the provider, which must be able
+// to return full messages for header rules to match
+func (s *EmailRules) header(id string) netmail.Header {
+	s.mutex.Lock()
+	header, cached := s.headers[id]
+	s.mutex.Unlock()
+	if cached {
+		return header
+	}
+
+	reader, ok := s.source.(messageSource)
+	if !ok {
+		return nil
+	}
+	raw, err := reader.ReadMessage(id)
+	if err != nil {
+		log.Printf("email rules: reading %s: %v", id, err)
+		return nil
+	}
+	msg, err := netmail.ReadMessage(bytes.NewReader(raw))
+	if err != nil {
+		log.Printf("email rules: parsing %s: %v", id, err)
+		return nil
+	}
+
+	s.mutex.Lock()
+	s.headers[id] = msg.Header
+	s.mutex.Unlock()
+	return msg.Header
+}
+
+// act runs the once-per-message actions
+func (s *EmailRules) act(ctx context.Context, email *models.Email, match *RuleMatch) {
+	if match.MarkRead && !email.Read {
+		if marker, ok := s.source.(readMarker); ok {
+			if err := marker.SetRead(email.ID, true); err != nil {
+				log.Printf("email rules: marking %s read: %v", email.ID, err)
+			} else {
+				email.Read = true
+			}
+		}
+	}
+
+	if match.Todo && s.todos != nil {
+		if _, err := s.todos.Add(TodoFromEmail(*email)); err != nil {
+			log.Printf("email rules: todo for %s: %v", email.ID, err)
+		}
+	}
+
+	if len(match.Notify) > 0 {
+		notification := Notification{
+			ID:    "email:" + email.ID,
+			Title: email.Subject,
+			Body:  "From " + email.From,
+			Time:  email.Time,
+			URL:   "/?email.message=" + url.QueryEscape(email.ID),
+		}
+		for _, name := range match.Notify {
+			channel, exists := s.channels[name]
+			if !exists {
+				log.Printf("email rules: unknown channel %q", name)
+				continue
+			}
+			if err := channel.Notify(ctx, notification); err != nil && !errors.Is(err, ErrNoRecipients) {
+				log.Printf("email rules: %s: %v", name, err)
+			}
+		}
+	}
+}
+
+// TodoFromEmail makes a todo for a message, with the subject as its text and
+// the sender in its notes
+func TodoFromEmail(email models.Email) models.Todo {
+	subject := email.Subject
+	if subject == "" {
+		subject = "(no subject)"
+	}
+	return models.Todo{
+		Message: subject,
+		Notes:   "From " + email.From,
+		EmailID: email.ID,
+	}
+}
+
+func containsFold(s, substr string) bool {
+	return strings.Contains(strings.ToLower(s), strings.ToLower(substr))
+}
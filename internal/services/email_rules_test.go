@@ -0,0 +1,137 @@
+package services
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"flexpane/internal/models"
+)
+
+// fakeMailbox is an email source that can return raw messages and mark
+// them read
+type fakeMailbox struct {
+	emails []models.Email
+	raw    map[string]string
+}
+
+func (m *fakeMailbox) GetEmails() ([]models.Email, error) {
+	return append([]models.Email(nil), m.emails...), nil
+}
+
+func (m *fakeMailbox) ReadMessage(id string) ([]byte, error) {
+	return []byte(m.raw[id]), nil
+}
+
+func (m *fakeMailbox) SetRead(id string, read bool) error {
+	for i := range m.emails {
+		if m.emails[i].ID == id {
+			m.emails[i].Read = read
+		}
+	}
+	return nil
+}
+
+func TestEmailRules_Check(t *testing.T) {
+	mailbox := &fakeMailbox{
+		emails: []models.Email{
+			{ID: "1", Subject: "Invoice #42", From: "Billing <billing@vendor.com>", Time: time.Now()},
+			{ID: "2", Subject: "Weekly digest", From: "news@tech.com", Time: time.Now()},
+		},
+		raw: map[string]string{
+			"2": "From: news@tech.com\r\nList-Id: <digest.tech.com>\r\nSubject: Weekly digest\r\n\r\nHello\r\n",
+		},
+	}
+	todos := NewTodoService(filepath.Join(t.TempDir(), "todos.json"))
+	browser := &recordingNotifier{}
+	rules := NewEmailRules(mailbox, []EmailRule{
+		{Name: "Bills", From: "BILLING@", Label: "bills", Todo: true, Notify: []string{"browser"}},
+		{Headers: map[string]string{"List-Id": "digest"}, Hide: true, MarkRead: true},
+	}, todos, map[string]Notifier{"browser": browser})
+
+	visible := rules.Label(mailbox.emails)
+	if len(visible) != 1 || visible[0].ID != "1" || len(visible[0].Labels) != 1 || visible[0].Labels[0] != "bills" {
+		t.Fatalf("Expected the digest hidden and the invoice labelled, got %+v", visible)
+	}
+	// The first check only primes; existing mail isn't acted on
+	if err := rules.Check(context.Background()); err != nil {
+		t.Fatalf("Check failed: %v", err)
+	}
+	if len(todos.GetTodos()) != 0 || len(browser.received) != 0 || mailbox.emails[1].Read {
+		t.Fatalf("Expected no actions for existing mail")
+	}
+
+	mailbox.emails = append(mailbox.emails,
+		models.Email{ID: "3", Subject: "Invoice #43", From: "billing@vendor.com", Time: time.Now()},
+		models.Email{ID: "4", Subject: "Weekly digest", From: "news@tech.com", Time: time.Now()},
+	)
+	mailbox.raw["4"] = mailbox.raw["2"]
+
+	// Listing new mail labels it but leaves the actions to the check
+	rules.Label(mailbox.emails)
+	if len(todos.GetTodos()) != 0 || len(browser.received) != 0 || mailbox.emails[3].Read {
+		t.Fatalf("Expected Label to run no actions")
+	}
+
+	// A check cancelled before acting leaves the new mail for the next one
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+	rules.Check(cancelled)
+	if len(todos.GetTodos()) != 0 {
+		t.Fatalf("Expected a cancelled check to run no actions")
+	}
+
+	if err := rules.Check(context.Background()); err != nil {
+		t.Fatalf("Check failed: %v", err)
+	}
+	rules.Check(context.Background()) // Actions run once per message
+
+	list := todos.GetTodos()
+	if len(list) != 1 || list[0].Message != "Invoice #43" || list[0].EmailID != "3" {
+		t.Errorf("Expected one todo for the new invoice, got %+v", list)
+	}
+	if len(browser.received) != 1 || browser.received[0].URL != "/?email.message=3" {
+		t.Errorf("Expected one notification for the new invoice, got %+v", browser.received)
+	}
+	if !mailbox.emails[3].Read || mailbox.emails[1].Read {
+		t.Errorf("Expected only the new digest marked read, got %+v", mailbox.emails)
+	}
+}
+
+func TestEmailRules_DryRun(t *testing.T) {
+	mailbox := &fakeMailbox{emails: []models.Email{
+		{ID: "1", Subject: "Invoice #42", From: "billing@vendor.com"},
+		{ID: "2", Subject: "Lunch?", From: "ann@company.com"},
+	}}
+	todos := NewTodoService(filepath.Join(t.TempDir(), "todos.json"))
+	rules := NewEmailRules(mailbox, []EmailRule{
+		{From: "billing@", Label: "bills"},
+		{Subject: "invoice", Todo: true},
+		{Headers: map[string]string{"List-Id": "x"}, Hide: true}, // No raw messages to match against
+	}, todos, nil)
+
+	matches, err := rules.DryRun()
+	if err != nil {
+		t.Fatalf("DryRun failed: %v", err)
+	}
+	if len(matches) != 1 || matches[0].EmailID != "1" || len(matches[0].Rules) != 2 || matches[0].Rules[1] != "rule 2" || !matches[0].Todo {
+		t.Errorf("Unexpected matches %+v", matches)
+	}
+	if len(todos.GetTodos()) != 0 {
+		t.Error("Expected a dry run to change nothing")
+	}
+}
+
+func TestEmailRule_Validate(t *testing.T) {
+	valid := EmailRule{From: "boss@", Label: "boss"}
+	if err := valid.Validate(); err != nil {
+		t.Errorf("Expected valid rule, got %v", err)
+	}
+	if err := (EmailRule{Label: "all"}).Validate(); err == nil {
+		t.Error("Expected error for a rule without conditions")
+	}
+	if err := (EmailRule{Subject: "hi"}).Validate(); err == nil {
+		t.Error("Expected error for a rule without actions")
+	}
+}
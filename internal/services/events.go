@@ -0,0 +1,162 @@
+package services
+
+import (
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Kinds of change event
+const (
+	EventTodosChanged    = "todos.changed"    // A todo was added, toggled or removed
+	EventEmailNew        = "email.new"        // New mail arrived
+	EventEmailChanged    = "email.changed"    // A message was read, flagged, archived or deleted
+	EventCalendarChanged = "calendar.changed" // An event was created, edited or deleted
+	EventSnoozeChanged   = "snooze.changed"   // An item was snoozed or brought back
+	EventNotification    = "notification"     // A reminder or alert for the page to show
+)
+
+// eventHistory is how many events are kept for reconnecting tabs to replay
+const eventHistory = 256
+
+// Event says that the data behind a pane changed, so open pages can reload
+// that pane, or carries a notification for open pages to show
+type Event struct {
+	ID           string        `json:"id"`
+	Kind         string        `json:"kind"`
+	Pane         string        `json:"pane,omitempty"` // ID of the pane that shows the changed data
+	Time         time.Time     `json:"time"`
+	Notification *Notification `json:"notification,omitempty"` // Set for EventNotification
+}
+
+// EventBus fans change events out to open pages and keeps recent ones so a
+// page that reconnects can catch up. Event IDs carry the run they belong to,
+// so an ID from before a restart is recognised rather than misread. A nil
+// bus drops events, so publishers needn't check whether one is set.
+type EventBus struct {
+	run string
+
+	mutex       sync.Mutex
+	seq         uint64
+	history     []Event
+	subscribers map[chan Event]struct{}
+}
+
+func NewEventBus() *EventBus {
+	return &EventBus{
+		run:         strconv.FormatInt(time.Now().UnixNano(), 36),
+		subscribers: map[chan Event]struct{}{},
+	}
+}
+
+// Publish records an event and sends it to every subscriber. A subscriber
+// too slow to keep up is dropped, which ends its stream; the page then
+// reconnects and replays what it missed.
+func (b *EventBus) Publish(kind, pane string) {
+	if b == nil {
+		return
+	}
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	b.publish(Event{Kind: kind, Pane: pane})
+}
+
+// PublishNotification sends a notification to open pages as an
+// EventNotification. It reports false, publishing nothing, when no page is
+// subscribed to show it.
+func (b *EventBus) PublishNotification(notification Notification) bool {
+	if b == nil {
+		return false
+	}
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	if len(b.subscribers) == 0 {
+		return false
+	}
+	b.publish(Event{Kind: EventNotification, Notification: &notification})
+	return true
+}
+
+// publish must be called with the mutex held
+func (b *EventBus) publish(event Event) {
+	b.seq++
+	event.ID = b.id(b.seq)
+	event.Time = time.Now()
+	b.history = append(b.history, event)
+	if len(b.history) > eventHistory {
+		b.history = b.history[len(b.history)-eventHistory:]
+	}
+
+	for ch := range b.subscribers {
+		select {
+		case ch <- event:
+		default:
+			delete(b.subscribers, ch)
+			close(ch)
+		}
+	}
+}
+
+// LastID returns the ID of the latest event, for a page to resume from
+func (b *EventBus) LastID() string {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	return b.id(b.seq)
+}
+
+// Subscription is an open page's view of the bus
+type Subscription struct {
+	Events <-chan Event // Closed if the subscriber falls behind
+	Missed []Event      // Events after the ID subscribed from, to send first
+	Reset  bool         // The ID can't be replayed from; assume anything changed
+	LastID string       // ID of the latest event when subscribing
+
+	bus *EventBus
+	ch  chan Event
+}
+
+// Subscribe registers a page. lastID is the last event it saw, or empty for
+// none; later events are replayed through Missed when they are still kept.
+func (b *EventBus) Subscribe(lastID string) *Subscription {
+	ch := make(chan Event, 64)
+
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	sub := &Subscription{Events: ch, LastID: b.id(b.seq), bus: b, ch: ch}
+	if lastID != "" {
+		sub.Missed, sub.Reset = b.since(lastID)
+	}
+	b.subscribers[ch] = struct{}{}
+	return sub
+}
+
+// Close unsubscribes. Call it when the page disconnects.
+func (s *Subscription) Close() {
+	s.bus.mutex.Lock()
+	defer s.bus.mutex.Unlock()
+	if _, exists := s.bus.subscribers[s.ch]; exists {
+		delete(s.bus.subscribers, s.ch)
+		close(s.ch)
+	}
+}
+
+// since returns the kept events after lastID, and whether some may be
+// missing because lastID is from another run or older than the history
+func (b *EventBus) since(lastID string) ([]Event, bool) {
+	run, seqText, _ := strings.Cut(lastID, "-")
+	seq, err := strconv.ParseUint(seqText, 10, 64)
+	if err != nil || run != b.run || seq > b.seq {
+		return nil, true
+	}
+	missed := b.seq - seq
+	if missed > uint64(len(b.history)) {
+		return nil, true
+	}
+	return append([]Event(nil), b.history[uint64(len(b.history))-missed:]...), false
+}
+
+func (b *EventBus) id(seq uint64) string {
+	return b.run + "-" + strconv.FormatUint(seq, 10)
+}
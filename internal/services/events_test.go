@@ -0,0 +1,126 @@
+package services
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"flexpane/internal/models"
+)
+
+// drain returns the events waiting on a subscription
+func drain(sub *Subscription) []Event {
+	var events []Event
+	for {
+		select {
+		case event, ok := <-sub.Events:
+			if !ok {
+				return events
+			}
+			events = append(events, event)
+		default:
+			return events
+		}
+	}
+}
+
+func TestEventBus_Replay(t *testing.T) {
+	bus := NewEventBus()
+	bus.Publish(EventTodosChanged, "todos")
+	since := bus.LastID()
+	bus.Publish(EventEmailNew, "email")
+	bus.Publish(EventCalendarChanged, "calendar")
+
+	sub := bus.Subscribe(since)
+	defer sub.Close()
+	if sub.Reset || len(sub.Missed) != 2 || sub.Missed[0].Pane != "email" || sub.Missed[1].Pane != "calendar" {
+		t.Fatalf("Expected the two later events replayed, got %+v", sub)
+	}
+	if sub.LastID != sub.Missed[1].ID {
+		t.Errorf("Expected LastID %s, got %s", sub.Missed[1].ID, sub.LastID)
+	}
+
+	bus.Publish(EventTodosChanged, "todos")
+	if events := drain(sub); len(events) != 1 || events[0].Kind != EventTodosChanged {
+		t.Errorf("Expected the new event delivered, got %+v", events)
+	}
+
+	// Nothing to replay for a fresh page; IDs from another run or beyond the
+	// history can't be replayed
+	if sub := bus.Subscribe(""); sub.Reset || len(sub.Missed) != 0 {
+		t.Errorf("Expected no replay without an ID, got %+v", sub)
+	}
+	if sub := bus.Subscribe(NewEventBus().LastID() + "x"); !sub.Reset {
+		t.Error("Expected a reset for an ID from another run")
+	}
+	for i := 0; i < eventHistory; i++ {
+		bus.Publish(EventTodosChanged, "todos")
+	}
+	if sub := bus.Subscribe(since); !sub.Reset {
+		t.Error("Expected a reset once the history has moved on")
+	}
+
+	var nilBus *EventBus
+	nilBus.Publish(EventTodosChanged, "todos") // Dropped
+}
+
+func TestEventBus_SlowSubscriber(t *testing.T) {
+	bus := NewEventBus()
+	sub := bus.Subscribe("")
+	for i := 0; i < 100; i++ {
+		bus.Publish(EventTodosChanged, "todos")
+	}
+
+	events := drain(sub)
+	if _, open := <-sub.Events; open {
+		t.Fatal("Expected the stream of a subscriber that fell behind to end")
+	}
+	// It picks up where it left off
+	resumed := bus.Subscribe(events[len(events)-1].ID)
+	if resumed.Reset || len(events)+len(resumed.Missed) != 100 {
+		t.Errorf("Expected to replay the rest, got %d then %d", len(events), len(resumed.Missed))
+	}
+	sub.Close() // Safe after the bus dropped it
+	resumed.Close()
+}
+
+func TestEventBus_Publishers(t *testing.T) {
+	bus := NewEventBus()
+	sub := bus.Subscribe("")
+	defer sub.Close()
+
+	todos := NewTodoService(filepath.Join(t.TempDir(), "todos.json"))
+	todos.SetEvents(bus)
+	todo, _ := todos.Add(models.Todo{Message: "Call the plumber"})
+	todos.SetDone(todo.ID, true)
+	if events := drain(sub); len(events) != 2 || events[0].Pane != "todos" || events[1].Kind != EventTodosChanged {
+		t.Errorf("Expected two todo changes, got %+v", events)
+	}
+
+	mailbox := &fakeMailbox{emails: []models.Email{{ID: "1", Subject: "Hello"}}}
+	rules := NewEmailRules(mailbox, nil, nil, nil)
+	rules.SetEvents(bus)
+	rules.Check(context.Background())
+	if events := drain(sub); len(events) != 0 {
+		t.Errorf("Expected no event for mail already there, got %+v", events)
+	}
+	mailbox.emails = append(mailbox.emails, models.Email{ID: "2", Subject: "New"})
+	rules.Check(context.Background())
+	if events := drain(sub); len(events) != 1 || events[0].Kind != EventEmailNew || events[0].Pane != "email" {
+		t.Errorf("Expected new mail announced, got %+v", events)
+	}
+
+	snoozes := NewSnoozeService(filepath.Join(t.TempDir(), "snoozes.json"))
+	snoozes.SetEvents(bus)
+	now := at(6, 9, 0)
+	snoozes.now = func() time.Time { return now }
+	snoozes.Snooze(SnoozeTodo, todo.ID, "Call the plumber", at(6, 10, 0), false)
+	snoozes.Check(context.Background())
+	now = at(6, 10, 30)
+	snoozes.Check(context.Background())
+	snoozes.Check(context.Background()) // Waking is announced once
+	if events := drain(sub); len(events) != 2 || events[0].Pane != "todos" || events[1].Kind != EventSnoozeChanged {
+		t.Errorf("Expected the snooze and its end announced, got %+v", events)
+	}
+}
@@ -0,0 +1,127 @@
+package services
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"strings"
+	"time"
+
+	"flexpane/internal/ical"
+	"flexpane/internal/models"
+)
+
+// How todos appear in the published feed
+const (
+	FeedTodosVTODO  = "vtodo"  // VTODO components, for clients that track tasks
+	FeedTodosEvents = "events" // All-day VEVENTs on the due date, for clients that ignore VTODO
+)
+
+// CalendarFeed publishes events from every calendar plus todo due dates as a
+// single iCalendar document
+type CalendarFeed struct {
+	sources  []EventSource
+	todos    *TodoService
+	todosAs  string
+	location *time.Location
+}
+
+// NewCalendarFeed creates a feed. todosAs is FeedTodosVTODO or
+// FeedTodosEvents; location decides which day a timed due date falls on when
+// todos are published as events.
+func NewCalendarFeed(sources []EventSource, todos *TodoService, todosAs string, location *time.Location) *CalendarFeed {
+	if todosAs == "" {
+		todosAs = FeedTodosVTODO
+	}
+	if location == nil {
+		location = time.Local
+	}
+	return &CalendarFeed{sources: sources, todos: todos, todosAs: todosAs, location: location}
+}
+
+// Events merges events from every source, dropping shared invites that
+// appear in more than one calendar. Only IDs in the globally unique
+// "id@domain" form RFC 5545 recommends for UIDs are matched across sources;
+// other IDs, such as a provider's row numbers, are only unique within their
+// own calendar. A failing source is logged and skipped so the rest of the
+// feed still publishes.
+func (f *CalendarFeed) Events() []models.Event {
+	seen := map[string]bool{}
+	events := []models.Event{}
+	for i, source := range f.sources {
+		sourceEvents, err := source.GetCalendarEvents()
+		if err != nil {
+			log.Printf("calendar feed: skipping source: %v", err)
+			continue
+		}
+		for _, event := range sourceEvents {
+			if event.ID == "" {
+				events = append(events, event)
+				continue
+			}
+			key := event.ID
+			if !strings.Contains(key, "@") {
+				key = fmt.Sprintf("%d/%s", i, key)
+			}
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+			events = append(events, event)
+		}
+	}
+	return events
+}
+
+// Write encodes the feed as a VCALENDAR
+func (f *CalendarFeed) Write(w io.Writer) error {
+	encoder := ical.NewEncoder(w)
+	encoder.Begin("VCALENDAR")
+	encoder.Line("VERSION:2.0")
+	encoder.Line("PRODID:" + ical.ProdID)
+	encoder.Line("CALSCALE:GREGORIAN")
+	encoder.Text("X-WR-CALNAME", "Flexpane")
+
+	for _, event := range f.Events() {
+		encoder.Event(event)
+	}
+
+	if f.todos != nil {
+		for _, todo := range f.todos.GetTodos() {
+			if f.todosAs == FeedTodosEvents {
+				if event, ok := f.todoEvent(todo); ok {
+					encoder.Event(event)
+				}
+				continue
+			}
+			// A VTODO without a due date has nothing to show in a calendar
+			if todo.Due != nil {
+				encoder.Todo(todo)
+			}
+		}
+	}
+
+	encoder.End("VCALENDAR")
+	return encoder.Err()
+}
+
+// todoEvent turns an open todo with a due date into an all-day event
+func (f *CalendarFeed) todoEvent(todo models.Todo) (models.Event, bool) {
+	if todo.Done || todo.Due == nil {
+		return models.Event{}, false
+	}
+
+	due := *todo.Due
+	if !todo.DueAllDay {
+		due = due.In(f.location)
+	}
+	start := time.Date(due.Year(), due.Month(), due.Day(), 0, 0, 0, 0, time.UTC)
+
+	return models.Event{
+		ID:     fmt.Sprintf("todo-%s@flexpane", todo.ID),
+		Title:  "Todo: " + todo.Message,
+		Start:  start,
+		End:    start.AddDate(0, 0, 1),
+		AllDay: true,
+	}, true
+}
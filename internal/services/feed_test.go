@@ -0,0 +1,78 @@
+package services
+
+import (
+	"bytes"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"flexpane/internal/ical"
+)
+
+func TestCalendarFeed_MergesSourcesAndTodos(t *testing.T) {
+	work := fakeSource{
+		{ID: "standup@example.com", Title: "Standup", Start: at(6, 9, 0), End: at(6, 9, 15)},
+		{ID: "1", Title: "Review", Start: at(6, 14, 0), End: at(6, 15, 0)},
+	}
+	home := fakeSource{
+		{ID: "1", Title: "Dentist", Start: at(7, 8, 0), End: at(7, 9, 0)},                    // Only unique within its calendar
+		{ID: "standup@example.com", Title: "Standup", Start: at(6, 9, 0), End: at(6, 9, 15)}, // Shared invite
+	}
+
+	todos := NewTodoService(filepath.Join(t.TempDir(), "todos.json"))
+	due := at(8, 0, 0)
+	todos.AddTodoDue("File taxes", &due, true)
+	todos.AddTodo("Someday")
+
+	var buf bytes.Buffer
+	if err := NewCalendarFeed([]EventSource{work, home}, todos, FeedTodosVTODO, time.UTC).Write(&buf); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+	feed := buf.String()
+
+	events, err := ical.DecodeEvents(strings.NewReader(feed))
+	if err != nil {
+		t.Fatalf("DecodeEvents failed: %v", err)
+	}
+	if len(events) != 3 {
+		t.Errorf("Expected 3 merged events, got %+v", events)
+	}
+	if strings.Count(feed, "BEGIN:VTODO") != 1 {
+		t.Errorf("Expected only the dated todo in feed:\n%s", feed)
+	}
+	if !strings.Contains(feed, "DUE;VALUE=DATE:20250108") {
+		t.Errorf("Expected all-day due date in feed:\n%s", feed)
+	}
+}
+
+func TestCalendarFeed_TodosAsEvents(t *testing.T) {
+	todos := NewTodoService(filepath.Join(t.TempDir(), "todos.json"))
+	due := time.Date(2025, 1, 8, 23, 30, 0, 0, time.UTC)
+	todos.AddTodoDue("Send invoice", &due, false)
+	todos.AddTodo("No due date")
+
+	tokyo, _ := time.LoadLocation("Asia/Tokyo")
+
+	var buf bytes.Buffer
+	if err := NewCalendarFeed(nil, todos, FeedTodosEvents, tokyo).Write(&buf); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+
+	events, err := ical.DecodeEvents(&buf)
+	if err != nil {
+		t.Fatalf("DecodeEvents failed: %v", err)
+	}
+	if len(events) != 1 {
+		t.Fatalf("Expected only the dated todo, got %+v", events)
+	}
+
+	// 23:30 UTC on the 8th is already the 9th in Tokyo
+	event := events[0]
+	if !event.AllDay || event.Start.Day() != 9 || event.Title != "Todo: Send invoice" {
+		t.Errorf("Unexpected todo event %+v", event)
+	}
+	if !strings.HasPrefix(event.ID, "todo-") {
+		t.Errorf("Expected todo UID, got %q", event.ID)
+	}
+}
@@ -0,0 +1,263 @@
+package services
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"time"
+
+	"flexpane/internal/models"
+)
+
+// EventSource is anything that can list calendar events, such as a provider
+type EventSource interface {
+	GetCalendarEvents() ([]models.Event, error)
+}
+
+// Slot is a span of time
+type Slot struct {
+	Start time.Time `json:"start"`
+	End   time.Time `json:"end"`
+}
+
+// Duration returns the length of the slot
+func (s Slot) Duration() time.Duration {
+	return s.End.Sub(s.Start)
+}
+
+// WorkingHours bounds the part of each day free slots are searched in
+type WorkingHours struct {
+	Start string   `json:"start"`          // "09:00"
+	End   string   `json:"end"`            // "17:00"
+	Days  []string `json:"days,omitempty"` // "mon".."sun"; weekdays when empty
+}
+
+// DefaultWorkingHours is 09:00-17:00, Monday to Friday
+var DefaultWorkingHours = WorkingHours{Start: "09:00", End: "17:00"}
+
+// MaxFreeBusyDays is the longest range free slots are searched over
+const MaxFreeBusyDays = 90
+
+// Validate checks the times and day names
+func (wh WorkingHours) Validate() error {
+	start, err := parseHourMinute(wh.Start)
+	if err != nil {
+		return fmt.Errorf("working_hours start: %w", err)
+	}
+	end, err := parseHourMinute(wh.End)
+	if err != nil {
+		return fmt.Errorf("working_hours end: %w", err)
+	}
+	if end <= start {
+		return fmt.Errorf("working_hours end must be after start")
+	}
+	for _, day := range wh.Days {
+		if _, ok := weekdays[strings.ToLower(day)]; !ok {
+			return fmt.Errorf("working_hours: unknown day %q", day)
+		}
+	}
+	return nil
+}
+
+// window returns the working window on date, or false on a day off
+func (wh WorkingHours) window(date time.Time) (Slot, bool) {
+	if !wh.worksOn(date.Weekday()) {
+		return Slot{}, false
+	}
+	start, errStart := parseHourMinute(wh.Start)
+	end, errEnd := parseHourMinute(wh.End)
+	if errStart != nil || errEnd != nil {
+		return Slot{}, false
+	}
+
+	// Build the wall-clock times directly rather than adding to midnight,
+	// which is off by an hour on days the clocks change
+	at := func(offset time.Duration) time.Time {
+		return time.Date(date.Year(), date.Month(), date.Day(), int(offset/time.Hour), int(offset%time.Hour/time.Minute), 0, 0, date.Location())
+	}
+	return Slot{Start: at(start), End: at(end)}, true
+}
+
+func (wh WorkingHours) worksOn(day time.Weekday) bool {
+	if len(wh.Days) == 0 {
+		return day != time.Saturday && day != time.Sunday
+	}
+	for _, name := range wh.Days {
+		if weekday, ok := weekdays[strings.ToLower(name)]; ok && weekday == day {
+			return true
+		}
+	}
+	return false
+}
+
+func parseHourMinute(value string) (time.Duration, error) {
+	t, err := time.Parse("15:04", value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid time %q, expected HH:MM", value)
+	}
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute, nil
+}
+
+// FindConflicts returns, for every timed event that overlaps another, the IDs
+// of the events it overlaps. All-day events never conflict.
+func FindConflicts(events []models.Event) map[string][]string {
+	timed := make([]models.Event, 0, len(events))
+	for _, event := range events {
+		if !event.AllDay && event.End.After(event.Start) {
+			timed = append(timed, event)
+		}
+	}
+	sort.Slice(timed, func(i, j int) bool { return timed[i].Start.Before(timed[j].Start) })
+
+	conflicts := make(map[string][]string)
+	for i := range timed {
+		for j := i + 1; j < len(timed) && timed[j].Start.Before(timed[i].End); j++ {
+			conflicts[timed[i].ID] = append(conflicts[timed[i].ID], timed[j].ID)
+			conflicts[timed[j].ID] = append(conflicts[timed[j].ID], timed[i].ID)
+		}
+	}
+	return conflicts
+}
+
+// BusySlots merges the timed events overlapping [from, to) into sorted,
+// non-overlapping busy slots
+func BusySlots(events []models.Event, from, to time.Time) []Slot {
+	var busy []Slot
+	for _, event := range events {
+		if event.AllDay || !event.End.After(from) || !event.Start.Before(to) {
+			continue
+		}
+		busy = append(busy, Slot{Start: event.Start, End: event.End})
+	}
+	sort.Slice(busy, func(i, j int) bool { return busy[i].Start.Before(busy[j].Start) })
+
+	merged := []Slot{}
+	for _, slot := range busy {
+		if n := len(merged); n > 0 && !slot.Start.After(merged[n-1].End) {
+			if slot.End.After(merged[n-1].End) {
+				merged[n-1].End = slot.End
+			}
+			continue
+		}
+		merged = append(merged, slot)
+	}
+	return merged
+}
+
+// FreeSlots returns the gaps of at least minDuration between events within
+// working hours, from from to to. Days are evaluated in from's location, and
+// no more than MaxFreeBusyDays are searched.
+func FreeSlots(events []models.Event, from, to time.Time, minDuration time.Duration, hours WorkingHours) []Slot {
+	if limit := from.AddDate(0, 0, MaxFreeBusyDays); to.After(limit) {
+		to = limit
+	}
+	busy := BusySlots(events, from, to)
+	free := []Slot{}
+
+	day := time.Date(from.Year(), from.Month(), from.Day(), 0, 0, 0, 0, from.Location())
+	for ; day.Before(to); day = day.AddDate(0, 0, 1) {
+		window, ok := hours.window(day)
+		if !ok {
+			continue
+		}
+		if window.Start.Before(from) {
+			window.Start = from
+		}
+		if window.End.After(to) {
+			window.End = to
+		}
+
+		cursor := window.Start
+		for _, slot := range busy {
+			if !slot.End.After(cursor) || !slot.Start.Before(window.End) {
+				continue
+			}
+			if slot.Start.After(cursor) && slot.Start.Sub(cursor) >= minDuration {
+				free = append(free, Slot{Start: cursor, End: slot.Start})
+			}
+			if slot.End.After(cursor) {
+				cursor = slot.End
+			}
+		}
+		if window.End.Sub(cursor) >= minDuration {
+			free = append(free, Slot{Start: cursor, End: window.End})
+		}
+	}
+
+	return free
+}
+
+// FreeBusyService computes availability across several calendars
+type FreeBusyService struct {
+	sources  []EventSource
+	hours    WorkingHours
+	location *time.Location
+}
+
+func NewFreeBusyService(sources []EventSource, hours WorkingHours, location *time.Location) *FreeBusyService {
+	return &FreeBusyService{
+		sources:  sources,
+		hours:    hours,
+		location: location,
+	}
+}
+
+// WorkingHours returns the configured working hours
+func (s *FreeBusyService) WorkingHours() WorkingHours {
+	return s.hours
+}
+
+// Events gathers events from every calendar. A failing source is logged and
+// skipped so the others still count; the names of skipped sources are
+// returned alongside.
+func (s *FreeBusyService) Events() (events []models.Event, skipped []string) {
+	skipped = []string{}
+	for i, source := range s.sources {
+		sourceEvents, err := source.GetCalendarEvents()
+		if err != nil {
+			log.Printf("free/busy: skipping source: %v", err)
+			skipped = append(skipped, sourceName(source, i))
+			continue
+		}
+		events = append(events, sourceEvents...)
+	}
+	return events, skipped
+}
+
+// FreeBusy returns the merged busy slots and the free slots of at least
+// duration between from and to across all calendars, and the names of any
+// calendars that couldn't be read and were left out
+func (s *FreeBusyService) FreeBusy(from, to time.Time, duration time.Duration) (busy, free []Slot, skipped []string) {
+	events, skipped := s.Events()
+
+	from, to = from.In(s.location), to.In(s.location)
+	return BusySlots(events, from, to), FreeSlots(events, from, to, duration, s.hours), skipped
+}
+
+// NamedSource labels a source so the errors it returns, and reports of it
+// being skipped, say which calendar it is
+func NamedSource(name string, source EventSource) EventSource {
+	return namedSource{name: name, source: source}
+}
+
+type namedSource struct {
+	name   string
+	source EventSource
+}
+
+func (n namedSource) GetCalendarEvents() ([]models.Event, error) {
+	events, err := n.source.GetCalendarEvents()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", n.name, err)
+	}
+	return events, nil
+}
+
+// sourceName returns a source's name, or its position for an unnamed one
+func sourceName(source EventSource, index int) string {
+	if named, ok := source.(namedSource); ok {
+		return named.name
+	}
+	return fmt.Sprintf("calendar %d", index+1)
+}
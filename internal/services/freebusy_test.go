@@ -0,0 +1,146 @@
+package services
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"flexpane/internal/models"
+)
+
+// Monday 6 January 2025
+func at(day, hour, minute int) time.Time {
+	return time.Date(2025, 1, day, hour, minute, 0, 0, time.UTC)
+}
+
+func TestFindConflicts(t *testing.T) {
+	events := []models.Event{
+		{ID: "a", Start: at(6, 9, 0), End: at(6, 10, 0)},
+		{ID: "b", Start: at(6, 9, 30), End: at(6, 10, 30)},
+		{ID: "c", Start: at(6, 10, 30), End: at(6, 11, 0)}, // Touches b but doesn't overlap
+		{ID: "d", Start: at(6, 0, 0), End: at(7, 0, 0), AllDay: true},
+	}
+
+	conflicts := FindConflicts(events)
+
+	if len(conflicts) != 2 {
+		t.Fatalf("Expected 2 conflicting events, got %v", conflicts)
+	}
+	if len(conflicts["a"]) != 1 || conflicts["a"][0] != "b" {
+		t.Errorf("Expected a to conflict with b, got %v", conflicts["a"])
+	}
+	if _, exists := conflicts["c"]; exists {
+		t.Error("Back-to-back events should not conflict")
+	}
+}
+
+func TestFreeSlots(t *testing.T) {
+	events := []models.Event{
+		{ID: "a", Start: at(6, 9, 0), End: at(6, 10, 0)},
+		{ID: "b", Start: at(6, 9, 30), End: at(6, 11, 0)},
+		{ID: "c", Start: at(6, 11, 15), End: at(6, 12, 0)},
+		{ID: "d", Start: at(6, 16, 30), End: at(6, 18, 0)},
+	}
+
+	// Monday through Tuesday; weekends would be skipped
+	free := FreeSlots(events, at(6, 0, 0), at(8, 0, 0), 30*time.Minute, DefaultWorkingHours)
+
+	want := []Slot{
+		{Start: at(6, 12, 0), End: at(6, 16, 30)},
+		{Start: at(7, 9, 0), End: at(7, 17, 0)},
+	}
+	if len(free) != len(want) {
+		t.Fatalf("Expected %d slots, got %+v", len(want), free)
+	}
+	for i := range want {
+		if !free[i].Start.Equal(want[i].Start) || !free[i].End.Equal(want[i].End) {
+			t.Errorf("Slot %d: expected %v-%v, got %v-%v", i, want[i].Start, want[i].End, free[i].Start, free[i].End)
+		}
+	}
+}
+
+func TestFreeSlots_SkipsDaysOff(t *testing.T) {
+	// Saturday and Sunday
+	free := FreeSlots(nil, at(4, 0, 0), at(6, 0, 0), time.Hour, DefaultWorkingHours)
+	if len(free) != 0 {
+		t.Errorf("Expected no free slots on the weekend, got %+v", free)
+	}
+
+	weekend := WorkingHours{Start: "10:00", End: "12:00", Days: []string{"sat"}}
+	free = FreeSlots(nil, at(4, 0, 0), at(6, 0, 0), time.Hour, weekend)
+	if len(free) != 1 || free[0].Start.Weekday() != time.Saturday {
+		t.Errorf("Expected one Saturday slot, got %+v", free)
+	}
+}
+
+func TestFreeBusyService_MergesCalendars(t *testing.T) {
+	work := fakeSource{{ID: "w", Start: at(6, 9, 0), End: at(6, 13, 0)}}
+	home := fakeSource{{ID: "h", Start: at(6, 12, 0), End: at(6, 16, 0)}}
+
+	service := NewFreeBusyService([]EventSource{work, home}, DefaultWorkingHours, time.UTC)
+	busy, free, skipped := service.FreeBusy(at(6, 0, 0), at(7, 0, 0), 30*time.Minute)
+	if len(skipped) != 0 {
+		t.Fatalf("Expected no skipped calendars, got %v", skipped)
+	}
+
+	if len(busy) != 1 || !busy[0].Start.Equal(at(6, 9, 0)) || !busy[0].End.Equal(at(6, 16, 0)) {
+		t.Errorf("Expected merged busy slot 09:00-16:00, got %+v", busy)
+	}
+	if len(free) != 1 || !free[0].Start.Equal(at(6, 16, 0)) {
+		t.Errorf("Expected free slot from 16:00, got %+v", free)
+	}
+}
+
+type fakeSource []models.Event
+
+func (f fakeSource) GetCalendarEvents() ([]models.Event, error) { return f, nil }
+
+func TestWorkingHours_Validate(t *testing.T) {
+	invalid := []WorkingHours{
+		{Start: "9am", End: "17:00"},
+		{Start: "17:00", End: "09:00"},
+		{Start: "09:00", End: "17:00", Days: []string{"funday"}},
+	}
+	for _, hours := range invalid {
+		if err := hours.Validate(); err == nil {
+			t.Errorf("Expected %+v to be invalid", hours)
+		}
+	}
+}
+
+func TestFreeBusyService_SkipsFailingSource(t *testing.T) {
+	work := fakeSource{{ID: "w", Start: at(6, 9, 0), End: at(6, 13, 0)}}
+	broken := NamedSource("broken", failingSource{})
+
+	service := NewFreeBusyService([]EventSource{work, broken}, DefaultWorkingHours, time.UTC)
+	busy, free, skipped := service.FreeBusy(at(6, 0, 0), at(7, 0, 0), 30*time.Minute)
+
+	if len(skipped) != 1 || skipped[0] != "broken" {
+		t.Errorf("Expected the broken calendar to be skipped, got %v", skipped)
+	}
+	if len(busy) != 1 || len(free) != 1 || !free[0].Start.Equal(at(6, 13, 0)) {
+		t.Errorf("Expected the working calendar to still count, got busy %+v free %+v", busy, free)
+	}
+}
+
+type failingSource struct{}
+
+func (failingSource) GetCalendarEvents() ([]models.Event, error) {
+	return nil, errors.New("unreachable")
+}
+
+func TestFreeSlots_DaylightSaving(t *testing.T) {
+	london, err := time.LoadLocation("Europe/London")
+	if err != nil {
+		t.Skip("Europe/London time zone not available")
+	}
+
+	// Clocks go forward at 01:00 on Sunday 30 March 2025
+	hours := WorkingHours{Start: "09:00", End: "17:00", Days: []string{"sun"}}
+	from := time.Date(2025, 3, 30, 0, 0, 0, 0, london)
+	free := FreeSlots(nil, from, from.AddDate(0, 0, 1), time.Hour, hours)
+
+	if len(free) != 1 || free[0].Start.Hour() != 9 || free[0].End.Hour() != 17 {
+		t.Errorf("Expected 09:00-17:00 local time, got %+v", free)
+	}
+}
@@ -0,0 +1,125 @@
+package services
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/smtp"
+	"time"
+)
+
+// MailerOptions configures the outgoing mail server
+type MailerOptions struct {
+	Addr     string // host:port
+	Username string
+	Password string
+	From     string
+
+	// TLS connects with implicit TLS (port 465). Otherwise the connection is
+	// upgraded with STARTTLS, which is required unless Insecure is set.
+	TLS      bool
+	Insecure bool
+	Timeout  time.Duration
+
+	TLSConfig *tls.Config // Optional; mainly for tests with private CAs
+}
+
+// ErrNoSTARTTLS is returned when the server can't encrypt the session and
+// plaintext sending wasn't allowed
+var ErrNoSTARTTLS = errors.New("smtp server does not support STARTTLS")
+
+// Mailer sends messages through an SMTP server
+type Mailer struct {
+	options MailerOptions
+}
+
+func NewMailer(options MailerOptions) *Mailer {
+	if options.Timeout <= 0 {
+		options.Timeout = 30 * time.Second
+	}
+	return &Mailer{options: options}
+}
+
+// From returns the configured sender address
+func (m *Mailer) From() string {
+	return m.options.From
+}
+
+// Send delivers a rendered message to the envelope recipients
+func (m *Mailer) Send(ctx context.Context, from string, recipients []string, message []byte) error {
+	host, _, err := net.SplitHostPort(m.options.Addr)
+	if err != nil {
+		return fmt.Errorf("invalid smtp address: %w", err)
+	}
+	tlsConfig := m.options.TLSConfig
+	if tlsConfig == nil {
+		tlsConfig = &tls.Config{}
+	}
+	tlsConfig = tlsConfig.Clone()
+	if tlsConfig.ServerName == "" {
+		tlsConfig.ServerName = host
+	}
+
+	dialer := &net.Dialer{Timeout: m.options.Timeout}
+	var conn net.Conn
+	if m.options.TLS {
+		conn, err = (&tls.Dialer{NetDialer: dialer, Config: tlsConfig}).DialContext(ctx, "tcp", m.options.Addr)
+	} else {
+		conn, err = dialer.DialContext(ctx, "tcp", m.options.Addr)
+	}
+	if err != nil {
+		return err
+	}
+	deadline := time.Now().Add(m.options.Timeout)
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+	}
+	conn.SetDeadline(deadline)
+
+	client, err := smtp.NewClient(conn, host)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	defer client.Close()
+
+	if !m.options.TLS {
+		if ok, _ := client.Extension("STARTTLS"); ok {
+			if err := client.StartTLS(tlsConfig); err != nil {
+				return err
+			}
+		} else if !m.options.Insecure {
+			return ErrNoSTARTTLS
+		}
+	}
+
+	if m.options.Username != "" {
+		// PlainAuth refuses to send credentials over an unencrypted
+		// connection to anything but localhost
+		if err := client.Auth(smtp.PlainAuth("", m.options.Username, m.options.Password, host)); err != nil {
+			return err
+		}
+	}
+
+	if err := client.Mail(from); err != nil {
+		return err
+	}
+	for _, recipient := range recipients {
+		if err := client.Rcpt(recipient); err != nil {
+			return fmt.Errorf("recipient %s: %w", recipient, err)
+		}
+	}
+	w, err := client.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(message); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return client.Quit()
+}
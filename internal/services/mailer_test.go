@@ -0,0 +1,173 @@
+package services
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"net"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// fakeSMTPServer is an in-process SMTP server that records what it receives
+type fakeSMTPServer struct {
+	listener  net.Listener
+	tlsConfig *tls.Config // Offers STARTTLS when set
+
+	mutex    sync.Mutex
+	auth     string
+	from     string
+	rcpt     []string
+	data     string
+	startTLS bool
+}
+
+// testTLS borrows httptest's certificate for 127.0.0.1
+func testTLS(t *testing.T) (*tls.Config, *tls.Config) {
+	t.Helper()
+	srv := httptest.NewUnstartedServer(nil)
+	srv.StartTLS()
+	t.Cleanup(srv.Close)
+
+	pool := x509.NewCertPool()
+	pool.AddCert(srv.Certificate())
+	return &tls.Config{Certificates: srv.TLS.Certificates}, &tls.Config{RootCAs: pool}
+}
+
+func newFakeSMTPServer(t *testing.T, tlsConfig *tls.Config) *fakeSMTPServer {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &fakeSMTPServer{listener: listener, tlsConfig: tlsConfig}
+	t.Cleanup(func() { listener.Close() })
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go s.serve(conn)
+		}
+	}()
+	return s
+}
+
+func (s *fakeSMTPServer) serve(conn net.Conn) {
+	defer func() { conn.Close() }()
+	r := bufio.NewReader(conn)
+	reply := func(line string) { conn.Write([]byte(line + "\r\n")) }
+	reply("220 fake ESMTP")
+
+	encrypted := false
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		line = strings.TrimRight(line, "\r\n")
+		verb := strings.ToUpper(strings.SplitN(line, " ", 2)[0])
+
+		s.mutex.Lock()
+		switch verb {
+		case "EHLO":
+			reply("250-fake")
+			if s.tlsConfig != nil && !encrypted {
+				reply("250-STARTTLS")
+			}
+			reply("250 AUTH PLAIN")
+		case "STARTTLS":
+			reply("220 go ahead")
+			tlsConn := tls.Server(conn, s.tlsConfig)
+			if err := tlsConn.Handshake(); err != nil {
+				s.mutex.Unlock()
+				return
+			}
+			conn, r, encrypted = tlsConn, bufio.NewReader(tlsConn), true
+			s.startTLS = true
+		case "AUTH":
+			fields := strings.Fields(line)
+			decoded, _ := base64.StdEncoding.DecodeString(fields[len(fields)-1])
+			s.auth = string(decoded)
+			reply("235 ok")
+		case "MAIL":
+			s.from = line
+			reply("250 ok")
+		case "RCPT":
+			s.rcpt = append(s.rcpt, line)
+			reply("250 ok")
+		case "DATA":
+			reply("354 send it")
+			var data strings.Builder
+			for {
+				line, err := r.ReadString('\n')
+				if err != nil || line == ".\r\n" {
+					break
+				}
+				data.WriteString(line)
+			}
+			s.data = data.String()
+			reply("250 queued")
+		case "QUIT":
+			reply("221 bye")
+			s.mutex.Unlock()
+			return
+		default:
+			reply("502 unknown")
+		}
+		s.mutex.Unlock()
+	}
+}
+
+func TestMailer_STARTTLSAndAuth(t *testing.T) {
+	serverTLS, clientTLS := testTLS(t)
+	server := newFakeSMTPServer(t, serverTLS)
+
+	mailer := NewMailer(MailerOptions{
+		Addr:      server.listener.Addr().String(),
+		Username:  "me",
+		Password:  "hunter2",
+		From:      "me@example.com",
+		TLSConfig: clientTLS,
+	})
+	message := "Subject: Hi\r\n\r\nHello\r\n"
+	if err := mailer.Send(context.Background(), "me@example.com", []string{"ann@example.com", "bob@example.com"}, []byte(message)); err != nil {
+		t.Fatalf("Send failed: %v", err)
+	}
+
+	server.mutex.Lock()
+	defer server.mutex.Unlock()
+	if !server.startTLS {
+		t.Error("Expected the session to be upgraded with STARTTLS")
+	}
+	if server.auth != "\x00me\x00hunter2" {
+		t.Errorf("Unexpected AUTH PLAIN credentials %q", server.auth)
+	}
+	if server.from != "MAIL FROM:<me@example.com>" || len(server.rcpt) != 2 {
+		t.Errorf("Unexpected envelope %q %v", server.from, server.rcpt)
+	}
+	if server.data != message {
+		t.Errorf("Expected message %q, got %q", message, server.data)
+	}
+}
+
+func TestMailer_RequiresSTARTTLS(t *testing.T) {
+	server := newFakeSMTPServer(t, nil)
+	options := MailerOptions{Addr: server.listener.Addr().String(), From: "me@example.com"}
+
+	err := NewMailer(options).Send(context.Background(), "me@example.com", []string{"ann@example.com"}, []byte("Subject: Hi\r\n\r\nHello\r\n"))
+	if err != ErrNoSTARTTLS {
+		t.Errorf("Expected ErrNoSTARTTLS, got %v", err)
+	}
+
+	options.Insecure = true
+	if err := NewMailer(options).Send(context.Background(), "me@example.com", []string{"ann@example.com"}, []byte("Subject: Hi\r\n\r\nHello\r\n")); err != nil {
+		t.Errorf("Expected insecure sending to work, got %v", err)
+	}
+}
@@ -0,0 +1,85 @@
+package services
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"flexpane/internal/models"
+)
+
+// joinLinkPattern matches meeting URLs for Zoom, Google Meet and Microsoft Teams
+var joinLinkPattern = regexp.MustCompile(`https://(?:[\w-]+\.)?(?:zoom\.us/(?:j|my|w)/[^\s<>"']+|meet\.google\.com/[a-z]{3,4}-[a-z]{4}-[a-z]{3,4}[^\s<>"']*|teams\.microsoft\.com/l/meetup-join/[^\s<>"']+|teams\.live\.com/meet/[^\s<>"']+)`)
+
+// JoinLink returns the first video meeting URL in an event's location or
+// description, or "" when there is none
+func JoinLink(event models.Event) string {
+	for _, text := range []string{event.Location, event.Description} {
+		if link := joinLinkPattern.FindString(text); link != "" {
+			return strings.TrimRight(link, ".,;)")
+		}
+	}
+	return ""
+}
+
+// MeetingStatus describes an event relative to the current time
+type MeetingStatus struct {
+	Event       models.Event
+	JoinURL     string
+	Countdown   string // Human-readable time until the event starts, or ends when in progress
+	LeavingSoon bool   // Starts within the leave warning threshold
+	InProgress  bool
+}
+
+// CurrentAndNext returns the timed events happening at now and the next
+// timed event to start. All-day events are ignored.
+func CurrentAndNext(events []models.Event, now time.Time, leaveWarning time.Duration) ([]MeetingStatus, *MeetingStatus) {
+	current := []MeetingStatus{}
+	var next *MeetingStatus
+
+	for _, event := range events {
+		if event.AllDay {
+			continue
+		}
+
+		switch {
+		case !event.Start.After(now) && event.End.After(now):
+			current = append(current, MeetingStatus{
+				Event:      event,
+				JoinURL:    JoinLink(event),
+				Countdown:  "ends in " + FormatCountdown(event.End.Sub(now)),
+				InProgress: true,
+			})
+
+		case event.Start.After(now):
+			if next != nil && !event.Start.Before(next.Event.Start) {
+				continue
+			}
+			until := event.Start.Sub(now)
+			next = &MeetingStatus{
+				Event:       event,
+				JoinURL:     JoinLink(event),
+				Countdown:   "in " + FormatCountdown(until),
+				LeavingSoon: until <= leaveWarning,
+			}
+		}
+	}
+
+	return current, next
+}
+
+// FormatCountdown renders a duration like "2h 05m", "12m" or "<1m"
+func FormatCountdown(d time.Duration) string {
+	d = d.Round(time.Minute)
+	switch {
+	case d < time.Minute:
+		return "<1m"
+	case d < time.Hour:
+		return fmt.Sprintf("%dm", int(d.Minutes()))
+	case d < 24*time.Hour:
+		return fmt.Sprintf("%dh %02dm", int(d.Hours()), int(d.Minutes())%60)
+	default:
+		return fmt.Sprintf("%dd %dh", int(d.Hours())/24, int(d.Hours())%24)
+	}
+}
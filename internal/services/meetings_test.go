@@ -0,0 +1,74 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"flexpane/internal/models"
+)
+
+func TestJoinLink(t *testing.T) {
+	tests := []struct {
+		event models.Event
+		want  string
+	}{
+		{models.Event{Location: "https://acme.zoom.us/j/123456789?pwd=abc"}, "https://acme.zoom.us/j/123456789?pwd=abc"},
+		{models.Event{Location: "Room 4", Description: "Join at https://meet.google.com/abc-defg-hij."}, "https://meet.google.com/abc-defg-hij"},
+		{models.Event{Description: "Teams: https://teams.microsoft.com/l/meetup-join/19%3ameeting_x/0"}, "https://teams.microsoft.com/l/meetup-join/19%3ameeting_x/0"},
+		{models.Event{Location: "Conference Room A", Description: "https://example.com/agenda"}, ""},
+	}
+
+	for _, tt := range tests {
+		if got := JoinLink(tt.event); got != tt.want {
+			t.Errorf("JoinLink(%q, %q) = %q, want %q", tt.event.Location, tt.event.Description, got, tt.want)
+		}
+	}
+}
+
+func TestCurrentAndNext(t *testing.T) {
+	events := []models.Event{
+		{ID: "standup", Start: at(6, 9, 0), End: at(6, 9, 30), Location: "https://zoom.us/j/1"},
+		{ID: "later", Start: at(6, 14, 0), End: at(6, 15, 0)},
+		{ID: "review", Start: at(6, 9, 40), End: at(6, 10, 30)},
+		{ID: "holiday", Start: at(6, 0, 0), End: at(7, 0, 0), AllDay: true},
+	}
+
+	current, next := CurrentAndNext(events, at(6, 9, 15), 5*time.Minute)
+
+	if len(current) != 1 || current[0].Event.ID != "standup" {
+		t.Fatalf("Expected standup in progress, got %+v", current)
+	}
+	if current[0].JoinURL != "https://zoom.us/j/1" || current[0].Countdown != "ends in 15m" {
+		t.Errorf("Unexpected current status %+v", current[0])
+	}
+	if next == nil || next.Event.ID != "review" {
+		t.Fatalf("Expected review next, got %+v", next)
+	}
+	if next.Countdown != "in 25m" || next.LeavingSoon {
+		t.Errorf("Unexpected next status %+v", next)
+	}
+
+	_, next = CurrentAndNext(events, at(6, 9, 36), 5*time.Minute)
+	if !next.LeavingSoon {
+		t.Error("Expected leaving soon within the warning threshold")
+	}
+
+	current, next = CurrentAndNext(events, at(6, 16, 0), 5*time.Minute)
+	if len(current) != 0 || next != nil {
+		t.Errorf("Expected nothing current or next, got %+v / %+v", current, next)
+	}
+}
+
+func TestFormatCountdown(t *testing.T) {
+	tests := map[time.Duration]string{
+		20 * time.Second:              "<1m",
+		12 * time.Minute:              "12m",
+		2*time.Hour + 5*time.Minute:   "2h 05m",
+		27*time.Hour + 10*time.Minute: "1d 3h",
+	}
+	for d, want := range tests {
+		if got := FormatCountdown(d); got != want {
+			t.Errorf("FormatCountdown(%v) = %q, want %q", d, got, want)
+		}
+	}
+}
@@ -0,0 +1,106 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"mime"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// BrowserNotifier sends notifications to open pages over the event bus,
+// where they show with the Notification API
+type BrowserNotifier struct {
+	events *EventBus
+}
+
+func NewBrowserNotifier(events *EventBus) *BrowserNotifier {
+	return &BrowserNotifier{events: events}
+}
+
+// Notify sends to every open page. With no pages open the reminder stays
+// pending so a page opened shortly afterwards still gets it.
+func (b *BrowserNotifier) Notify(ctx context.Context, notification Notification) error {
+	if !b.events.PublishNotification(notification) {
+		return ErrNoRecipients
+	}
+	return nil
+}
+
+// WebhookNotifier POSTs notifications as JSON to a URL
+type WebhookNotifier struct {
+	url     string
+	headers map[string]string
+	client  *http.Client
+}
+
+func NewWebhookNotifier(url string, headers map[string]string) *WebhookNotifier {
+	return &WebhookNotifier{
+		url:     url,
+		headers: headers,
+		client:  &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+func (n *WebhookNotifier) Notify(ctx context.Context, notification Notification) error {
+	body, err := json.Marshal(notification)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", n.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for name, value := range n.headers {
+		req.Header.Set(name, value)
+	}
+
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("webhook returned %s", resp.Status)
+	}
+	return nil
+}
+
+// SMTPNotifier emails notifications through a Mailer
+type SMTPNotifier struct {
+	mailer *Mailer
+	to     []string
+}
+
+func NewSMTPNotifier(mailer *Mailer, to []string) *SMTPNotifier {
+	return &SMTPNotifier{mailer: mailer, to: to}
+}
+
+func (n *SMTPNotifier) Notify(ctx context.Context, notification Notification) error {
+	from := n.mailer.From()
+
+	var msg strings.Builder
+	fmt.Fprintf(&msg, "From: %s\r\n", from)
+	fmt.Fprintf(&msg, "To: %s\r\n", strings.Join(n.to, ", "))
+	fmt.Fprintf(&msg, "Subject: %s\r\n", headerValue("Reminder: "+notification.Title))
+	fmt.Fprintf(&msg, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	msg.WriteString("Content-Type: text/plain; charset=utf-8\r\n\r\n")
+	msg.WriteString(notification.Body + "\r\n")
+	if notification.URL != "" {
+		msg.WriteString(notification.URL + "\r\n")
+	}
+
+	return n.mailer.Send(ctx, from, n.to, []byte(msg.String()))
+}
+
+// headerValue keeps user text from breaking out of a header line and
+// encodes non-ASCII text
+func headerValue(s string) string {
+	return mime.QEncoding.Encode("utf-8", strings.NewReplacer("\r", " ", "\n", " ").Replace(s))
+}
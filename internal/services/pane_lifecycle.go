@@ -0,0 +1,88 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"flexpane/internal/models"
+)
+
+// Start initializes every enabled pane and then starts them, in
+// configuration order. ctx is passed to Start and should last until
+// shutdown. If any pane fails, the panes already started are stopped and
+// the error names the pane. Panes that were only initialized have nothing
+// running and are left alone.
+func (pr *PaneRegistry) Start(ctx context.Context) error {
+	panes := pr.enabledPanes()
+	for _, pane := range panes {
+		if initializer, ok := pane.(models.Initializer); ok {
+			if err := initializer.Init(ctx); err != nil {
+				return fmt.Errorf("pane %s: init: %w", pane.ID(), err)
+			}
+		}
+	}
+
+	for _, pane := range panes {
+		if starter, ok := pane.(models.Starter); ok {
+			if err := starter.Start(ctx); err != nil {
+				return pr.abortStart(ctx, fmt.Errorf("pane %s: start: %w", pane.ID(), err))
+			}
+		}
+		pr.running = append(pr.running, pane)
+	}
+	return nil
+}
+
+// abortStart stops the panes Start got going and returns its error
+func (pr *PaneRegistry) abortStart(ctx context.Context, err error) error {
+	if stopErr := pr.Stop(context.WithoutCancel(ctx)); stopErr != nil {
+		return errors.Join(err, stopErr)
+	}
+	return err
+}
+
+// Stop stops the started panes in reverse order. Every pane is asked to
+// stop even if an earlier one fails.
+func (pr *PaneRegistry) Stop(ctx context.Context) error {
+	var errs []error
+	for i := len(pr.running) - 1; i >= 0; i-- {
+		pane := pr.running[i]
+		if stopper, ok := pane.(models.Stopper); ok {
+			if err := stopper.Stop(ctx); err != nil {
+				errs = append(errs, fmt.Errorf("pane %s: stop: %w", pane.ID(), err))
+			}
+		}
+	}
+	pr.running = nil
+	return errors.Join(errs...)
+}
+
+// Health checks every enabled pane in parallel, each within its load
+// timeout, and reports them in configuration order
+func (pr *PaneRegistry) Health(ctx context.Context) []models.PaneHealth {
+	panes := pr.enabledPanes()
+	health := make([]models.PaneHealth, len(panes))
+	var wg sync.WaitGroup
+	for i, pane := range panes {
+		health[i] = models.PaneHealth{ID: pane.ID(), Status: models.HealthOK}
+		checker, ok := pane.(models.HealthChecker)
+		if !ok {
+			continue
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := withDeadline(ctx, pr.timeout(pane.ID()), func(ctx context.Context) (struct{}, error) {
+				return struct{}{}, checker.Health(ctx)
+			})
+			if err != nil {
+				health[i].Status = models.HealthFailing
+				health[i].Error = err.Error()
+			}
+		}()
+	}
+	wg.Wait()
+	return health
+}
@@ -0,0 +1,133 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"flexpane/internal/models"
+)
+
+// lifecyclePane records its hooks being called
+type lifecyclePane struct {
+	MockPane
+	calls     *[]string
+	initErr   error
+	startErr  error
+	stopErr   error
+	healthErr error
+	hang      bool // Health blocks until its deadline
+}
+
+func (p *lifecyclePane) Init(ctx context.Context) error {
+	*p.calls = append(*p.calls, "init "+p.id)
+	return p.initErr
+}
+
+func (p *lifecyclePane) Start(ctx context.Context) error {
+	*p.calls = append(*p.calls, "start "+p.id)
+	return p.startErr
+}
+
+func (p *lifecyclePane) Stop(ctx context.Context) error {
+	*p.calls = append(*p.calls, "stop "+p.id)
+	return p.stopErr
+}
+
+func (p *lifecyclePane) Health(ctx context.Context) error {
+	if p.hang {
+		<-ctx.Done()
+		return ctx.Err()
+	}
+	return p.healthErr
+}
+
+func TestPaneRegistry_Lifecycle(t *testing.T) {
+	var calls []string
+	registry := NewPaneRegistry()
+	registry.RegisterPane(&lifecyclePane{MockPane: MockPane{id: "a"}, calls: &calls})
+	registry.RegisterPane(&lifecyclePane{MockPane: MockPane{id: "b"}, calls: &calls, stopErr: errors.New("still busy")})
+	registry.RegisterPane(&MockPane{id: "plain"})
+	registry.RegisterPane(&lifecyclePane{MockPane: MockPane{id: "disabled"}, calls: &calls})
+	registry.SetEnabledPanes([]string{"a", "plain", "b"})
+
+	if err := registry.Start(context.Background()); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+	err := registry.Stop(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "pane b: stop: still busy") {
+		t.Errorf("Expected b's stop error, got %v", err)
+	}
+
+	want := "init a,init b,start a,start b,stop b,stop a"
+	if got := strings.Join(calls, ","); got != want {
+		t.Errorf("Expected %s, got %s", want, got)
+	}
+}
+
+func TestPaneRegistry_InitFailure(t *testing.T) {
+	var calls []string
+	registry := NewPaneRegistry()
+	registry.RegisterPane(&lifecyclePane{MockPane: MockPane{id: "a"}, calls: &calls})
+	registry.RegisterPane(&lifecyclePane{MockPane: MockPane{id: "b"}, calls: &calls, initErr: errors.New("no API key")})
+	registry.RegisterPane(&lifecyclePane{MockPane: MockPane{id: "c"}, calls: &calls})
+	registry.SetEnabledPanes([]string{"a", "b", "c"})
+
+	err := registry.Start(context.Background())
+	if err == nil || err.Error() != "pane b: init: no API key" {
+		t.Fatalf("Expected b's init error, got %v", err)
+	}
+	// Nothing starts, so there is nothing to stop
+	want := "init a,init b"
+	if got := strings.Join(calls, ","); got != want {
+		t.Errorf("Expected %s, got %s", want, got)
+	}
+}
+
+func TestPaneRegistry_StartFailure(t *testing.T) {
+	var calls []string
+	registry := NewPaneRegistry()
+	registry.RegisterPane(&lifecyclePane{MockPane: MockPane{id: "a"}, calls: &calls})
+	registry.RegisterPane(&lifecyclePane{MockPane: MockPane{id: "b"}, calls: &calls, startErr: errors.New("port in use")})
+	registry.RegisterPane(&lifecyclePane{MockPane: MockPane{id: "c"}, calls: &calls})
+	registry.SetEnabledPanes([]string{"a", "b", "c"})
+
+	err := registry.Start(context.Background())
+	if err == nil || err.Error() != "pane b: start: port in use" {
+		t.Fatalf("Expected b's start error, got %v", err)
+	}
+	// Only the pane that started is stopped
+	want := "init a,init b,init c,start a,start b,stop a"
+	if got := strings.Join(calls, ","); got != want {
+		t.Errorf("Expected %s, got %s", want, got)
+	}
+}
+
+func TestPaneRegistry_Health(t *testing.T) {
+	var calls []string
+	registry := NewPaneRegistry()
+	registry.RegisterPane(&lifecyclePane{MockPane: MockPane{id: "ok"}, calls: &calls})
+	registry.RegisterPane(&lifecyclePane{MockPane: MockPane{id: "broken"}, calls: &calls, healthErr: errors.New("mailbox unreachable")})
+	registry.RegisterPane(&lifecyclePane{MockPane: MockPane{id: "stuck"}, calls: &calls, hang: true})
+	registry.RegisterPane(&MockPane{id: "plain"})
+	registry.SetEnabledPanes([]string{"ok", "broken", "stuck", "plain"})
+	registry.SetTimeouts(map[string]time.Duration{"stuck": 50 * time.Millisecond})
+
+	health := registry.Health(context.Background())
+	want := []models.PaneHealth{
+		{ID: "ok", Status: models.HealthOK},
+		{ID: "broken", Status: models.HealthFailing, Error: "mailbox unreachable"},
+		{ID: "stuck", Status: models.HealthFailing, Error: context.DeadlineExceeded.Error()},
+		{ID: "plain", Status: models.HealthOK},
+	}
+	if len(health) != len(want) {
+		t.Fatalf("Expected %d panes, got %+v", len(want), health)
+	}
+	for i := range want {
+		if health[i] != want[i] {
+			t.Errorf("Expected %+v, got %+v", want[i], health[i])
+		}
+	}
+}
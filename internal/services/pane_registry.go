@@ -2,15 +2,30 @@ package services
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"slices"
+	"sort"
+	"sync"
+	"time"
 
 	"flexpane/internal/models"
 )
 
+// DefaultPaneTimeout bounds how long a page waits for one pane's data
+const DefaultPaneTimeout = 5 * time.Second
+
 // PaneRegistry manages all available panes
 type PaneRegistry struct {
-	panes   map[string]models.Pane
-	enabled []string
-	layout  map[string]PaneLayoutConfig
+	panes    map[string]models.Pane
+	enabled  []string
+	layout   map[string]PaneLayoutConfig
+	timeouts map[string]time.Duration
+	refresh  map[string]time.Duration
+
+	running []models.Pane // Initialized panes, in start order
 }
 
 // PaneLayoutConfig holds layout configuration for a pane
@@ -26,6 +41,18 @@ func NewPaneRegistry() *PaneRegistry {
 	}
 }
 
+// SetTimeouts sets how long each pane may take to load its data. Panes not
+// listed get DefaultPaneTimeout.
+func (pr *PaneRegistry) SetTimeouts(timeouts map[string]time.Duration) {
+	pr.timeouts = timeouts
+}
+
+// SetRefreshes sets how often an open page reloads each pane. Panes not
+// listed only change when the page is reloaded or acted on.
+func (pr *PaneRegistry) SetRefreshes(refreshes map[string]time.Duration) {
+	pr.refresh = refreshes
+}
+
 // RegisterPane adds a pane to the registry
 func (pr *PaneRegistry) RegisterPane(pane models.Pane) {
 	pr.panes[pane.ID()] = pane
@@ -41,41 +68,159 @@ func (pr *PaneRegistry) SetLayoutConfig(layout map[string]PaneLayoutConfig) {
 	pr.layout = layout
 }
 
-// GetEnabledPanes returns all enabled panes with their data
+// GetEnabledPanes returns all enabled panes with their data. Panes load in
+// parallel, each with its own deadline derived from ctx; a pane that misses
+// it or fails is returned with an error state rather than holding up the
+// page.
 func (pr *PaneRegistry) GetEnabledPanes(ctx context.Context) ([]models.PaneData, error) {
-	var paneData []models.PaneData
+	panes := pr.enabledPanes()
+
+	// Each goroutine fills its own slot, so panes keep configuration order
+	paneData := make([]models.PaneData, len(panes))
+	var wg sync.WaitGroup
+	for i, pane := range panes {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			paneData[i] = pr.paneData(ctx, pane)
+		}()
+	}
+	wg.Wait()
 
+	return paneData, nil
+}
+
+// enabledPanes returns the enabled panes in configuration order
+func (pr *PaneRegistry) enabledPanes() []models.Pane {
+	var panes []models.Pane
 	for _, paneID := range pr.enabled {
 		pane, exists := pr.panes[paneID]
 		if !exists {
 			continue // Skip missing panes gracefully
 		}
+		panes = append(panes, pane)
+	}
+	return panes
+}
+
+// GetEnabledPane loads a single enabled pane, for refreshing it on its own
+func (pr *PaneRegistry) GetEnabledPane(ctx context.Context, paneID string) (models.PaneData, bool) {
+	pane, exists := pr.panes[paneID]
+	if !exists || !slices.Contains(pr.enabled, paneID) {
+		return models.PaneData{}, false
+	}
+	return pr.paneData(ctx, pane), true
+}
 
-		data, err := pane.GetData(ctx)
-		if err != nil {
-			// TODO: Add logging, for now continue with nil data
-			data = nil
+func (pr *PaneRegistry) paneData(ctx context.Context, pane models.Pane) models.PaneData {
+	// Get layout config for this pane (required)
+	layoutConfig := pr.layout[pane.ID()]
+
+	paneData := models.PaneData{
+		ID:       pane.ID(),
+		Title:    pane.Title(),
+		GridArea: layoutConfig.GridArea,
+		Template: pane.Template(),
+		Refresh:  int(pr.refresh[pane.ID()] / time.Second),
+	}
+
+	timeout := pr.timeout(pane.ID())
+	data, err := pr.load(ctx, pane, timeout)
+	if err != nil {
+		paneData.Error = paneError(err, timeout)
+		if ctx.Err() == nil { // Not worth logging when the browser went away
+			log.Printf("pane %s: %s: %v", pane.ID(), paneData.Error.Kind, err)
 		}
+		return paneData
+	}
+	paneData.Data = data
+	return paneData
+}
 
-		// Get layout config for this pane (required)
-		layoutConfig := pr.layout[paneID]
+func (pr *PaneRegistry) timeout(paneID string) time.Duration {
+	if d, exists := pr.timeouts[paneID]; exists && d > 0 {
+		return d
+	}
+	return DefaultPaneTimeout
+}
+
+// LoadData fetches one pane's data within its timeout, for serving it
+// outside a page
+func (pr *PaneRegistry) LoadData(ctx context.Context, pane models.Pane) (interface{}, error) {
+	return pr.load(ctx, pane, pr.timeout(pane.ID()))
+}
+
+// load fetches one pane's data, giving up at the pane's deadline
+func (pr *PaneRegistry) load(ctx context.Context, pane models.Pane, timeout time.Duration) (interface{}, error) {
+	return withDeadline(ctx, timeout, pane.GetData)
+}
+
+// withDeadline calls f, giving up after timeout even if f ignores ctx
+func withDeadline[T any](ctx context.Context, timeout time.Duration, f func(context.Context) (T, error)) (T, error) {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	type result struct {
+		value T
+		err   error
+	}
+	// Buffered so a pane that ignores ctx can still finish and exit
+	done := make(chan result, 1)
+	go func() {
+		value, err := f(ctx)
+		done <- result{value, err}
+	}()
+
+	select {
+	case r := <-done:
+		return r.value, r.err
+	case <-ctx.Done():
+		var zero T
+		return zero, ctx.Err()
+	}
+}
 
-		paneData = append(paneData, models.PaneData{
-			ID:       pane.ID(),
-			Title:    pane.Title(),
-			GridArea: layoutConfig.GridArea,
-			Data:     data,
-			Template: pane.Template(),
-		})
+// paneError classifies a load failure for display
+func paneError(err error, timeout time.Duration) *models.PaneError {
+	if errors.Is(err, context.DeadlineExceeded) {
+		return &models.PaneError{
+			Kind:    models.PaneErrorTimeout,
+			Message: fmt.Sprintf("Didn't load within %v", timeout),
+		}
+	}
+	var retry models.RetryAfterError
+	if errors.As(err, &retry) && retry.RetryAfter() > 0 {
+		return &models.PaneError{
+			Kind:       models.PaneErrorRateLimited,
+			Message:    "The provider asked to wait before trying again",
+			RetryAfter: int(math.Ceil(retry.RetryAfter().Seconds())),
+		}
+	}
+	return &models.PaneError{
+		Kind:    models.PaneErrorFailed,
+		Message: "Couldn't load data",
 	}
+}
 
-	// Panes are returned in configuration order
+// Panes returns every registered pane, ordered by ID
+func (pr *PaneRegistry) Panes() []models.Pane {
+	panes := make([]models.Pane, 0, len(pr.panes))
+	for _, pane := range pr.panes {
+		panes = append(panes, pane)
+	}
+	sort.Slice(panes, func(i, j int) bool {
+		return panes[i].ID() < panes[j].ID()
+	})
+	return panes
+}
 
-	return paneData, nil
+// IsEnabled reports whether a pane is shown on the page
+func (pr *PaneRegistry) IsEnabled(paneID string) bool {
+	return slices.Contains(pr.enabled, paneID)
 }
 
 // GetPane returns a specific pane by ID
 func (pr *PaneRegistry) GetPane(paneID string) (models.Pane, bool) {
 	pane, exists := pr.panes[paneID]
 	return pane, exists
-}
\ No newline at end of file
+}
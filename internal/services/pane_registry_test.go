@@ -2,7 +2,11 @@ package services
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"strings"
 	"testing"
+	"time"
 
 	"flexpane/internal/models"
 )
@@ -16,9 +20,9 @@ type MockPane struct {
 	err      error
 }
 
-func (m *MockPane) ID() string                                        { return m.id }
-func (m *MockPane) Title() string                                     { return m.title }
-func (m *MockPane) Template() string                                  { return m.template }
+func (m *MockPane) ID() string                                       { return m.id }
+func (m *MockPane) Title() string                                    { return m.title }
+func (m *MockPane) Template() string                                 { return m.template }
 func (m *MockPane) GetData(ctx context.Context) (interface{}, error) { return m.data, m.err }
 
 func TestPaneRegistry_RegisterPane(t *testing.T) {
@@ -69,4 +73,85 @@ func TestPaneRegistry_GetEnabledPanes(t *testing.T) {
 	}
 }
 
-// Removed ordering and error tests - not needed with simplified design
\ No newline at end of file
+// Removed ordering and error tests - not needed with simplified design
+
+// slowPane takes delay to load, or until its context ends
+type slowPane struct {
+	MockPane
+	delay time.Duration
+}
+
+func (s *slowPane) GetData(ctx context.Context) (interface{}, error) {
+	select {
+	case <-time.After(s.delay):
+		return s.data, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func TestPaneRegistry_LoadsPanesConcurrently(t *testing.T) {
+	registry := NewPaneRegistry()
+	registry.RegisterPane(&slowPane{MockPane{id: "a", data: "A"}, 100 * time.Millisecond})
+	registry.RegisterPane(&slowPane{MockPane{id: "b", data: "B"}, 100 * time.Millisecond})
+	registry.RegisterPane(&slowPane{MockPane{id: "stuck", data: "C"}, time.Hour})
+	registry.SetEnabledPanes([]string{"b", "stuck", "missing", "a"})
+	registry.SetTimeouts(map[string]time.Duration{"stuck": 150 * time.Millisecond})
+
+	start := time.Now()
+	paneData, err := registry.GetEnabledPanes(context.Background())
+	if err != nil {
+		t.Fatalf("GetEnabledPanes failed: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Expected panes to load in parallel, took %v", elapsed)
+	}
+
+	if len(paneData) != 3 || paneData[0].ID != "b" || paneData[1].ID != "stuck" || paneData[2].ID != "a" {
+		t.Fatalf("Expected configuration order, got %+v", paneData)
+	}
+	if paneData[0].Data != "B" || paneData[2].Data != "A" || paneData[0].Error != nil {
+		t.Errorf("Expected loaded panes, got %+v", paneData)
+	}
+	if paneData[1].Error == nil || paneData[1].Error.Kind != models.PaneErrorTimeout || paneData[1].Data != nil {
+		t.Errorf("Expected the stuck pane to time out, got %+v", paneData[1])
+	}
+}
+
+// rateLimitError asks to be retried later
+type rateLimitError struct{}
+
+func (rateLimitError) Error() string             { return "429 Too Many Requests" }
+func (rateLimitError) RetryAfter() time.Duration { return 90 * time.Second }
+
+func TestPaneRegistry_PaneErrors(t *testing.T) {
+	registry := NewPaneRegistry()
+	registry.RegisterPane(&MockPane{id: "broken", data: "partial", err: errors.New("connection refused")})
+	registry.RegisterPane(&MockPane{id: "limited", err: fmt.Errorf("caldav: %w", rateLimitError{})})
+	registry.RegisterPane(&MockPane{id: "hidden", data: "x"})
+	registry.SetEnabledPanes([]string{"broken", "limited"})
+
+	paneData, err := registry.GetEnabledPanes(context.Background())
+	if err != nil {
+		t.Fatalf("GetEnabledPanes failed: %v", err)
+	}
+	broken := paneData[0]
+	if broken.Data != nil || broken.Error == nil || broken.Error.Kind != models.PaneErrorFailed {
+		t.Errorf("Expected a failed state, got %+v", broken)
+	}
+	if strings.Contains(broken.Error.Message, "refused") {
+		t.Errorf("Expected the underlying error to stay out of the message, got %q", broken.Error.Message)
+	}
+	limited := paneData[1].Error
+	if limited == nil || limited.Kind != models.PaneErrorRateLimited || limited.RetryAfter != 90 {
+		t.Errorf("Expected a rate limited state, got %+v", limited)
+	}
+
+	// Single panes reload on their own, but only when enabled
+	if single, ok := registry.GetEnabledPane(context.Background(), "limited"); !ok || single.Error == nil {
+		t.Errorf("Expected the limited pane, got %+v", single)
+	}
+	if _, ok := registry.GetEnabledPane(context.Background(), "hidden"); ok {
+		t.Error("Expected disabled panes to be unavailable")
+	}
+}
@@ -0,0 +1,68 @@
+package services
+
+import (
+	"context"
+	"sync"
+)
+
+// Poller runs a background check, such as SnoozeService.Run, on behalf of
+// the panes that need it. Several panes may share one: it starts with the
+// first pane and stops with the last, so the check never runs twice at once
+// and nothing is notified twice.
+type Poller struct {
+	run func(ctx context.Context)
+
+	mutex  sync.Mutex
+	users  int
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// NewPoller returns a poller for run, which must return once ctx is
+// cancelled
+func NewPoller(run func(ctx context.Context)) *Poller {
+	return &Poller{run: run}
+}
+
+// Start runs the check if no other pane has already started it. It runs
+// until ctx is cancelled or every pane that started it has stopped it.
+func (p *Poller) Start(ctx context.Context) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	p.users++
+	if p.users > 1 {
+		return
+	}
+
+	ctx, p.cancel = context.WithCancel(ctx)
+	p.done = make(chan struct{})
+	go func(done chan struct{}) {
+		defer close(done)
+		p.run(ctx)
+	}(p.done)
+}
+
+// Stop releases a pane's use of the poller. When the last pane stops it,
+// the check is cancelled and Stop waits for it to return, or for ctx.
+func (p *Poller) Stop(ctx context.Context) error {
+	p.mutex.Lock()
+	if p.users == 0 {
+		p.mutex.Unlock()
+		return nil
+	}
+	p.users--
+	if p.users > 0 {
+		p.mutex.Unlock()
+		return nil
+	}
+	p.cancel()
+	done := p.done
+	p.mutex.Unlock()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,66 @@
+package services
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestPoller_SharedBetweenPanes(t *testing.T) {
+	var running, runs atomic.Int32
+	poller := NewPoller(func(ctx context.Context) {
+		runs.Add(1)
+		running.Add(1)
+		defer running.Add(-1)
+		<-ctx.Done()
+	})
+
+	ctx := context.Background()
+	poller.Start(ctx)
+	poller.Start(ctx)
+	waitFor(t, func() bool { return running.Load() == 1 })
+
+	// The first pane to stop leaves the check running for the other
+	if err := poller.Stop(ctx); err != nil {
+		t.Fatalf("Stop failed: %v", err)
+	}
+	if running.Load() != 1 {
+		t.Fatal("Expected the check to keep running while a pane uses it")
+	}
+
+	// The last one ends it and waits for it to return
+	if err := poller.Stop(ctx); err != nil {
+		t.Fatalf("Stop failed: %v", err)
+	}
+	if running.Load() != 0 {
+		t.Error("Expected the check to have returned")
+	}
+	if runs.Load() != 1 {
+		t.Errorf("Expected the check to run once, ran %d times", runs.Load())
+	}
+}
+
+func TestPoller_StopTimesOut(t *testing.T) {
+	release := make(chan struct{})
+	defer close(release)
+	poller := NewPoller(func(ctx context.Context) { <-release })
+	poller.Start(context.Background())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := poller.Stop(ctx); err != context.DeadlineExceeded {
+		t.Errorf("Expected the deadline, got %v", err)
+	}
+}
+
+func waitFor(t *testing.T, done func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for !done() {
+		if time.Now().After(deadline) {
+			t.Fatal("Timed out waiting")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
@@ -0,0 +1,239 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"flexpane/internal/models"
+)
+
+// DefaultQuickAddDuration is used when quick-add text doesn't say how long
+const DefaultQuickAddDuration = time.Hour
+
+var (
+	clockPattern    = regexp.MustCompile(`^(\d{1,2})(?::(\d{2}))?(am|pm)?$`)
+	durationPattern = regexp.MustCompile(`^(\d+h)?(\d+m)?$`)
+	isoDatePattern  = regexp.MustCompile(`^\d{4}-\d{2}-\d{2}$`)
+	dayPattern      = regexp.MustCompile(`^(\d{1,2})(st|nd|rd|th)?$`)
+)
+
+var weekdays = map[string]time.Weekday{
+	"sun": time.Sunday, "sunday": time.Sunday,
+	"mon": time.Monday, "monday": time.Monday,
+	"tue": time.Tuesday, "tues": time.Tuesday, "tuesday": time.Tuesday,
+	"wed": time.Wednesday, "wednesday": time.Wednesday,
+	"thu": time.Thursday, "thur": time.Thursday, "thurs": time.Thursday, "thursday": time.Thursday,
+	"fri": time.Friday, "friday": time.Friday,
+	"sat": time.Saturday, "saturday": time.Saturday,
+}
+
+// ParseQuickAdd turns text like "Lunch with Sam tomorrow 12:30 1h @Cafe" into
+// an event. Dates (today, tomorrow, weekdays, 2025-01-06, Jan 6), times
+// (12:30, 3pm, noon) and durations (1h, 45m, 1h30m) can appear anywhere;
+// words after an @ become the location and everything else is the title.
+// Weekdays only count as dates after the title, so "Sun Valley trip sat"
+// happens on Saturday. Without a time the event is all-day.
+func ParseQuickAdd(text string, now time.Time) (models.Event, error) {
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+
+	date := today
+	var clock *time.Duration
+	duration := time.Duration(0)
+	var title, location []string
+	inLocation := false
+
+	words := strings.Fields(text)
+	for i := 0; i < len(words); i++ {
+		word := words[i]
+		lower := quickAddWord(word)
+		next := ""
+		if i+1 < len(words) {
+			next = quickAddWord(words[i+1])
+		}
+
+		// Connecting words only count when they introduce something we parse
+		if lower == "at" || lower == "on" || lower == "for" || lower == "next" {
+			if _, ok := parseClock(next); ok {
+				continue
+			}
+			if _, ok := parseDuration(next); ok && lower == "for" {
+				continue
+			}
+			if _, ok := weekdayDate(words, i+1, len(title) > 0); ok {
+				continue
+			}
+		}
+
+		switch {
+		case lower == "today" || lower == "tonight":
+			date = today
+			continue
+		case lower == "tomorrow":
+			date = today.AddDate(0, 0, 1)
+			continue
+		case isoDatePattern.MatchString(lower):
+			parsed, err := time.ParseInLocation("2006-01-02", lower, now.Location())
+			if err != nil {
+				return models.Event{}, fmt.Errorf("%s is not a date", lower)
+			}
+			date = parsed
+			continue
+		}
+		if weekday, ok := weekdayDate(words, i, len(title) > 0); ok {
+			ahead := (int(weekday) - int(today.Weekday()) + 7) % 7
+			if ahead == 0 {
+				ahead = 7
+			}
+			date = today.AddDate(0, 0, ahead)
+			continue
+		}
+		if month, ok := parseMonth(lower); ok {
+			if match := dayPattern.FindStringSubmatch(next); match != nil {
+				day, _ := strconv.Atoi(match[1])
+				date = time.Date(today.Year(), month, day, 0, 0, 0, 0, now.Location())
+				if date.Month() != month {
+					// time.Date would roll "feb 31" over into March
+					return models.Event{}, fmt.Errorf("%s %d is not a date", month, day)
+				}
+				if date.Before(today) {
+					date = date.AddDate(1, 0, 0)
+				}
+				i++
+				continue
+			}
+		}
+		if offset, ok := parseClock(lower); ok {
+			clock = &offset
+			continue
+		}
+		if d, ok := parseDuration(lower); ok {
+			duration = d
+			continue
+		}
+
+		if strings.HasPrefix(word, "@") {
+			inLocation = true
+			word = strings.TrimPrefix(word, "@")
+			if word == "" {
+				continue
+			}
+		}
+		if inLocation {
+			location = append(location, word)
+		} else {
+			title = append(title, word)
+		}
+	}
+
+	if len(title) == 0 {
+		return models.Event{}, errors.New("quick add needs a title")
+	}
+
+	event := models.Event{
+		Title:    strings.Join(title, " "),
+		Location: strings.Join(location, " "),
+	}
+	if clock == nil {
+		event.AllDay = true
+		event.Start = date
+		event.End = date.AddDate(0, 0, 1)
+		return event, nil
+	}
+
+	if duration == 0 {
+		duration = DefaultQuickAddDuration
+	}
+	// Built from the wall clock, since midnight plus a duration is off by an
+	// hour on days the clocks change
+	event.Start = time.Date(date.Year(), date.Month(), date.Day(), int(*clock/time.Hour), int(*clock%time.Hour/time.Minute), 0, 0, date.Location())
+	event.End = event.Start.Add(duration)
+	return event, nil
+}
+
+// quickAddWord normalizes a word for matching
+func quickAddWord(word string) string {
+	return strings.ToLower(strings.TrimRight(word, ","))
+}
+
+// weekdayDate returns the weekday words[i] names when it stands on its own as
+// a date: after the title, and at the end, before a comma or before another
+// part quick add parses. Otherwise it belongs to the title.
+func weekdayDate(words []string, i int, afterTitle bool) (time.Weekday, bool) {
+	if !afterTitle || i >= len(words) {
+		return 0, false
+	}
+	weekday, ok := weekdays[quickAddWord(words[i])]
+	if !ok {
+		return 0, false
+	}
+	if i+1 == len(words) || strings.HasSuffix(words[i], ",") {
+		return weekday, true
+	}
+
+	next := quickAddWord(words[i+1])
+	_, isClock := parseClock(next)
+	_, isDuration := parseDuration(next)
+	standalone := isClock || isDuration || next == "at" || next == "for" || strings.HasPrefix(next, "@")
+	return weekday, standalone
+}
+
+// parseClock returns the offset from midnight for times like 9, 9am, 12:30 or 3:15pm
+func parseClock(word string) (time.Duration, bool) {
+	switch word {
+	case "noon", "midday":
+		return 12 * time.Hour, true
+	case "midnight":
+		return 0, true
+	}
+
+	match := clockPattern.FindStringSubmatch(word)
+	if match == nil || (match[2] == "" && match[3] == "") {
+		return 0, false // A bare number is too ambiguous
+	}
+
+	hour, _ := strconv.Atoi(match[1])
+	minute := 0
+	if match[2] != "" {
+		minute, _ = strconv.Atoi(match[2])
+	}
+	switch match[3] {
+	case "am":
+		if hour == 12 {
+			hour = 0
+		}
+	case "pm":
+		if hour < 12 {
+			hour += 12
+		}
+	}
+	if hour > 23 || minute > 59 {
+		return 0, false
+	}
+
+	return time.Duration(hour)*time.Hour + time.Duration(minute)*time.Minute, true
+}
+
+func parseDuration(word string) (time.Duration, bool) {
+	word = strings.NewReplacer("hrs", "h", "hr", "h", "mins", "m", "min", "m").Replace(word)
+	if word == "" || !durationPattern.MatchString(word) {
+		return 0, false
+	}
+	d, err := time.ParseDuration(word)
+	return d, err == nil && d > 0
+}
+
+func parseMonth(word string) (time.Month, bool) {
+	if len(word) < 3 {
+		return 0, false
+	}
+	for month := time.January; month <= time.December; month++ {
+		if strings.HasPrefix(strings.ToLower(month.String()), word) {
+			return month, true
+		}
+	}
+	return 0, false
+}
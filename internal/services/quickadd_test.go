@@ -0,0 +1,132 @@
+package services
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseQuickAdd(t *testing.T) {
+	// Monday 6 January 2025, 08:00
+	now := time.Date(2025, 1, 6, 8, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		text     string
+		title    string
+		location string
+		start    time.Time
+		end      time.Time
+		allDay   bool
+	}{
+		{
+			text:  "Lunch with Sam tomorrow 12:30 1h",
+			title: "Lunch with Sam",
+			start: time.Date(2025, 1, 7, 12, 30, 0, 0, time.UTC),
+			end:   time.Date(2025, 1, 7, 13, 30, 0, 0, time.UTC),
+		},
+		{
+			text:     "Dentist friday at 3pm for 45m @Main Street Clinic",
+			title:    "Dentist",
+			location: "Main Street Clinic",
+			start:    time.Date(2025, 1, 10, 15, 0, 0, 0, time.UTC),
+			end:      time.Date(2025, 1, 10, 15, 45, 0, 0, time.UTC),
+		},
+		{
+			text:  "Planning monday 9am 1h30m",
+			title: "Planning",
+			start: time.Date(2025, 1, 13, 9, 0, 0, 0, time.UTC),
+			end:   time.Date(2025, 1, 13, 10, 30, 0, 0, time.UTC),
+		},
+		{
+			text:   "Conference 2025-02-03",
+			title:  "Conference",
+			start:  time.Date(2025, 2, 3, 0, 0, 0, 0, time.UTC),
+			end:    time.Date(2025, 2, 4, 0, 0, 0, 0, time.UTC),
+			allDay: true,
+		},
+		{
+			text:  "Standup noon",
+			title: "Standup",
+			start: time.Date(2025, 1, 6, 12, 0, 0, 0, time.UTC),
+			end:   time.Date(2025, 1, 6, 13, 0, 0, 0, time.UTC),
+		},
+		{
+			text:   "Birthday party jan 2",
+			title:  "Birthday party",
+			start:  time.Date(2026, 1, 2, 0, 0, 0, 0, time.UTC),
+			end:    time.Date(2026, 1, 3, 0, 0, 0, 0, time.UTC),
+			allDay: true,
+		},
+		{
+			text:   "Sun Valley trip sat",
+			title:  "Sun Valley trip",
+			start:  time.Date(2025, 1, 11, 0, 0, 0, 0, time.UTC),
+			end:    time.Date(2025, 1, 12, 0, 0, 0, 0, time.UTC),
+			allDay: true,
+		},
+		{
+			text:  "Trip to Sun Valley 9am",
+			title: "Trip to Sun Valley",
+			start: time.Date(2025, 1, 6, 9, 0, 0, 0, time.UTC),
+			end:   time.Date(2025, 1, 6, 10, 0, 0, 0, time.UTC),
+		},
+		{
+			text:  "Meet at 10:15 in lobby",
+			title: "Meet in lobby",
+			start: time.Date(2025, 1, 6, 10, 15, 0, 0, time.UTC),
+			end:   time.Date(2025, 1, 6, 11, 15, 0, 0, time.UTC),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.text, func(t *testing.T) {
+			event, err := ParseQuickAdd(tt.text, now)
+			if err != nil {
+				t.Fatalf("ParseQuickAdd failed: %v", err)
+			}
+			if event.Title != tt.title {
+				t.Errorf("Expected title %q, got %q", tt.title, event.Title)
+			}
+			if event.Location != tt.location {
+				t.Errorf("Expected location %q, got %q", tt.location, event.Location)
+			}
+			if !event.Start.Equal(tt.start) || !event.End.Equal(tt.end) {
+				t.Errorf("Expected %v - %v, got %v - %v", tt.start, tt.end, event.Start, event.End)
+			}
+			if event.AllDay != tt.allDay {
+				t.Errorf("Expected all-day %v, got %v", tt.allDay, event.AllDay)
+			}
+		})
+	}
+}
+
+func TestParseQuickAdd_DaylightSaving(t *testing.T) {
+	loc, err := time.LoadLocation("Europe/London")
+	if err != nil {
+		t.Skipf("Time zone data unavailable: %v", err)
+	}
+	// Clocks go forward at 01:00 on Sunday 30 March 2025
+	now := time.Date(2025, 3, 29, 12, 0, 0, 0, loc)
+
+	event, err := ParseQuickAdd("Brunch tomorrow 11am", now)
+	if err != nil {
+		t.Fatalf("ParseQuickAdd failed: %v", err)
+	}
+	if want := time.Date(2025, 3, 30, 11, 0, 0, 0, loc); !event.Start.Equal(want) {
+		t.Errorf("Expected start %v, got %v", want, event.Start)
+	}
+}
+
+func TestParseQuickAdd_InvalidDate(t *testing.T) {
+	now := time.Date(2025, 1, 6, 8, 0, 0, 0, time.UTC)
+	for _, text := range []string{"Party feb 31 8pm", "Party 2026-02-30 8pm", "Party 2026-13-01"} {
+		if event, err := ParseQuickAdd(text, now); err == nil {
+			t.Errorf("Expected an error for %q, got %+v", text, event)
+		}
+	}
+}
+
+func TestParseQuickAdd_RequiresTitle(t *testing.T) {
+	if _, err := ParseQuickAdd("tomorrow 3pm", time.Now()); err == nil {
+		t.Error("Expected error when only a date and time are given")
+	}
+}
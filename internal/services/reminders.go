@@ -0,0 +1,324 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"flexpane/internal/models"
+)
+
+// What a reminder rule watches
+const (
+	ReminderOnEvent = "event" // Timed calendar events, relative to their start
+	ReminderOnTodo  = "todo"  // Open todos, relative to their due time
+)
+
+// DefaultReminderInterval is how often reminders are evaluated
+const DefaultReminderInterval = time.Minute
+
+// reminderGrace bounds how late a reminder may still be delivered, so a
+// restart or an offline channel doesn't fire a burst of stale alerts
+const reminderGrace = 15 * time.Minute
+
+// ReminderRule fires a notification Before an event starts or a todo is due,
+// delivered through the named channels
+type ReminderRule struct {
+	On       string   `json:"on"`
+	Before   string   `json:"before,omitempty"` // Duration, e.g. "10m"; empty means at the time itself
+	Channels []string `json:"channels"`
+}
+
+// Validate checks the rule's kind and offset
+func (r ReminderRule) Validate() error {
+	if r.On != ReminderOnEvent && r.On != ReminderOnTodo {
+		return fmt.Errorf("invalid reminder on %q: expected %q or %q", r.On, ReminderOnEvent, ReminderOnTodo)
+	}
+	if _, err := r.offset(); err != nil {
+		return fmt.Errorf("invalid reminder before %q: %w", r.Before, err)
+	}
+	if len(r.Channels) == 0 {
+		return errors.New("reminder rule needs at least one channel")
+	}
+	return nil
+}
+
+func (r ReminderRule) offset() (time.Duration, error) {
+	if r.Before == "" {
+		return 0, nil
+	}
+	return time.ParseDuration(r.Before)
+}
+
+// Notification is one alert sent to a channel. ID identifies the reminder
+// (item and trigger time) and is what clients pass back to snooze it.
+type Notification struct {
+	ID    string    `json:"id"`
+	Title string    `json:"title"`
+	Body  string    `json:"body"`
+	Time  time.Time `json:"time"` // When the event starts or the todo is due
+	URL   string    `json:"url,omitempty"`
+}
+
+// Notifier delivers notifications through one channel
+type Notifier interface {
+	Notify(ctx context.Context, notification Notification) error
+}
+
+// ErrNoRecipients is returned by channels with nobody to deliver to right
+// now. The reminder stays pending and is retried without logging.
+var ErrNoRecipients = errors.New("no recipients connected")
+
+// pendingReminder remembers a delivered reminder so it can be snoozed
+type pendingReminder struct {
+	notification Notification
+	channels     []string
+	until        time.Time // Zero unless snoozed
+}
+
+// ReminderService evaluates reminder rules against upcoming events and due
+// todos and delivers each reminder once per channel
+type ReminderService struct {
+	sources  []EventSource
+	todos    *TodoService
+	rules    []ReminderRule
+	channels map[string]Notifier
+	location *time.Location
+	now      func() time.Time
+
+	mutex     sync.Mutex
+	sent      map[string]time.Time // Dedupe key -> trigger time
+	delivered map[string]*pendingReminder
+}
+
+// NewReminderService creates a reminder service. location anchors all-day
+// todo due dates to the user's midnight.
+func NewReminderService(sources []EventSource, todos *TodoService, rules []ReminderRule, channels map[string]Notifier, location *time.Location) *ReminderService {
+	if location == nil {
+		location = time.Local
+	}
+	return &ReminderService{
+		sources:   sources,
+		todos:     todos,
+		rules:     rules,
+		channels:  channels,
+		location:  location,
+		now:       time.Now,
+		sent:      map[string]time.Time{},
+		delivered: map[string]*pendingReminder{},
+	}
+}
+
+// Run checks reminders every interval until ctx is cancelled
+func (s *ReminderService) Run(ctx context.Context, interval time.Duration) {
+	if interval <= 0 {
+		interval = DefaultReminderInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		s.Check(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Check delivers every reminder that is due and not yet sent, plus snoozed
+// reminders whose snooze has ended
+func (s *ReminderService) Check(ctx context.Context) {
+	now := s.now()
+
+	var events []models.Event
+	for _, source := range s.sources {
+		sourceEvents, err := source.GetCalendarEvents()
+		if err != nil {
+			log.Printf("reminders: skipping source: %v", err)
+			continue
+		}
+		events = append(events, sourceEvents...)
+	}
+	var todos []models.Todo
+	if s.todos != nil {
+		todos = s.todos.GetTodos()
+	}
+
+	for _, rule := range s.rules {
+		offset, err := rule.offset()
+		if err != nil {
+			continue
+		}
+
+		switch rule.On {
+		case ReminderOnEvent:
+			for _, event := range events {
+				if event.AllDay {
+					continue
+				}
+				s.fire(ctx, rule, eventNotification(event, offset), event.Start.Add(-offset), now)
+			}
+		case ReminderOnTodo:
+			for _, todo := range todos {
+				if todo.Done || todo.Due == nil {
+					continue
+				}
+				notification := todoNotification(todo, s.dueTime(todo), offset)
+				s.fire(ctx, rule, notification, notification.Time.Add(-offset), now)
+			}
+		}
+	}
+
+	s.resendSnoozed(ctx, now)
+	s.prune(now)
+}
+
+// Snooze suppresses a delivered reminder until the given time, then sends it
+// again through the same channels
+func (s *ReminderService) Snooze(id string, until time.Time) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	pending, exists := s.delivered[id]
+	if !exists {
+		return fmt.Errorf("unknown reminder %q", id)
+	}
+	pending.until = until
+	return nil
+}
+
+func (s *ReminderService) fire(ctx context.Context, rule ReminderRule, notification Notification, trigger, now time.Time) {
+	if now.Before(trigger) || !now.Before(trigger.Add(reminderGrace)) {
+		return
+	}
+
+	for _, name := range rule.Channels {
+		key := notification.ID + "|" + name
+
+		s.mutex.Lock()
+		_, done := s.sent[key]
+		s.mutex.Unlock()
+		if done {
+			continue
+		}
+
+		if !s.deliver(ctx, name, notification) {
+			continue
+		}
+
+		s.mutex.Lock()
+		s.sent[key] = trigger
+		pending, exists := s.delivered[notification.ID]
+		if !exists {
+			pending = &pendingReminder{notification: notification}
+			s.delivered[notification.ID] = pending
+		}
+		pending.channels = appendUnique(pending.channels, name)
+		s.mutex.Unlock()
+	}
+}
+
+func (s *ReminderService) resendSnoozed(ctx context.Context, now time.Time) {
+	s.mutex.Lock()
+	var due []*pendingReminder
+	for _, pending := range s.delivered {
+		if !pending.until.IsZero() && !now.Before(pending.until) {
+			pending.until = time.Time{}
+			due = append(due, pending)
+		}
+	}
+	s.mutex.Unlock()
+
+	for _, pending := range due {
+		for _, name := range pending.channels {
+			s.deliver(ctx, name, pending.notification)
+		}
+	}
+}
+
+// deliver sends through one channel and reports whether it succeeded
+func (s *ReminderService) deliver(ctx context.Context, name string, notification Notification) bool {
+	channel, exists := s.channels[name]
+	if !exists {
+		log.Printf("reminders: unknown channel %q", name)
+		return false
+	}
+	if err := channel.Notify(ctx, notification); err != nil {
+		if !errors.Is(err, ErrNoRecipients) {
+			log.Printf("reminders: %s: %v", name, err)
+		}
+		return false
+	}
+	return true
+}
+
+// prune forgets reminders long past their trigger
+func (s *ReminderService) prune(now time.Time) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	cutoff := now.Add(-24 * time.Hour)
+	for key, trigger := range s.sent {
+		if trigger.Before(cutoff) {
+			delete(s.sent, key)
+		}
+	}
+	for id, pending := range s.delivered {
+		if pending.until.IsZero() && pending.notification.Time.Before(cutoff) {
+			delete(s.delivered, id)
+		}
+	}
+}
+
+// dueTime anchors all-day due dates to midnight in the user's zone
+func (s *ReminderService) dueTime(todo models.Todo) time.Time {
+	due := *todo.Due
+	if todo.DueAllDay {
+		return time.Date(due.Year(), due.Month(), due.Day(), 0, 0, 0, 0, s.location)
+	}
+	return due
+}
+
+func eventNotification(event models.Event, offset time.Duration) Notification {
+	body := "Starts now"
+	if offset > 0 {
+		body = "Starts in " + FormatCountdown(offset)
+	}
+	if event.Location != "" {
+		body += " · " + event.Location
+	}
+	return Notification{
+		ID:    fmt.Sprintf("event:%s:%d", event.ID, event.Start.Add(-offset).Unix()),
+		Title: event.Title,
+		Body:  body,
+		Time:  event.Start,
+		URL:   JoinLink(event),
+	}
+}
+
+func todoNotification(todo models.Todo, due time.Time, offset time.Duration) Notification {
+	body := "Due now"
+	if offset > 0 {
+		body = "Due in " + FormatCountdown(offset)
+	}
+	return Notification{
+		ID:    fmt.Sprintf("todo:%s:%d", todo.ID, due.Add(-offset).Unix()),
+		Title: todo.Message,
+		Body:  body,
+		Time:  due,
+	}
+}
+
+func appendUnique(list []string, value string) []string {
+	for _, existing := range list {
+		if existing == value {
+			return list
+		}
+	}
+	return append(list, value)
+}
@@ -0,0 +1,174 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// recordingNotifier collects notifications and can simulate an outage
+type recordingNotifier struct {
+	received []Notification
+	err      error
+}
+
+func (r *recordingNotifier) Notify(ctx context.Context, notification Notification) error {
+	if r.err != nil {
+		return r.err
+	}
+	r.received = append(r.received, notification)
+	return nil
+}
+
+func TestReminderService_EventsAndTodos(t *testing.T) {
+	calendar := fakeSource{
+		{ID: "standup", Title: "Standup", Start: at(6, 9, 0), End: at(6, 9, 15), Location: "https://zoom.us/j/1"},
+		{ID: "holiday", Title: "Holiday", Start: at(6, 0, 0), End: at(7, 0, 0), AllDay: true},
+	}
+	todos := NewTodoService(filepath.Join(t.TempDir(), "todos.json"))
+	due := at(6, 0, 0)
+	todos.AddTodoDue("Pay rent", &due, true)
+	deadline := at(6, 8, 50)
+	todos.AddTodoDue("Send slides", &deadline, false)
+
+	browser := &recordingNotifier{}
+	webhook := &recordingNotifier{}
+	rules := []ReminderRule{
+		{On: ReminderOnEvent, Before: "10m", Channels: []string{"browser", "webhook"}},
+		{On: ReminderOnTodo, Channels: []string{"browser"}},
+	}
+	service := NewReminderService([]EventSource{calendar}, todos, rules, map[string]Notifier{"browser": browser, "webhook": webhook}, time.UTC)
+
+	now := at(6, 8, 45)
+	service.now = func() time.Time { return now }
+
+	service.Check(context.Background())
+	if len(browser.received) != 0 {
+		t.Fatalf("Expected nothing before the reminder window, got %+v", browser.received)
+	}
+
+	now = at(6, 8, 51)
+	service.Check(context.Background())
+	service.Check(context.Background()) // Deduplicated
+
+	if len(browser.received) != 2 || len(webhook.received) != 1 {
+		t.Fatalf("Expected one event reminder per channel and one todo reminder, got %+v / %+v", browser.received, webhook.received)
+	}
+	if got := browser.received[0]; got.Title != "Standup" || got.URL != "https://zoom.us/j/1" || got.Body != "Starts in 10m · https://zoom.us/j/1" {
+		t.Errorf("Unexpected notification %+v", got)
+	}
+
+	if got := browser.received[1]; got.Title != "Send slides" || got.Body != "Due now" {
+		t.Errorf("Unexpected todo notification %+v", got)
+	}
+	// The all-day todo was due at midnight, long past the grace period
+}
+
+func TestReminderService_RetriesFailedChannel(t *testing.T) {
+	calendar := fakeSource{{ID: "review", Title: "Review", Start: at(6, 14, 0), End: at(6, 15, 0)}}
+	browser := &recordingNotifier{err: ErrNoRecipients}
+	rules := []ReminderRule{{On: ReminderOnEvent, Channels: []string{"browser"}}}
+	service := NewReminderService([]EventSource{calendar}, nil, rules, map[string]Notifier{"browser": browser}, time.UTC)
+
+	service.now = func() time.Time { return at(6, 14, 0) }
+	service.Check(context.Background())
+
+	browser.err = nil
+	service.now = func() time.Time { return at(6, 14, 5) }
+	service.Check(context.Background())
+
+	if len(browser.received) != 1 || browser.received[0].Body != "Starts now" {
+		t.Errorf("Expected reminder once a tab connects, got %+v", browser.received)
+	}
+}
+
+func TestReminderService_Snooze(t *testing.T) {
+	calendar := fakeSource{{ID: "review", Title: "Review", Start: at(6, 14, 0), End: at(6, 15, 0)}}
+	browser := &recordingNotifier{}
+	rules := []ReminderRule{{On: ReminderOnEvent, Before: "5m", Channels: []string{"browser"}}}
+	service := NewReminderService([]EventSource{calendar}, nil, rules, map[string]Notifier{"browser": browser}, time.UTC)
+
+	now := at(6, 13, 55)
+	service.now = func() time.Time { return now }
+	service.Check(context.Background())
+
+	if err := service.Snooze(browser.received[0].ID, at(6, 14, 2)); err != nil {
+		t.Fatalf("Snooze failed: %v", err)
+	}
+	if err := service.Snooze("unknown", at(6, 14, 2)); err == nil {
+		t.Error("Expected error snoozing an unknown reminder")
+	}
+
+	now = at(6, 14, 0)
+	service.Check(context.Background())
+	if len(browser.received) != 1 {
+		t.Fatalf("Expected no delivery while snoozed, got %d", len(browser.received))
+	}
+
+	now = at(6, 14, 2)
+	service.Check(context.Background())
+	service.Check(context.Background())
+	if len(browser.received) != 2 {
+		t.Errorf("Expected exactly one redelivery after the snooze, got %d", len(browser.received))
+	}
+}
+
+func TestReminderRule_Validate(t *testing.T) {
+	invalid := []ReminderRule{
+		{On: "meeting", Channels: []string{"browser"}},
+		{On: ReminderOnEvent, Before: "soon", Channels: []string{"browser"}},
+		{On: ReminderOnTodo},
+	}
+	for _, rule := range invalid {
+		if rule.Validate() == nil {
+			t.Errorf("Expected %+v to be invalid", rule)
+		}
+	}
+}
+
+func TestWebhookNotifier(t *testing.T) {
+	var received Notification
+	var auth string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auth = r.Header.Get("Authorization")
+		json.NewDecoder(r.Body).Decode(&received)
+	}))
+	defer server.Close()
+
+	notifier := NewWebhookNotifier(server.URL, map[string]string{"Authorization": "Bearer abc"})
+	if err := notifier.Notify(context.Background(), Notification{ID: "event:1:0", Title: "Standup"}); err != nil {
+		t.Fatalf("Notify failed: %v", err)
+	}
+	if received.Title != "Standup" || auth != "Bearer abc" {
+		t.Errorf("Unexpected webhook request %+v (auth %q)", received, auth)
+	}
+
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(503)
+	}))
+	defer failing.Close()
+
+	if err := NewWebhookNotifier(failing.URL, nil).Notify(context.Background(), Notification{}); err == nil {
+		t.Error("Expected error for a failing webhook")
+	}
+}
+
+func TestBrowserNotifier(t *testing.T) {
+	bus := NewEventBus()
+	browser := NewBrowserNotifier(bus)
+	if err := browser.Notify(context.Background(), Notification{}); err != ErrNoRecipients {
+		t.Errorf("Expected ErrNoRecipients without tabs, got %v", err)
+	}
+
+	sub := bus.Subscribe("")
+	defer sub.Close()
+
+	browser.Notify(context.Background(), Notification{Title: "Standup"})
+	if got := <-sub.Events; got.Kind != EventNotification || got.Notification.Title != "Standup" {
+		t.Errorf("Expected notification on subscriber, got %+v", got)
+	}
+}
@@ -0,0 +1,280 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Kinds of item that can be snoozed
+const (
+	SnoozeEmail = "email"
+	SnoozeTodo  = "todo"
+)
+
+// Snooze hides an item until a later time. Once the time passes the item
+// has woken: it shows at the top of its pane until it is acted on.
+type Snooze struct {
+	Kind     string    `json:"kind"`
+	ID       string    `json:"id"`
+	Title    string    `json:"title"` // Used for the notification
+	Until    time.Time `json:"until"`
+	Notify   bool      `json:"notify,omitempty"`
+	Notified bool      `json:"notified,omitempty"`
+}
+
+// SnoozeService keeps snoozed emails and todos in Flexpane's own store,
+// since mail providers have no notion of snoozing
+type SnoozeService struct {
+	filename string
+	notifier Notifier
+	events   *EventBus
+	now      func() time.Time
+
+	mutex     sync.Mutex
+	snoozes   []Snooze
+	lastCheck time.Time
+}
+
+func NewSnoozeService(filename string) *SnoozeService {
+	service := &SnoozeService{
+		filename: filename,
+		now:      time.Now,
+	}
+	if err := service.load(); err != nil {
+		log.Printf("snooze: %v", err)
+	}
+	return service
+}
+
+// SetNotifier sets where notifications go when a snooze with Notify ends
+func (s *SnoozeService) SetNotifier(notifier Notifier) {
+	s.notifier = notifier
+}
+
+// SetEvents sets where snoozing and waking are announced, so open pages hide
+// and show items
+func (s *SnoozeService) SetEvents(events *EventBus) {
+	s.events = events
+}
+
+// Snooze hides an item until the given time, replacing any earlier snooze
+func (s *SnoozeService) Snooze(kind, id, title string, until time.Time, notify bool) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.remove(kind, id)
+	s.snoozes = append(s.snoozes, Snooze{Kind: kind, ID: id, Title: title, Until: until, Notify: notify})
+	if err := s.save(); err != nil {
+		return err
+	}
+	s.events.Publish(EventSnoozeChanged, snoozePane(kind))
+	return nil
+}
+
+// Clear forgets an item's snooze, whether or not it has woken. Clearing an
+// item that isn't snoozed does nothing.
+func (s *SnoozeService) Clear(kind, id string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if !s.remove(kind, id) {
+		return nil
+	}
+	if err := s.save(); err != nil {
+		return err
+	}
+	s.events.Publish(EventSnoozeChanged, snoozePane(kind))
+	return nil
+}
+
+// Arrange orders a pane's items, given their IDs in display order. It
+// returns the positions to show, woken items first with the most recently
+// woken at the top, and the IDs of those woken items. Snoozed items are left
+// out, and woken snoozes for items that no longer exist are forgotten.
+func (s *SnoozeService) Arrange(kind string, ids []string) ([]int, map[string]bool) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	now := s.now()
+	present := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		present[id] = true
+	}
+
+	hidden := map[string]bool{}
+	var woken []Snooze
+	kept := s.snoozes[:0]
+	changed := false
+	for _, snooze := range s.snoozes {
+		if snooze.Kind == kind {
+			switch {
+			case now.Before(snooze.Until):
+				hidden[snooze.ID] = true
+			case !present[snooze.ID]:
+				changed = true
+				continue
+			default:
+				woken = append(woken, snooze)
+			}
+		}
+		kept = append(kept, snooze)
+	}
+	s.snoozes = kept
+	if changed {
+		if err := s.save(); err != nil {
+			log.Printf("snooze: %v", err)
+		}
+	}
+
+	// The item that woke most recently goes first
+	sort.SliceStable(woken, func(i, j int) bool {
+		return woken[i].Until.After(woken[j].Until)
+	})
+
+	position := make(map[string]int, len(ids))
+	for i, id := range ids {
+		position[id] = i
+	}
+	order := make([]int, 0, len(ids))
+	woke := make(map[string]bool, len(woken))
+	for _, snooze := range woken {
+		order = append(order, position[snooze.ID])
+		woke[snooze.ID] = true
+	}
+	for i, id := range ids {
+		if !hidden[id] && !woke[id] {
+			order = append(order, i)
+		}
+	}
+	return order, woke
+}
+
+// Run sends notifications for ended snoozes every interval until ctx is
+// cancelled
+func (s *SnoozeService) Run(ctx context.Context, interval time.Duration) {
+	if interval <= 0 {
+		interval = DefaultReminderInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		s.Check(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Check announces snoozes that have ended since the last check and notifies
+// about them. Undelivered notifications are retried until they are too stale
+// to be useful.
+func (s *SnoozeService) Check(ctx context.Context) {
+	now := s.now()
+
+	s.mutex.Lock()
+	var due []Snooze
+	woken := map[string]bool{}
+	for _, snooze := range s.snoozes {
+		if snooze.Until.After(s.lastCheck) && !now.Before(snooze.Until) {
+			woken[snooze.Kind] = true
+		}
+		if snooze.Notify && !snooze.Notified && !now.Before(snooze.Until) {
+			due = append(due, snooze)
+		}
+	}
+	s.lastCheck = now
+	s.mutex.Unlock()
+
+	for kind := range woken {
+		s.events.Publish(EventSnoozeChanged, snoozePane(kind))
+	}
+	if s.notifier == nil {
+		return
+	}
+
+	for _, snooze := range due {
+		err := s.notifier.Notify(ctx, snoozeNotification(snooze))
+		if err != nil && !errors.Is(err, ErrNoRecipients) {
+			log.Printf("snooze: %v", err)
+		}
+		if err != nil && now.Before(snooze.Until.Add(reminderGrace)) {
+			continue
+		}
+
+		s.mutex.Lock()
+		for i := range s.snoozes {
+			if s.snoozes[i].Kind == snooze.Kind && s.snoozes[i].ID == snooze.ID && s.snoozes[i].Until.Equal(snooze.Until) {
+				s.snoozes[i].Notified = true
+			}
+		}
+		if err := s.save(); err != nil {
+			log.Printf("snooze: %v", err)
+		}
+		s.mutex.Unlock()
+	}
+}
+
+// snoozePane returns the ID of the pane that lists a kind of item
+func snoozePane(kind string) string {
+	if kind == SnoozeTodo {
+		return "todos"
+	}
+	return kind
+}
+
+func snoozeNotification(snooze Snooze) Notification {
+	notification := Notification{
+		ID:    "snooze:" + snooze.Kind + ":" + snooze.ID,
+		Title: snooze.Title,
+		Body:  "Back from snooze",
+		Time:  snooze.Until,
+	}
+	if snooze.Kind == SnoozeEmail {
+		notification.URL = "/?email.message=" + url.QueryEscape(snooze.ID)
+	}
+	return notification
+}
+
+// remove deletes an item's snooze and reports whether there was one
+func (s *SnoozeService) remove(kind, id string) bool {
+	for i, snooze := range s.snoozes {
+		if snooze.Kind == kind && snooze.ID == id {
+			s.snoozes = append(s.snoozes[:i], s.snoozes[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+func (s *SnoozeService) load() error {
+	data, err := os.ReadFile(s.filename)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, &s.snoozes)
+}
+
+func (s *SnoozeService) save() error {
+	if err := os.MkdirAll(filepath.Dir(s.filename), 0755); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(s.snoozes, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(s.filename, data, 0644)
+}
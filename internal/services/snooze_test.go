@@ -0,0 +1,73 @@
+package services
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestSnoozeService_Arrange(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "snoozes.json")
+	service := NewSnoozeService(path)
+	now := at(6, 9, 0)
+	service.now = func() time.Time { return now }
+
+	service.Snooze(SnoozeEmail, "b", "B", at(6, 10, 0), false)
+	service.Snooze(SnoozeEmail, "c", "C", at(6, 11, 0), false)
+	service.Snooze(SnoozeTodo, "a", "Todo A", at(6, 10, 0), false) // Other kinds don't interfere
+
+	ids := []string{"a", "b", "c", "d"}
+	order, woke := service.Arrange(SnoozeEmail, ids)
+	if len(order) != 2 || order[0] != 0 || order[1] != 3 || len(woke) != 0 {
+		t.Fatalf("Expected snoozed emails hidden, got %v %v", order, woke)
+	}
+
+	// Snoozes persist, and woken items come back at the top, latest first
+	service = NewSnoozeService(path)
+	now = at(6, 12, 0)
+	service.now = func() time.Time { return now }
+	order, woke = service.Arrange(SnoozeEmail, ids)
+	if len(order) != 4 || order[0] != 2 || order[1] != 1 || order[2] != 0 || !woke["b"] || !woke["c"] {
+		t.Fatalf("Expected woken emails first, got %v %v", order, woke)
+	}
+
+	// Acting on an item settles it, and woken snoozes for items that are
+	// gone are forgotten
+	service.Clear(SnoozeEmail, "b")
+	order, woke = service.Arrange(SnoozeEmail, []string{"a", "b", "d"})
+	if len(order) != 3 || order[0] != 0 || len(woke) != 0 {
+		t.Fatalf("Expected the original order, got %v %v", order, woke)
+	}
+	if _, woke := service.Arrange(SnoozeEmail, []string{"c"}); len(woke) != 0 {
+		t.Errorf("Expected the snooze for a missing email forgotten, got %v", woke)
+	}
+	if _, woke := service.Arrange(SnoozeTodo, []string{"a"}); !woke["a"] {
+		t.Errorf("Expected the todo to wake, got %v", woke)
+	}
+}
+
+func TestSnoozeService_Notify(t *testing.T) {
+	service := NewSnoozeService(filepath.Join(t.TempDir(), "snoozes.json"))
+	browser := &recordingNotifier{err: ErrNoRecipients}
+	service.SetNotifier(browser)
+	now := at(6, 9, 0)
+	service.now = func() time.Time { return now }
+
+	service.Snooze(SnoozeEmail, "1", "Budget Meeting", at(6, 10, 0), true)
+	service.Snooze(SnoozeTodo, "2", "Quiet", at(6, 10, 0), false)
+
+	service.Check(context.Background())
+	now = at(6, 10, 1)
+	service.Check(context.Background()) // Nobody connected; retried later
+
+	browser.err = nil
+	service.Check(context.Background())
+	service.Check(context.Background())
+	if len(browser.received) != 1 {
+		t.Fatalf("Expected one notification, got %+v", browser.received)
+	}
+	if got := browser.received[0]; got.Title != "Budget Meeting" || got.URL != "/?email.message=1" {
+		t.Errorf("Unexpected notification %+v", got)
+	}
+}
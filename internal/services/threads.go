@@ -0,0 +1,175 @@
+package services
+
+import (
+	netmail "net/mail"
+	"regexp"
+	"sort"
+	"strings"
+
+	"flexpane/internal/models"
+)
+
+// Thread is a conversation of related emails
+type Thread struct {
+	ID           string         `json:"id"` // ID of the oldest email
+	Subject      string         `json:"subject"`
+	Emails       []models.Email `json:"emails"` // Oldest first
+	Participants []string       `json:"participants"`
+	Latest       models.Email   `json:"latest"`
+	Count        int            `json:"count"`
+	Unread       int            `json:"unread"`
+	Flagged      bool           `json:"flagged,omitempty"`
+}
+
+// subjectPrefix matches reply and forward markers and mailing list tags
+var subjectPrefix = regexp.MustCompile(`(?i)^\s*(((re|fwd?|aw|sv|antw)(\[\d+\])?\s*:)|\[[^\]]*\])\s*`)
+
+// GroupThreads groups emails into conversations, newest conversation first.
+// Messages are linked through Message-ID, In-Reply-To and References, so
+// replies to the same missing parent still end up together. Messages without
+// any of those headers fall back to their subject, but only when some message
+// with that subject is a reply, so unrelated messages that happen to share a
+// subject like "Hello" stay apart. They join the conversation with that
+// subject when there is exactly one; messages that have headers are never
+// merged by subject.
+func GroupThreads(emails []models.Email) []Thread {
+	parent := map[string]string{}
+	var find func(key string) string
+	find = func(key string) string {
+		p, exists := parent[key]
+		if !exists || p == key {
+			parent[key] = key
+			return key
+		}
+		root := find(p)
+		parent[key] = root
+		return root
+	}
+	union := func(a, b string) {
+		parent[find(a)] = find(b)
+	}
+
+	keys := make([]string, len(emails))
+	headerless := make([]bool, len(emails))
+	replied := map[string]bool{}
+	for i, email := range emails {
+		headerless[i] = email.MessageID == "" && email.InReplyTo == "" && len(email.References) == 0
+		keys[i] = "email:" + email.ID
+		if email.MessageID != "" {
+			union(keys[i], "msg:"+email.MessageID)
+		}
+		for _, ref := range email.References {
+			union(keys[i], "msg:"+ref)
+		}
+		if email.InReplyTo != "" {
+			union(keys[i], "msg:"+email.InReplyTo)
+		}
+
+		if subject, reply := NormalizeSubject(email.Subject); reply && subject != "" {
+			replied[subject] = true
+		}
+	}
+
+	// Conversations threaded by headers, per replied-to subject
+	conversations := map[string]map[string]bool{}
+	for i, email := range emails {
+		subject, _ := NormalizeSubject(email.Subject)
+		if headerless[i] || !replied[subject] {
+			continue
+		}
+		if conversations[subject] == nil {
+			conversations[subject] = map[string]bool{}
+		}
+		conversations[subject][find(keys[i])] = true
+	}
+	for subject, roots := range conversations {
+		if len(roots) == 1 {
+			for root := range roots {
+				union("subject:"+subject, root)
+			}
+		}
+	}
+	for i, email := range emails {
+		if subject, _ := NormalizeSubject(email.Subject); headerless[i] && replied[subject] {
+			union(keys[i], "subject:"+subject)
+		}
+	}
+
+	groups := map[string][]models.Email{}
+	var roots []string
+	for i, email := range emails {
+		root := find(keys[i])
+		if _, exists := groups[root]; !exists {
+			roots = append(roots, root)
+		}
+		groups[root] = append(groups[root], email)
+	}
+
+	threads := make([]Thread, 0, len(roots))
+	for _, root := range roots {
+		threads = append(threads, newThread(groups[root]))
+	}
+	sort.SliceStable(threads, func(i, j int) bool {
+		return threads[i].Latest.Time.After(threads[j].Latest.Time)
+	})
+	return threads
+}
+
+func newThread(emails []models.Email) Thread {
+	sort.SliceStable(emails, func(i, j int) bool {
+		return emails[i].Time.Before(emails[j].Time)
+	})
+
+	thread := Thread{
+		ID:      emails[0].ID,
+		Subject: emails[0].Subject,
+		Emails:  emails,
+		Latest:  emails[len(emails)-1],
+		Count:   len(emails),
+	}
+
+	seen := map[string]bool{}
+	for _, email := range emails {
+		if !email.Read {
+			thread.Unread++
+		}
+		if email.Flagged {
+			thread.Flagged = true
+		}
+		name := participantName(email.From)
+		if name != "" && !seen[strings.ToLower(name)] {
+			seen[strings.ToLower(name)] = true
+			thread.Participants = append(thread.Participants, name)
+		}
+	}
+	return thread
+}
+
+// NormalizeSubject strips reply and forward markers and list tags, reporting
+// whether the subject was marked as a reply or forward
+func NormalizeSubject(subject string) (string, bool) {
+	reply := false
+	for {
+		match := subjectPrefix.FindStringSubmatch(subject)
+		if match == nil {
+			break
+		}
+		if match[2] != "" {
+			reply = true
+		}
+		subject = subject[len(match[0]):]
+	}
+	return strings.ToLower(strings.Join(strings.Fields(subject), " ")), reply
+}
+
+// participantName shortens "Ann Lee <ann@example.com>" to "Ann Lee"
+func participantName(from string) string {
+	addr, err := netmail.ParseAddress(from)
+	if err != nil {
+		return strings.TrimSpace(from)
+	}
+	if addr.Name != "" {
+		return addr.Name
+	}
+	return addr.Address
+}
@@ -0,0 +1,88 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"flexpane/internal/models"
+)
+
+func TestGroupThreads(t *testing.T) {
+	base := time.Date(2024, 3, 1, 9, 0, 0, 0, time.UTC)
+	emails := []models.Email{
+		{ID: "a", Subject: "Budget", From: "Sarah <sarah@example.com>", Time: base, Read: true, MessageID: "a@x"},
+		{ID: "b", Subject: "Re: Budget", From: "mike@example.com", Time: base.Add(2 * time.Hour), MessageID: "b@x", InReplyTo: "a@x", References: []string{"a@x"}},
+		// Reply to a message that isn't in the mailbox, sharing its parent with c
+		{ID: "c", Subject: "Re: Offsite", From: "Ann <ann@example.com>", Time: base.Add(time.Hour), MessageID: "c@x", References: []string{"missing@x"}},
+		{ID: "d", Subject: "RE: [team] Offsite", From: "Sarah <sarah@example.com>", Time: base.Add(3 * time.Hour), MessageID: "d@x", References: []string{"missing@x", "c@x"}},
+		// No headers, joined by subject because "Re: Budget" exists
+		{ID: "e", Subject: "Fwd: Budget", From: "Ann <ann@example.com>", Time: base.Add(4 * time.Hour), Flagged: true},
+		// Same subject but nobody replied, so these stay apart
+		{ID: "f", Subject: "Hello", From: "x@example.com", Time: base.Add(-time.Hour)},
+		{ID: "g", Subject: "Hello", From: "y@example.com", Time: base.Add(-2 * time.Hour)},
+	}
+
+	threads := GroupThreads(emails)
+	if len(threads) != 4 {
+		t.Fatalf("Expected 4 threads, got %d: %+v", len(threads), threads)
+	}
+
+	budget := threads[0]
+	if budget.ID != "a" || budget.Count != 3 || budget.Subject != "Budget" || budget.Latest.ID != "e" {
+		t.Errorf("Unexpected budget thread %+v", budget)
+	}
+	if budget.Unread != 2 || !budget.Flagged {
+		t.Errorf("Expected 2 unread and flagged, got %d %v", budget.Unread, budget.Flagged)
+	}
+	if want := []string{"Sarah", "mike@example.com", "Ann"}; len(budget.Participants) != 3 || budget.Participants[0] != want[0] || budget.Participants[1] != want[1] || budget.Participants[2] != want[2] {
+		t.Errorf("Expected participants %v, got %v", want, budget.Participants)
+	}
+
+	offsite := threads[1]
+	if offsite.Count != 2 || offsite.Emails[0].ID != "c" || offsite.Emails[1].ID != "d" {
+		t.Errorf("Unexpected offsite thread %+v", offsite)
+	}
+
+	if threads[2].ID != "f" || threads[3].ID != "g" {
+		t.Errorf("Expected unrelated Hello messages apart, got %q and %q", threads[2].ID, threads[3].ID)
+	}
+}
+
+func TestGroupThreads_SharedSubject(t *testing.T) {
+	base := time.Date(2024, 3, 4, 9, 0, 0, 0, time.UTC)
+	emails := []models.Email{
+		{ID: "a", Subject: "Weekly sync", Time: base, MessageID: "a@x"},
+		{ID: "b", Subject: "Re: Weekly sync", Time: base.Add(time.Hour), MessageID: "b@x", InReplyTo: "a@x", References: []string{"a@x"}},
+		// A week later, a separate conversation with the same subject
+		{ID: "c", Subject: "Weekly sync", Time: base.Add(7 * 24 * time.Hour), MessageID: "c@x"},
+		{ID: "d", Subject: "Re: Weekly sync", Time: base.Add(7*24*time.Hour + time.Hour), MessageID: "d@x", InReplyTo: "c@x", References: []string{"c@x"}},
+	}
+
+	threads := GroupThreads(emails)
+	if len(threads) != 2 {
+		t.Fatalf("Expected the two conversations to stay apart, got %+v", threads)
+	}
+	if threads[0].ID != "c" || threads[0].Count != 2 || threads[1].ID != "a" || threads[1].Count != 2 {
+		t.Errorf("Expected c+d and a+b, got %+v", threads)
+	}
+}
+
+func TestNormalizeSubject(t *testing.T) {
+	tests := []struct {
+		subject string
+		want    string
+		reply   bool
+	}{
+		{"Budget", "budget", false},
+		{"Re: Budget", "budget", true},
+		{"RE[2]: Fwd:  Budget  ", "budget", true},
+		{"[team] AW: Budget", "budget", true},
+		{"[announce] Release", "release", false},
+	}
+	for _, tt := range tests {
+		got, reply := NormalizeSubject(tt.subject)
+		if got != tt.want || reply != tt.reply {
+			t.Errorf("NormalizeSubject(%q) = %q, %v; want %q, %v", tt.subject, got, reply, tt.want, tt.reply)
+		}
+	}
+}
@@ -1,10 +1,13 @@
 package services
 
 import (
+	"crypto/rand"
+	"encoding/hex"
 	"encoding/json"
 	"os"
 	"path/filepath"
 	"sync"
+	"time"
 
 	"flexpane/internal/models"
 )
@@ -13,6 +16,7 @@ type TodoService struct {
 	filename string
 	todos    []models.Todo
 	mutex    sync.RWMutex
+	events   *EventBus
 }
 
 func NewTodoService(filename string) *TodoService {
@@ -24,6 +28,11 @@ func NewTodoService(filename string) *TodoService {
 	return service
 }
 
+// SetEvents sets where changes to the todos are announced
+func (s *TodoService) SetEvents(events *EventBus) {
+	s.events = events
+}
+
 func (s *TodoService) GetTodos() []models.Todo {
 	s.mutex.RLock()
 	defer s.mutex.RUnlock()
@@ -31,15 +40,28 @@ func (s *TodoService) GetTodos() []models.Todo {
 }
 
 func (s *TodoService) AddTodo(message string) error {
-	s.mutex.Lock()
-	defer s.mutex.Unlock()
+	return s.AddTodoDue(message, nil, false)
+}
 
-	s.todos = append(s.todos, models.Todo{
-		Done:    false,
-		Message: message,
+// AddTodoDue adds a todo with an optional due time. When allDay is set only
+// the date of due is significant.
+func (s *TodoService) AddTodoDue(message string, due *time.Time, allDay bool) error {
+	_, err := s.Add(models.Todo{
+		Message:   message,
+		Due:       due,
+		DueAllDay: due != nil && allDay,
 	})
+	return err
+}
 
-	return s.save()
+// Add appends a todo, giving it a new ID, and returns the stored todo
+func (s *TodoService) Add(todo models.Todo) (models.Todo, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	todo.ID = newTodoID()
+	s.todos = append(s.todos, todo)
+	return todo, s.changed()
 }
 
 func (s *TodoService) ToggleTodo(index int) error {
@@ -51,7 +73,22 @@ func (s *TodoService) ToggleTodo(index int) error {
 	}
 
 	s.todos[index].Done = !s.todos[index].Done
-	return s.save()
+	return s.changed()
+}
+
+// SetDone marks the todo with the given ID done or not done, and reports
+// whether it exists
+func (s *TodoService) SetDone(id string, done bool) (bool, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	for i := range s.todos {
+		if s.todos[i].ID == id {
+			s.todos[i].Done = done
+			return true, s.changed()
+		}
+	}
+	return false, nil
 }
 
 func (s *TodoService) load() error {
@@ -70,7 +107,31 @@ func (s *TodoService) load() error {
 		return err
 	}
 
-	return json.Unmarshal(data, &s.todos)
+	if err := json.Unmarshal(data, &s.todos); err != nil {
+		return err
+	}
+
+	// Todos saved before IDs existed get one now so feed UIDs stay stable
+	assigned := false
+	for i := range s.todos {
+		if s.todos[i].ID == "" {
+			s.todos[i].ID = newTodoID()
+			assigned = true
+		}
+	}
+	if assigned {
+		return s.save()
+	}
+	return nil
+}
+
+// changed saves the todos and announces the change
+func (s *TodoService) changed() error {
+	if err := s.save(); err != nil {
+		return err
+	}
+	s.events.Publish(EventTodosChanged, "todos")
+	return nil
 }
 
 func (s *TodoService) save() error {
@@ -80,4 +141,9 @@ func (s *TodoService) save() error {
 	}
 
 	return os.WriteFile(s.filename, data, 0644)
-}
\ No newline at end of file
+}
+func newTodoID() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
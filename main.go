@@ -1,81 +1,235 @@
 package main
 
 import (
-	"encoding/json"
-	"html/template"
+	"context"
 	"log"
+	"net"
 	"net/http"
 	"os"
+	"os/signal"
+	"sort"
+	"syscall"
 	"time"
 
+	"flexpane/internal/config"
 	"flexpane/internal/handlers"
 	"flexpane/internal/panes"
 	"flexpane/internal/providers"
+	"flexpane/internal/secrets"
 	"flexpane/internal/services"
 )
 
-type PaneConfig struct {
-	Enabled []string                                   `json:"enabled"`
-	Layout  map[string]services.PaneLayoutConfig `json:"layout"`
-}
+// shutdownTimeout bounds how long shutdown waits for requests and panes
+const shutdownTimeout = 10 * time.Second
 
 func main() {
-	// Initialize services
+	// Keep secrets out of the log
+	log.SetOutput(secrets.RedactingWriter(os.Stderr))
+
+	// Load configuration (defaults apply when the file is missing)
+	cfg, err := config.Load("config/panes.json")
+	if err != nil {
+		log.Fatalf("Failed to load config: %v", err)
+	}
+
+	// Resolve secret:// references in provider settings
+	secretStore, err := cfg.OpenSecrets()
+	if err != nil {
+		log.Fatalf("Failed to open secrets: %v", err)
+	}
+	if err := cfg.ResolveSecrets(secretStore); err != nil {
+		log.Fatalf("Failed to resolve secrets: %v", err)
+	}
+
+	// Initialize services. Changes are announced on the event bus so open
+	// pages can reload the panes they affect.
+	events := services.NewEventBus()
 	todoService := services.NewTodoService("data/todos.json")
+	todoService.SetEvents(events)
+	snoozeService := services.NewSnoozeService("data/snoozes.json")
+	snoozeService.SetEvents(events)
 
-	// Create data provider
-	dataProvider, err := providers.CreateProvider("mock")
+	// Create the named provider instances declared in config
+	instances, err := providers.CreateInstances(cfg.Providers)
 	if err != nil {
-		log.Fatalf("Failed to create provider: %v", err)
+		log.Fatalf("Failed to create providers: %v", err)
+	}
+	providerFor := func(paneID string) providers.DataProvider {
+		provider, exists := instances[cfg.ProviderFor(paneID)]
+		if !exists {
+			log.Fatalf("No provider configured for pane %q", paneID)
+		}
+		return provider
 	}
 
-	// Parse templates - include all template files
-	tmpl := template.Must(template.ParseGlob("web/templates/*.html"))
-	tmpl = template.Must(tmpl.ParseGlob("web/templates/components/*.html"))
-	tmpl = template.Must(tmpl.ParseGlob("web/templates/panes/*.html"))
+	// Display zones (validated when the config was loaded)
+	location, secondaryLocation, _ := cfg.Locations()
 
 	// Create pane registry
 	registry := services.NewPaneRegistry()
 
 	// Register available panes
-	registry.RegisterPane(panes.NewCalendarPane(dataProvider))
-	registry.RegisterPane(panes.NewTodoPane(todoService))
-	registry.RegisterPane(panes.NewEmailPane(dataProvider))
-
-	// Load pane configuration
-	defaultPanes := []string{"calendar", "todos", "email"}
-	registry.SetEnabledPanes(defaultPanes) // Set defaults first
-	
-	if configData, err := os.ReadFile("config/panes.json"); err == nil {
-		var config PaneConfig
-		if err := json.Unmarshal(configData, &config); err == nil {
-			registry.SetEnabledPanes(config.Enabled)
-			registry.SetLayoutConfig(config.Layout)
-		}
+	calendarPane := panes.NewCalendarPane(providerFor("calendar"))
+	calendarPane.SetTimeZones(location, secondaryLocation)
+	calendarPane.SetLeaveWarning(cfg.LeaveWarningDuration())
+	calendarPane.SetFreeBusy(services.NewFreeBusyService(calendarSources(instances), cfg.WorkingHours, location))
+	calendarPane.SetEvents(events)
+	emailPane := panes.NewEmailPane(providerFor("email"))
+	emailPane.SetTimeZone(location)
+	emailPane.SetTodos(todoService)
+	emailPane.SetSnoozes(snoozeService)
+	emailPane.SetEvents(events)
+	if cfg.Email.SMTP != nil {
+		emailPane.SetMailer(cfg.Email.SMTP.Mailer())
+	}
+
+	// Reminders, email rules and snoozes share the notification channels
+	browser := services.NewBrowserNotifier(events)
+	channels := cfg.Reminders.Notifiers(browser)
+	snoozeService.SetNotifier(browser)
+
+	// Without rules this still watches the mailbox for new mail
+	emailRules := services.NewEmailRules(providerFor("email"), cfg.Email.Rules, todoService, channels)
+	emailRules.SetEvents(events)
+	if len(cfg.Email.Rules) > 0 {
+		emailPane.SetRules(emailRules)
+	}
+
+	todoPane := panes.NewTodoPane(todoService)
+	todoPane.SetSnoozes(snoozeService)
+
+	// Background checks run while a pane that needs them is started. Email
+	// rules run with the email pane; snoozes with either pane that snoozes,
+	// since any item may ask for a notification; reminders, once configured,
+	// with either pane whose items they cover.
+	emailPoller := services.NewPoller(func(ctx context.Context) {
+		emailRules.Run(ctx, cfg.Email.RulesInterval())
+	})
+	snoozePoller := services.NewPoller(func(ctx context.Context) {
+		snoozeService.Run(ctx, services.DefaultReminderInterval)
+	})
+	emailPane.SetPollers(emailPoller, snoozePoller)
+	var reminders *services.ReminderService
+	if len(cfg.Reminders.Rules) > 0 {
+		reminders = services.NewReminderService(calendarSources(instances), todoService, cfg.Reminders.Rules, channels, location)
+		reminderPoller := services.NewPoller(func(ctx context.Context) {
+			reminders.Run(ctx, cfg.Reminders.IntervalDuration())
+		})
+		calendarPane.SetPollers(reminderPoller)
+		todoPane.SetPollers(snoozePoller, reminderPoller)
+	} else {
+		todoPane.SetPollers(snoozePoller)
+	}
+
+	registry.RegisterPane(calendarPane)
+	registry.RegisterPane(todoPane)
+	registry.RegisterPane(emailPane)
+
+	// Apply pane configuration
+	registry.SetEnabledPanes(cfg.Enabled)
+	registry.SetLayoutConfig(cfg.Layout)
+	registry.SetTimeouts(cfg.PaneTimeouts())
+	registry.SetRefreshes(cfg.PaneRefreshes())
+
+	// Parse templates, including any that panes ship themselves
+	tmpl, err := handlers.ParseTemplates("web/templates", registry)
+	if err != nil {
+		log.Fatalf("Failed to parse templates: %v", err)
+	}
+
+	// Panes with lifecycle hooks initialize and start before serving, and
+	// run until interrupted
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	if err := registry.Start(ctx); err != nil {
+		log.Fatalf("Failed to start panes: %v", err)
 	}
 
 	// Initialize handlers
 	handler := handlers.NewHandler(registry, tmpl)
+	handler.SetTimeZone(location)
+	if token := cfg.FeedToken(); !token.IsZero() {
+		handler.SetFeed(services.NewCalendarFeed(calendarSources(instances), todoService, cfg.Feed.Todos, location), token)
+		log.Println("Calendar feed enabled at /feed/{token}/calendar.ics")
+	}
+
+	handler.SetReminders(reminders, browser)
+	handler.SetEvents(events)
 
 	// Routes
 	http.HandleFunc("/", handler.Home)
-	http.HandleFunc("/api/todos", handler.TodosAPI) // Legacy route for backward compatibility
-	// TODO: Add generic /api/{pane} route pattern for extensibility
+	http.HandleFunc("GET /panes/{id}", handler.Pane) // One pane's HTML, for retrying it
+	http.HandleFunc("GET /health", handler.Health)
+	http.HandleFunc("GET /api/panes", handler.Panes)
+	http.HandleFunc("/api/panes/{id}", handler.PaneAPI)
+	http.HandleFunc("/api/panes/{id}/{rest...}", handler.PaneAPI) // e.g. /api/panes/todos/items/{itemID}
+	// Aliases for the routes that predate /api/panes
+	http.HandleFunc("/api/todos", handler.TodosAPI)
+	http.HandleFunc("/api/todos/{rest...}", handler.TodosAPI) // e.g. /api/todos/snooze?id=
+	http.HandleFunc("/api/calendar", handler.CalendarAPI)
+	http.HandleFunc("/api/calendar/{rest...}", handler.CalendarAPI) // e.g. /api/calendar/freebusy
+	http.HandleFunc("/api/email", handler.EmailAPI)
+	http.HandleFunc("/api/email/{rest...}", handler.EmailAPI) // e.g. /api/email/message?id=
+	http.HandleFunc("GET /feed/{token}/calendar.ics", handler.CalendarFeed)
+	http.HandleFunc("GET /events", handler.Events) // Pane changes, for live updates
+	http.HandleFunc("POST /api/notifications/snooze", handler.SnoozeNotification)
 
-	// Static files  
+	// Static files
 	// TODO: SECURITY - Static file serving vulnerable to directory traversal attacks (../../../etc/passwd)
 	// Consider implementing path validation or using a more secure static file handler
 	fs := http.FileServer(http.Dir("web/static/"))
 	http.Handle("/static/", http.StripPrefix("/static/", fs))
+	http.HandleFunc("GET /static/panes/{id}/{file...}", handler.PaneAsset) // Files panes ship
 
 	// Start server
 	server := &http.Server{
 		Addr:         ":3000",
-		Handler:      nil,
+		Handler:      secrets.RedactHandler(http.DefaultServeMux),
+		BaseContext:  func(net.Listener) context.Context { return ctx },
 		ReadTimeout:  10 * time.Second,
 		WriteTimeout: 10 * time.Second,
 	}
 
+	// Shut down cleanly on interrupt: close open streams, let requests
+	// finish, then stop the panes
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+		defer cancel()
+		if err := server.Shutdown(shutdownCtx); err != nil {
+			log.Printf("Shutdown: %v", err)
+		}
+	}()
+
 	log.Println("Flexpane (extensible panes) server starting on :3000")
-	log.Fatal(server.ListenAndServe())
-}
\ No newline at end of file
+	serveErr := server.ListenAndServe()
+
+	stopCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	if err := registry.Stop(stopCtx); err != nil {
+		log.Printf("Failed to stop panes: %v", err)
+	}
+	// Plugins are told to shut down rather than left running
+	if err := providers.CloseInstances(instances); err != nil {
+		log.Printf("Failed to close providers: %v", err)
+	}
+	if serveErr != http.ErrServerClosed {
+		log.Fatal(serveErr)
+	}
+}
+
+// calendarSources returns every provider instance in name order, so free/busy
+// covers all configured calendars
+func calendarSources(instances map[string]providers.DataProvider) []services.EventSource {
+	names := make([]string, 0, len(instances))
+	for name := range instances {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	sources := make([]services.EventSource, 0, len(names))
+	for _, name := range names {
+		sources = append(sources, services.NamedSource(name, instances[name]))
+	}
+	return sources
+}
@@ -0,0 +1,97 @@
+// Command reference is the reference Flexpane provider plugin. It speaks the
+// JSON-RPC protocol described in docs/plugins.md and serves a fixed set of
+// events and emails relative to the current time.
+//
+// The -delay and -exit-after flags let tests exercise timeouts and crashes.
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"flexpane/internal/models"
+)
+
+type request struct {
+	ID     *int64          `json:"id"`
+	Method string          `json:"method"`
+	Params json.RawMessage `json:"params"`
+}
+
+type response struct {
+	JSONRPC string      `json:"jsonrpc"`
+	ID      int64       `json:"id"`
+	Result  interface{} `json:"result,omitempty"`
+	Error   *rpcError   `json:"error,omitempty"`
+}
+
+type rpcError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func main() {
+	delay := flag.Duration("delay", 0, "wait this long before answering data requests")
+	exitAfter := flag.Int("exit-after", 0, "exit after answering this many data requests (0 = never)")
+	flag.Parse()
+
+	encoder := json.NewEncoder(os.Stdout)
+	scanner := bufio.NewScanner(os.Stdin)
+	answered := 0
+
+	for scanner.Scan() {
+		var req request
+		if err := json.Unmarshal(scanner.Bytes(), &req); err != nil {
+			fmt.Fprintf(os.Stderr, "invalid request: %v\n", err)
+			continue
+		}
+		if req.Method == "shutdown" {
+			return
+		}
+		if req.ID == nil {
+			continue // Unknown notification
+		}
+
+		resp := response{JSONRPC: "2.0", ID: *req.ID}
+		switch req.Method {
+		case "initialize":
+			resp.Result = map[string]interface{}{"name": "reference", "methods": []string{"getCalendarEvents", "getEmails"}}
+		case "getCalendarEvents":
+			time.Sleep(*delay)
+			resp.Result = events(time.Now())
+			answered++
+		case "getEmails":
+			time.Sleep(*delay)
+			resp.Result = emails(time.Now())
+			answered++
+		default:
+			resp.Error = &rpcError{Code: -32601, Message: "method not found: " + req.Method}
+		}
+
+		if err := encoder.Encode(resp); err != nil {
+			fmt.Fprintf(os.Stderr, "write failed: %v\n", err)
+			return
+		}
+		if *exitAfter > 0 && answered >= *exitAfter {
+			os.Exit(1) // Simulate a crash
+		}
+	}
+}
+
+func events(now time.Time) []models.Event {
+	start := now.Truncate(time.Hour).Add(time.Hour)
+	return []models.Event{
+		{ID: "plugin-1", Title: "Plugin Sync", Start: start, End: start.Add(30 * time.Minute), Location: "Terminal"},
+		{ID: "plugin-2", Title: "Data Review", Start: start.Add(2 * time.Hour), End: start.Add(3 * time.Hour)},
+	}
+}
+
+func emails(now time.Time) []models.Email {
+	return []models.Email{
+		{ID: "plugin-1", Subject: "Nightly export finished", From: "cron@internal", Preview: "All jobs completed", Time: now.Add(-time.Hour)},
+	}
+}
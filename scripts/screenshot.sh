@@ -11,7 +11,8 @@ echo "📸 Taking Flexplane screenshot..."
 # Check if server is running
 if ! curl -s $URL > /dev/null; then
     echo "❌ Server not running at $URL"
-    echo "💡 Start server with: go run main.go"
+    echo "💡 Start server with recorded data for reproducible screenshots:"
+    echo "   FLEXPANE_PROVIDER=replay FLEXPANE_CASSETTE=testdata/cassette.json go run main.go"
     exit 1
 fi
 
@@ -3,9 +3,163 @@
 
 document.addEventListener('DOMContentLoaded', function() {
     initializeDateDisplay();
-    initializeTodoInteractivity();
+    initializeTimeZoneCheck();
+    initializePanes(document);
+    initializeNotifications();
+    initializeLiveUpdates();
 });
 
+// Pane initializers look only inside root, so a pane swapped in on its own
+// can be set up without binding the rest of the page twice
+function initializePanes(root) {
+    initializeTodoInteractivity(root);
+    initializeCalendarInteractivity(root);
+    initializeEmailInteractivity(root);
+    initializeEmailCompose(root);
+    initializeSnooze(root);
+    initializeMeetingCountdown(root);
+    initializePaneRetry(root);
+    initializePaneRefresh(root);
+    paneInitializers.forEach(initialize => initialize(root));
+}
+
+// Scripts that panes ship register their setup here, so it runs on load and
+// again whenever their pane is swapped in
+const paneInitializers = [];
+
+function registerPaneInitializer(initialize) {
+    paneInitializers.push(initialize);
+}
+
+// Failed panes offer a retry that reloads just that pane, once any
+// retry-after the provider asked for has passed
+function initializePaneRetry(root) {
+    root.querySelectorAll('.pane-retry').forEach(button => {
+        const wait = parseInt(button.dataset.retryAfter || '0', 10);
+        if (wait > 0) {
+            button.disabled = true;
+            setTimeout(() => { button.disabled = false; }, wait * 1000);
+        }
+        button.addEventListener('click', () => refreshPane(button.dataset.paneId));
+    });
+}
+
+// Panes with data-refresh reload themselves every that many seconds. The
+// timer is set again when the fresh pane is initialized, and waits while the
+// page is hidden or the user is typing in the pane.
+function initializePaneRefresh(root) {
+    const panes = Array.from(root.querySelectorAll('.pane[data-refresh]'));
+    if (root.matches && root.matches('.pane[data-refresh]')) {
+        panes.push(root);
+    }
+    panes.forEach(pane => {
+        const interval = parseInt(pane.dataset.refresh, 10) * 1000;
+        if (!(interval > 0)) return;
+
+        const schedule = () => setTimeout(function() {
+            if (!pane.isConnected) return; // Swapped out already
+            if (document.hidden || paneBusy(pane)) {
+                schedule();
+                return;
+            }
+            refreshPane(pane.dataset.paneId).then(refreshed => {
+                if (!refreshed) schedule();
+            });
+        }, interval);
+        schedule();
+    });
+}
+
+// A pane is busy while the user is in it or has typed something not yet
+// submitted, which swapping it out would lose
+function paneBusy(pane) {
+    if (pane.contains(document.activeElement)) return true;
+    return Array.from(pane.querySelectorAll('textarea, input[type="text"], input:not([type])'))
+        .some(field => field.value !== field.defaultValue);
+}
+
+// Pages follow /events so a change made anywhere, in another tab or in the
+// background, reloads the panes it affects. Notifications arrive on the same
+// stream. EventSource reconnects on its own and resumes from the last event
+// it saw.
+function initializeLiveUpdates() {
+    const eventId = document.body.dataset.eventId;
+    if (!eventId || !window.EventSource) return;
+
+    const stream = new EventSource(`/events?since=${encodeURIComponent(eventId)}`);
+    stream.addEventListener('change', function(e) {
+        schedulePaneRefresh(JSON.parse(e.data).pane);
+    });
+    if (document.body.dataset.notifications === 'on') {
+        stream.addEventListener('notification', function(e) {
+            showNotification(JSON.parse(e.data).notification);
+        });
+    }
+    // Too much was missed to replay; anything may have changed
+    stream.addEventListener('reset', function() {
+        document.querySelectorAll('.pane[data-pane-id]').forEach(pane => {
+            schedulePaneRefresh(pane.dataset.paneId);
+        });
+    });
+}
+
+// Changes often come in bursts, so each pane reloads once they settle, and
+// not while it is busy
+const pendingRefreshes = {};
+
+function schedulePaneRefresh(paneId) {
+    clearTimeout(pendingRefreshes[paneId]);
+    pendingRefreshes[paneId] = setTimeout(function() {
+        delete pendingRefreshes[paneId];
+        const pane = document.querySelector(`.pane[data-pane-id="${CSS.escape(paneId)}"]`);
+        if (!pane) return;
+        if (paneBusy(pane)) {
+            pendingRefreshes[paneId] = setTimeout(() => schedulePaneRefresh(paneId), 2000);
+            return;
+        }
+        refreshPane(paneId);
+    }, 250);
+}
+
+// refreshPane swaps a pane for a freshly rendered copy from /panes/{id},
+// and reports whether it did. Refreshes of one pane run in turn, so one asked
+// for after a change never loses to an earlier one still loading.
+const paneRefreshes = {};
+
+function refreshPane(paneId) {
+    const previous = paneRefreshes[paneId] || Promise.resolve();
+    const next = previous.then(() => loadPane(paneId));
+    paneRefreshes[paneId] = next;
+    return next;
+}
+
+async function loadPane(paneId) {
+    const pane = document.querySelector(`.pane[data-pane-id="${CSS.escape(paneId)}"]`);
+    if (!pane) return false;
+
+    pane.classList.add('refreshing');
+    try {
+        const response = await fetch(`/panes/${encodeURIComponent(paneId)}${window.location.search}`);
+        if (!response.ok) {
+            console.error('Failed to refresh pane');
+            return false;
+        }
+
+        const template = document.createElement('template');
+        template.innerHTML = (await response.text()).trim();
+        const fresh = template.content.querySelector('.pane');
+        if (!fresh) return false;
+        pane.replaceWith(fresh);
+        initializePanes(fresh);
+        return true;
+    } catch (error) {
+        console.error('Error refreshing pane:', error);
+        return false;
+    } finally {
+        pane.classList.remove('refreshing');
+    }
+}
+
 // Update header with current date
 function initializeDateDisplay() {
     const dateElement = document.getElementById('current-date');
@@ -20,11 +174,119 @@ function initializeDateDisplay() {
     }
 }
 
+// Warn when times are rendered in a different zone than the browser's
+function initializeTimeZoneCheck() {
+    const configured = document.body.dataset.timezone;
+    const warning = document.getElementById('timezone-warning');
+    if (!configured || !warning) return;
+
+    const browser = Intl.DateTimeFormat().resolvedOptions().timeZone;
+    if (browser && browser !== configured) {
+        warning.textContent = `Times are shown in ${configured}; your browser is set to ${browser}.`;
+        warning.hidden = false;
+    }
+}
+
+// Reminders arrive on the /events stream and show as desktop notifications
+// (when permitted) plus an in-page toast with a snooze button
+function initializeNotifications() {
+    if (document.body.dataset.notifications !== 'on' || !window.EventSource) return;
+
+    const enableButton = document.getElementById('enable-notifications');
+    if (enableButton && 'Notification' in window && Notification.permission === 'default') {
+        enableButton.hidden = false;
+        enableButton.addEventListener('click', async function() {
+            await Notification.requestPermission();
+            enableButton.hidden = true;
+        });
+    }
+
+}
+
+function showNotification(notification) {
+    if ('Notification' in window && Notification.permission === 'granted') {
+        const desktop = new Notification(notification.title, {
+            body: notification.body,
+            tag: notification.id
+        });
+        desktop.onclick = function() {
+            window.focus();
+            if (notification.url) window.open(notification.url, '_blank', 'noopener');
+        };
+    }
+
+    const container = document.getElementById('notifications');
+    if (!container) return;
+
+    const toast = document.createElement('div');
+    toast.className = 'notification-toast';
+
+    const title = document.createElement('strong');
+    title.textContent = notification.title;
+    const body = document.createElement('span');
+    body.textContent = notification.body;
+    toast.append(title, body);
+
+    // Only reminders can be snoozed from the toast; email rules and snoozed
+    // items link to the message instead of a meeting
+    const reminder = /^(event|todo):/.test(notification.id);
+
+    if (notification.url) {
+        const join = document.createElement('a');
+        join.className = 'join-link';
+        join.href = notification.url;
+        join.target = '_blank';
+        join.rel = 'noopener';
+        join.textContent = reminder ? 'Join' : 'Open';
+        toast.append(join);
+    }
+
+    if (reminder) {
+        const snooze = document.createElement('button');
+        snooze.textContent = 'Snooze 10m';
+        snooze.addEventListener('click', () => handleSnoozeNotification(notification.id, toast));
+        toast.append(snooze);
+    }
+
+    const dismiss = document.createElement('button');
+    dismiss.textContent = '×';
+    dismiss.setAttribute('aria-label', 'Dismiss');
+    dismiss.addEventListener('click', () => toast.remove());
+
+    toast.append(dismiss);
+    container.append(toast);
+}
+
+async function handleSnoozeNotification(id, toast) {
+    // Optimistic UI update
+    toast.hidden = true;
+
+    try {
+        const response = await fetch('/api/notifications/snooze', {
+            method: 'POST',
+            headers: {
+                'Content-Type': 'application/json',
+            },
+            body: JSON.stringify({ id: id, for: '10m' })
+        });
+
+        if (response.ok) {
+            toast.remove();
+        } else {
+            toast.hidden = false;
+            console.error('Failed to snooze reminder');
+        }
+    } catch (error) {
+        toast.hidden = false;
+        console.error('Error snoozing reminder:', error);
+    }
+}
+
 // Todo interactivity
-function initializeTodoInteractivity() {
+function initializeTodoInteractivity(root) {
     // Add todo form submission
-    const addButton = document.getElementById('add-todo-btn');
-    const newTodoInput = document.getElementById('new-todo');
+    const addButton = root.querySelector('#add-todo-btn');
+    const newTodoInput = root.querySelector('#new-todo');
 
     if (addButton && newTodoInput) {
         // Button click
@@ -39,29 +301,38 @@ function initializeTodoInteractivity() {
     }
 
     // Todo checkbox toggles
-    document.querySelectorAll('.todo-checkbox').forEach(checkbox => {
+    root.querySelectorAll('.todo-checkbox').forEach(checkbox => {
         checkbox.addEventListener('change', handleToggleTodo);
     });
 }
 
 async function handleAddTodo() {
     const input = document.getElementById('new-todo');
+    const dueInput = document.getElementById('new-todo-due');
     const message = input.value.trim();
 
     if (!message) return;
 
+    const body = { message: message };
+    if (dueInput && dueInput.value) {
+        body.due = dueInput.value;
+    }
+
     try {
         const response = await fetch('/api/todos', {
             method: 'POST',
             headers: {
                 'Content-Type': 'application/json',
             },
-            body: JSON.stringify({ message: message })
+            body: JSON.stringify(body)
         });
 
         if (response.ok) {
-            // Optimistic UI update - reload page to get fresh data
-            window.location.reload();
+            // Keep the input focused for adding the next one
+            refreshPane('todos').then(() => {
+                const fresh = document.getElementById('new-todo');
+                if (fresh) fresh.focus();
+            });
         } else {
             console.error('Failed to add todo');
         }
@@ -73,14 +344,18 @@ async function handleAddTodo() {
 async function handleToggleTodo(event) {
     const checkbox = event.target;
     const todoItem = checkbox.closest('.todo-item');
-    const index = todoItem.dataset.index;
+    const id = todoItem.dataset.todoId;
 
     // Optimistic UI update
     todoItem.classList.toggle('completed', checkbox.checked);
 
     try {
-        const response = await fetch(`/api/todos?index=${index}`, {
-            method: 'PATCH'
+        const response = await fetch(`/api/panes/todos/items/${encodeURIComponent(id)}`, {
+            method: 'PATCH',
+            headers: {
+                'Content-Type': 'application/json',
+            },
+            body: JSON.stringify({ done: checkbox.checked })
         });
 
         if (!response.ok) {
@@ -95,4 +370,427 @@ async function handleToggleTodo(event) {
         todoItem.classList.toggle('completed', checkbox.checked);
         console.error('Error toggling todo:', error);
     }
+}
+
+// Calendar interactivity
+function initializeCalendarInteractivity(root) {
+    const addButton = root.querySelector('#add-event-btn');
+    const newEventInput = root.querySelector('#new-event');
+
+    if (addButton && newEventInput) {
+        addButton.addEventListener('click', handleAddEvent);
+
+        newEventInput.addEventListener('keypress', function(e) {
+            if (e.key === 'Enter') {
+                handleAddEvent();
+            }
+        });
+    }
+
+    root.querySelectorAll('.event-delete').forEach(button => {
+        button.addEventListener('click', handleDeleteEvent);
+    });
+}
+
+// Email interactivity
+function initializeEmailInteractivity(root) {
+    root.querySelectorAll('.email-toggle-read').forEach(button => {
+        button.addEventListener('click', handleToggleEmailRead);
+    });
+    root.querySelectorAll('.email-flag').forEach(button => {
+        button.addEventListener('click', handleToggleEmailFlag);
+    });
+    root.querySelectorAll('.email-archive').forEach(button => {
+        button.addEventListener('click', event => handleRemoveEmail(event, 'POST', '/api/email/archive'));
+    });
+    root.querySelectorAll('.email-delete').forEach(button => {
+        button.addEventListener('click', event => handleRemoveEmail(event, 'DELETE', '/api/email'));
+    });
+    root.querySelectorAll('.email-make-todo').forEach(button => {
+        button.addEventListener('click', handleMakeTodo);
+    });
+
+    const message = root.querySelector('.email-message');
+    if (message) {
+        reportEmailOpened(message.dataset.emailId);
+    }
+}
+
+// The message shown in the reading view, reported once per page so
+// refreshing the pane doesn't mark it read or end its snooze again
+let openedEmailId = null;
+
+async function reportEmailOpened(id) {
+    if (id === openedEmailId) {
+        return;
+    }
+    openedEmailId = id;
+
+    try {
+        const response = await fetch(`/api/email/opened?id=${encodeURIComponent(id)}`, {
+            method: 'POST'
+        });
+        if (!response.ok) {
+            console.error('Failed to mark email opened');
+        }
+    } catch (error) {
+        console.error('Error marking email opened:', error);
+    }
+}
+
+function setEmailRead(emailItem, read) {
+    emailItem.classList.toggle('unread', !read);
+    emailItem.querySelector('.email-toggle-read').textContent = read ? 'Mark unread' : 'Mark read';
+
+    const thread = emailItem.closest('.email-thread');
+    if (thread) {
+        thread.classList.toggle('unread', thread.querySelector('.email-item.unread') !== null);
+    }
+}
+
+function setEmailFlagged(emailItem, flagged) {
+    emailItem.classList.toggle('flagged', flagged);
+    emailItem.querySelector('.email-flag').setAttribute('aria-pressed', flagged);
+}
+
+async function updateEmail(id, changes) {
+    const response = await fetch(`/api/email?id=${encodeURIComponent(id)}`, {
+        method: 'PATCH',
+        headers: {
+            'Content-Type': 'application/json',
+        },
+        body: JSON.stringify(changes)
+    });
+    return response.ok;
+}
+
+async function handleToggleEmailRead(event) {
+    const emailItem = event.target.closest('.email-item');
+    const read = emailItem.classList.contains('unread');
+
+    // Optimistic UI update
+    setEmailRead(emailItem, read);
+
+    try {
+        if (!await updateEmail(emailItem.dataset.emailId, { read: read })) {
+            setEmailRead(emailItem, !read);
+            console.error('Failed to update email');
+        }
+    } catch (error) {
+        setEmailRead(emailItem, !read);
+        console.error('Error updating email:', error);
+    }
+}
+
+async function handleToggleEmailFlag(event) {
+    const emailItem = event.target.closest('.email-item');
+    const flagged = !emailItem.classList.contains('flagged');
+
+    // Optimistic UI update
+    setEmailFlagged(emailItem, flagged);
+
+    try {
+        if (!await updateEmail(emailItem.dataset.emailId, { flagged: flagged })) {
+            setEmailFlagged(emailItem, !flagged);
+            console.error('Failed to update email');
+        }
+    } catch (error) {
+        setEmailFlagged(emailItem, !flagged);
+        console.error('Error updating email:', error);
+    }
+}
+
+async function handleRemoveEmail(event, method, path) {
+    const emailItem = event.target.closest('.email-item');
+    const id = emailItem.dataset.emailId;
+
+    // Optimistic UI update
+    emailItem.hidden = true;
+
+    try {
+        const response = await fetch(`${path}?id=${encodeURIComponent(id)}`, {
+            method: method
+        });
+
+        if (!response.ok) {
+            emailItem.hidden = false;
+            console.error('Failed to remove email');
+        }
+    } catch (error) {
+        emailItem.hidden = false;
+        console.error('Error removing email:', error);
+    }
+}
+
+async function handleMakeTodo(event) {
+    const button = event.target;
+    const emailItem = button.closest('.email-item, .email-message');
+    const archive = button.dataset.archive === 'true';
+
+    button.disabled = true;
+    try {
+        const response = await fetch(`/api/email/todo?id=${encodeURIComponent(emailItem.dataset.emailId)}`, {
+            method: 'POST',
+            headers: {
+                'Content-Type': 'application/json',
+            },
+            body: JSON.stringify({ archive: archive })
+        });
+
+        if (response.ok) {
+            button.textContent = 'Todo added';
+            if (archive) {
+                window.location.href = emailListLocation();
+            } else {
+                refreshPane('todos');
+            }
+        } else {
+            button.disabled = false;
+            console.error('Failed to make todo');
+        }
+    } catch (error) {
+        button.disabled = false;
+        console.error('Error making todo:', error);
+    }
+}
+
+// The current page with the reading view closed, like the message's back
+// link: other panes keep their state
+function emailListLocation() {
+    const query = new URLSearchParams(window.location.search);
+    for (const key of ['message', 'images']) {
+        query.delete(`email.${key}`);
+        query.delete(key);
+    }
+    return `${window.location.pathname}?${query}`;
+}
+
+// Snoozed emails and todos are hidden until the chosen time, then come back
+// at the top of their pane
+function initializeSnooze(root) {
+    root.querySelectorAll('.snooze-select').forEach(select => {
+        select.addEventListener('change', handleSnooze);
+    });
+}
+
+function snoozeUntil(choice) {
+    const until = new Date();
+    switch (choice) {
+    case '1h':
+        until.setHours(until.getHours() + 1);
+        break;
+    case '3h':
+        until.setHours(until.getHours() + 3);
+        break;
+    case 'tomorrow':
+        until.setDate(until.getDate() + 1);
+        until.setHours(8, 0, 0, 0);
+        break;
+    case 'next-week':
+        // Monday morning
+        until.setDate(until.getDate() + ((8 - until.getDay()) % 7 || 7));
+        until.setHours(8, 0, 0, 0);
+        break;
+    default:
+        return null;
+    }
+    return until;
+}
+
+async function handleSnooze(event) {
+    const select = event.target;
+    const until = snoozeUntil(select.value);
+    if (!until) return;
+
+    const item = select.closest('[data-email-id], [data-todo-id]');
+    const path = item.dataset.emailId !== undefined
+        ? `/api/email/snooze?id=${encodeURIComponent(item.dataset.emailId)}`
+        : `/api/todos/snooze?id=${encodeURIComponent(item.dataset.todoId)}`;
+
+    // Optimistic UI update
+    item.hidden = true;
+
+    try {
+        const response = await fetch(path, {
+            method: 'POST',
+            headers: {
+                'Content-Type': 'application/json',
+            },
+            body: JSON.stringify({
+                until: until.toISOString(),
+                notify: document.body.dataset.notifications === 'on'
+            })
+        });
+
+        if (!response.ok) {
+            item.hidden = false;
+            select.value = '';
+            console.error('Failed to snooze');
+        }
+    } catch (error) {
+        item.hidden = false;
+        select.value = '';
+        console.error('Error snoozing:', error);
+    }
+}
+
+// Compose, reply and forward share one form per view; the mode decides
+// which fields show and where it posts
+function initializeEmailCompose(root) {
+    const form = root.querySelector('.email-compose');
+    if (!form) return;
+
+    root.querySelectorAll('.email-compose-open').forEach(button => {
+        button.addEventListener('click', function() {
+            const mode = button.dataset.mode;
+            form.dataset.mode = mode;
+            form.querySelector('.email-compose-to').hidden = mode === 'reply' || mode === 'reply-all';
+            form.querySelector('.email-compose-subject').hidden = mode !== 'send';
+            form.querySelector('.email-compose-status').textContent = '';
+            form.hidden = false;
+            form.querySelector('textarea').focus();
+        });
+    });
+
+    form.querySelector('.email-compose-cancel').addEventListener('click', function() {
+        form.hidden = true;
+    });
+
+    form.addEventListener('submit', handleSendEmail);
+}
+
+async function handleSendEmail(event) {
+    event.preventDefault();
+    const form = event.target;
+    const status = form.querySelector('.email-compose-status');
+    const mode = form.dataset.mode;
+
+    const body = {
+        body: form.elements.body.value,
+        all: mode === 'reply-all'
+    };
+    const to = form.elements.to.value.split(',').map(s => s.trim()).filter(Boolean);
+    if (to.length) body.to = to;
+    if (mode === 'send') body.subject = form.elements.subject.value;
+
+    let url = '/api/email/send';
+    if (mode !== 'send') {
+        const id = form.closest('.email-message').dataset.emailId;
+        url = `/api/email/${mode === 'forward' ? 'forward' : 'reply'}?id=${encodeURIComponent(id)}`;
+    }
+
+    status.textContent = 'Sending…';
+    try {
+        const response = await fetch(url, {
+            method: 'POST',
+            headers: {
+                'Content-Type': 'application/json',
+            },
+            body: JSON.stringify(body)
+        });
+
+        if (response.ok) {
+            form.reset();
+            form.hidden = true;
+        } else {
+            status.textContent = await response.text();
+        }
+    } catch (error) {
+        status.textContent = 'Failed to send';
+        console.error('Error sending email:', error);
+    }
+}
+
+// Tick the now/next meeting countdowns without reloading the page
+function initializeMeetingCountdown(root) {
+    const container = root.querySelector('.calendar-now');
+    if (!container) return;
+
+    const leaveWarning = parseInt(container.dataset.leaveWarning || '0', 10) * 1000;
+
+    function tick() {
+        const now = Date.now();
+        container.querySelectorAll('.countdown').forEach(countdown => {
+            const remaining = new Date(countdown.dataset.target).getTime() - now;
+            const status = countdown.closest('.meeting-status');
+
+            if (remaining <= 0) {
+                status.classList.add('started');
+                countdown.textContent = status.classList.contains('in-progress') ? 'ended' : 'started';
+                return;
+            }
+
+            countdown.textContent = (countdown.dataset.prefix || '') + formatCountdown(remaining);
+            if (status.classList.contains('next')) {
+                status.classList.toggle('leaving-soon', remaining <= leaveWarning);
+            }
+        });
+    }
+
+    tick();
+    const timer = setInterval(function() {
+        // Stop once the pane has been swapped out
+        if (!container.isConnected) {
+            clearInterval(timer);
+            return;
+        }
+        tick();
+    }, 1000);
+}
+
+// Mirrors services.FormatCountdown
+function formatCountdown(ms) {
+    const minutes = Math.round(ms / 60000);
+    if (minutes < 1) return '<1m';
+    if (minutes < 60) return `${minutes}m`;
+    const hours = Math.floor(minutes / 60);
+    if (hours < 24) return `${hours}h ${String(minutes % 60).padStart(2, '0')}m`;
+    return `${Math.floor(hours / 24)}d ${hours % 24}h`;
+}
+
+async function handleAddEvent() {
+    const input = document.getElementById('new-event');
+    const text = input.value.trim();
+
+    if (!text) return;
+
+    try {
+        const response = await fetch('/api/calendar', {
+            method: 'POST',
+            headers: {
+                'Content-Type': 'application/json',
+            },
+            body: JSON.stringify({ text: text })
+        });
+
+        if (response.ok) {
+            refreshPane('calendar');
+        } else {
+            console.error('Failed to add event:', await response.text());
+        }
+    } catch (error) {
+        console.error('Error adding event:', error);
+    }
+}
+
+async function handleDeleteEvent(event) {
+    const eventItem = event.target.closest('.calendar-event');
+    const id = eventItem.dataset.eventId;
+
+    // Optimistic UI update
+    eventItem.hidden = true;
+
+    try {
+        const response = await fetch(`/api/calendar?id=${encodeURIComponent(id)}`, {
+            method: 'DELETE'
+        });
+
+        if (!response.ok) {
+            eventItem.hidden = false;
+            console.error('Failed to delete event');
+        }
+    } catch (error) {
+        eventItem.hidden = false;
+        console.error('Error deleting event:', error);
+    }
 }
\ No newline at end of file